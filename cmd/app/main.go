@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -17,7 +18,9 @@ import (
 	"nats/internal/infra/nats"
 	"nats/internal/infra/valkey"
 	imiddle "nats/internal/middleware"
+	"nats/internal/natsapi"
 	"nats/internal/repo"
+	"nats/internal/rpc"
 	"nats/internal/service"
 	"nats/pkg/config"
 	"nats/pkg/glogger"
@@ -37,6 +40,23 @@ func main() {
 
 	const apiVer = "v1"
 
+	// Notification signing key, nil when signing is not configured
+	signer, err := service.NewNotificationSigner(cfg)
+	if err != nil {
+		glogger.Error(ctx, "Notification signer create failed", "error", err)
+		os.Exit(1)
+	}
+
+	// gRPC listener, bound before connecting the infrastructure so a taken address fails fast
+	var grpcLis net.Listener
+	if cfg.Grpc.Addr != "" {
+		grpcLis, err = net.Listen("tcp", cfg.Grpc.Addr)
+		if err != nil {
+			glogger.Error(ctx, "gRPC listen failed", "addr", cfg.Grpc.Addr, "error", err)
+			os.Exit(1)
+		}
+	}
+
 	// NATS POOL Create and DI
 	jsClient, err := nats.NewConnectionPool(ctx, cfg)
 	if err != nil {
@@ -58,22 +78,58 @@ func main() {
 	natsRepo := repo.NewNatsRepo(jsClient)
 	valkeyRepo := repo.NewValkeyRepo(valkeyClient)
 
+	deadLetterDispatcher := service.NewDeadLetterDispatcher(logs.WithLogger(ctx, logger), natsRepo, valkeyRepo, cfg)
+	if err := deadLetterDispatcher.Start(); err != nil {
+		glogger.Error(ctx, "Dead-letter dispatcher start failed", "error", err)
+		valkeyClient.Shutdown(ctx)
+		jsClient.ShutdownNatsPool(ctx)
+		os.Exit(1)
+	}
+	defer deadLetterDispatcher.Stop()
+
 	// Service resource create
 	ackDispatcher := service.NewAckDispatcher(100000, cfg.Publish.Worker, valkeyRepo) // Queue Size : TPS 100000
 	ackDispatcher.Start()
 	defer ackDispatcher.Stop()
 
 	ackTimeout := 30 * time.Second
-	publishSvc := service.NewPublishService(ackDispatcher, ackTimeout, natsRepo, valkeyRepo)
-	topicSvc := service.NewTopicService(natsRepo, cfg)
+	topicCache := service.NewTopicCache(natsRepo, 5*time.Second) // shared so topic changes evict the publish lookups
+	publishSvc := service.NewPublishService(ackDispatcher, ackTimeout, natsRepo, valkeyRepo, topicCache, cfg.Publish.MaxBatchEntries)
+	topicSvc := service.NewTopicService(natsRepo, topicCache, cfg)
+	subscriptionSvc := service.NewSubscriptionService(natsRepo, cfg)
+	messageMoveSvc := service.NewMessageMoveService(natsRepo, valkeyRepo, cfg)
+	queueSvc := service.NewQueueService(natsRepo, cfg)
+	pullSvc := service.NewPullService(natsRepo, cfg)
+	streamSvc := service.NewStreamService(natsRepo, cfg)
+
+	// NATS micro service (sns.api.<account>.<operation>)
+	natsAPI := natsapi.NewServer(logs.WithLogger(ctx, logger), natsRepo, topicSvc, publishSvc)
+	if err := natsAPI.Start(); err != nil {
+		glogger.Error(ctx, "NATS API start failed", "error", err)
+		ackDispatcher.Stop()
+		deadLetterDispatcher.Stop()
+		valkeyClient.Shutdown(ctx)
+		jsClient.ShutdownNatsPool(ctx)
+		os.Exit(1)
+	}
+	defer natsAPI.Stop()
+
+	deliveryDispatcher := service.NewDeliveryDispatcher(logs.WithLogger(ctx, logger), natsRepo, valkeyRepo, signer, cfg)
+	deliveryDispatcher.Start()
+	defer deliveryDispatcher.Stop()
+
+	messageMoveDispatcher := service.NewMessageMoveDispatcher(logs.WithLogger(ctx, logger), natsRepo, valkeyRepo, cfg)
+	messageMoveDispatcher.Start()
+	defer messageMoveDispatcher.Stop()
 
 	// Handler resource create
-	accountBase := handler.AccountBaseHandlers(topicSvc)
-	accountTopicBase := handler.AccountTopicBaseHandlers(topicSvc, publishSvc)
+	accountBase := handler.AccountBaseHandlers(topicSvc, messageMoveSvc)
+	accountTopicBase := handler.AccountTopicBaseHandlers(topicSvc, publishSvc, subscriptionSvc, queueSvc, pullSvc, streamSvc)
 
 	// echo start
 	e := echo.New()
 	e.Any("/metrics", echo.WrapHandler(promhttp.Handler()))
+	e.GET("/"+apiVer+handler.SigningCertPath, handler.NewSigningHandler(signer).Certificate())
 	imiddle.AttachMiddlewares(e, logger)
 
 	// Setup router
@@ -87,6 +143,17 @@ func main() {
 		}
 	}()
 
+	// gRPC server next to the HTTP API, sharing the services
+	grpcServer := rpc.NewServer(logger, topicSvc, publishSvc, streamSvc)
+	if grpcLis != nil {
+		go func() {
+			glogger.Info(ctx, "gRPC server is running", "addr", cfg.Grpc.Addr)
+			if err := grpcServer.Serve(grpcLis); err != nil {
+				glogger.Warn(ctx, "gRPC server shutdown", "error", err)
+			}
+		}()
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
@@ -97,5 +164,16 @@ func main() {
 	if err := e.Shutdown(ctx); err != nil {
 		glogger.Error(ctx, "Echo server shutdown failed", "error", err)
 	}
+	// GracefulStop waits for Subscribe streams, which only end when the client cancels them
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
 	glogger.Info(ctx, "The server has been shut down normally")
 }
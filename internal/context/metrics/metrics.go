@@ -15,6 +15,33 @@ var (
 		[]string{"action", "status"},
 	)
 
+	// gRPC 호출 수 측정
+	GrpcCallCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_calls_total",
+			Help: "Total number of gRPC calls by method and status code",
+		},
+		[]string{"method", "code"},
+	)
+
+	// 구독 endpoint 전달 결과
+	DeliveryCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "subscription_deliveries_total",
+			Help: "Total number of subscription delivery attempts by protocol and result",
+		},
+		[]string{"protocol", "result"},
+	)
+
+	// 연결 중인 SSE/WebSocket 스트림 수
+	ActiveStreams = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "topic_streams_active",
+			Help: "Number of connected live topic streams by transport",
+		},
+		[]string{"transport"},
+	)
+
 	// NATS 연결 상태 메트릭
 	NatsReconnects = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -48,6 +75,9 @@ var (
 
 func StartMetrics() {
 	prometheus.MustRegister(ApiCallCounter)
+	prometheus.MustRegister(GrpcCallCounter)
+	prometheus.MustRegister(DeliveryCounter)
+	prometheus.MustRegister(ActiveStreams)
 	prometheus.MustRegister(NatsReconnects)
 	prometheus.MustRegister(NatsDisconnects)
 	prometheus.MustRegister(ValkeyReconnects)
@@ -18,4 +18,5 @@ type AckTask struct {
 	Ctx       context.Context
 	AckFuture jetstream.PubAckFuture
 	TimeOut   time.Duration
+	OnError   func(err error) // optional, called when JetStream rejects the publish
 }
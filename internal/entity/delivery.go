@@ -0,0 +1,49 @@
+package entity
+
+import "fmt"
+
+// HTTP headers sent with every webhook delivery.
+const (
+	HTTPHeaderMessageType       = "x-sns-message-type"
+	HTTPHeaderMessageId         = "x-sns-message-id"
+	HTTPHeaderTopicSrn          = "x-sns-topic-srn"
+	HTTPHeaderSubscriptionSrn   = "x-sns-subscription-srn"
+	HTTPHeaderMessageAttributes = "x-sns-message-attributes" // JSON object of the publish MessageAttributes
+)
+
+// Delivered message types.
+const (
+	MessageTypeNotification             = "Notification"             // a message published to the topic
+	MessageTypeSubscriptionConfirmation = "SubscriptionConfirmation" // asks the endpoint to confirm the subscription
+)
+
+// Headers recorded on a message copied to a dead-letter topic.
+const (
+	HeaderDeadLetterTopicSrn        = "Sns-Dlq-Topic-Srn"
+	HeaderDeadLetterSubscriptionSrn = "Sns-Dlq-Subscription-Srn"
+	HeaderDeadLetterSubject         = "Sns-Dlq-Subject" // subject the message was originally published on
+	HeaderDeadLetterSequence        = "Sns-Dlq-Sequence"
+	HeaderDeadLetterAttempts        = "Sns-Dlq-Attempts"
+	HeaderDeadLetterLastError       = "Sns-Dlq-Last-Error"
+)
+
+// AdvisoryMaxDeliveries is published by JetStream when a message reaches the consumer MaxDeliver.
+// The last two tokens are the stream and the consumer.
+const AdvisoryMaxDeliveries = "$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.*.*"
+
+// AdvisoryStreamName is the stream keeping the AdvisoryMaxDeliveries advisories until they are
+// handled. The leading '_' can never start the stream of a topic, account ids are not empty.
+const AdvisoryStreamName = "_sns_advisories"
+
+// MaxDeliveriesAdvisory is the payload of AdvisoryMaxDeliveries.
+type MaxDeliveriesAdvisory struct {
+	Stream     string `json:"stream"`
+	Consumer   string `json:"consumer"`
+	StreamSeq  uint64 `json:"stream_seq"`
+	Deliveries uint64 `json:"deliveries"`
+}
+
+// DeliveryErrorKey is the valkey key holding the last delivery error of a message.
+func DeliveryErrorKey(stream, consumer string, seq uint64) string {
+	return fmt.Sprintf("sns:delivery:error:%s:%s:%d", stream, consumer, seq)
+}
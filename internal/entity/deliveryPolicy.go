@@ -0,0 +1,136 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Backoff functions of a delivery retry policy.
+const (
+	BackoffLinear      = "linear"      // delays are evenly spaced between min and max
+	BackoffArithmetic  = "arithmetic"  // delays grow with the square of the retry index
+	BackoffGeometric   = "geometric"   // delays double from min until they reach max
+	BackoffExponential = "exponential" // delays grow by a constant factor from min to max
+)
+
+// Delivery policy limits.
+const (
+	MaxDelayTarget = 3600 // seconds
+	MaxNumRetries  = 100
+)
+
+// DeliveryPolicy is the DeliveryPolicy subscription attribute.
+//
+//	{"healthyRetryPolicy": {"minDelayTarget": 1, "maxDelayTarget": 60, "numRetries": 10, "backoffFunction": "exponential"},
+//	 "throttlePolicy": {"maxReceivesPerSecond": 10}}
+type DeliveryPolicy struct {
+	HealthyRetryPolicy RetryPolicy     `json:"healthyRetryPolicy"`
+	ThrottlePolicy     *ThrottlePolicy `json:"throttlePolicy,omitempty"`
+}
+
+// RetryPolicy schedules the redelivery of messages the endpoint did not accept.
+// Delays are in seconds.
+type RetryPolicy struct {
+	MinDelayTarget  int    `json:"minDelayTarget"`
+	MaxDelayTarget  int    `json:"maxDelayTarget"`
+	NumRetries      int    `json:"numRetries"`
+	BackoffFunction string `json:"backoffFunction,omitempty"`
+}
+
+// ThrottlePolicy caps the delivery rate of a subscription.
+type ThrottlePolicy struct {
+	MaxReceivesPerSecond int `json:"maxReceivesPerSecond"`
+}
+
+// ParseDeliveryPolicy decodes and validates a DeliveryPolicy attribute value.
+func ParseDeliveryPolicy(value string) (DeliveryPolicy, error) {
+	var p DeliveryPolicy
+	if err := json.Unmarshal([]byte(value), &p); err != nil {
+		return DeliveryPolicy{}, fmt.Errorf("%w: DeliveryPolicy is not valid JSON", ErrInvalidParameter)
+	}
+	if p.HealthyRetryPolicy.BackoffFunction == "" {
+		p.HealthyRetryPolicy.BackoffFunction = BackoffLinear
+	}
+	if err := p.Validate(); err != nil {
+		return DeliveryPolicy{}, err
+	}
+	return p, nil
+}
+
+func (p DeliveryPolicy) Validate() error {
+	r := p.HealthyRetryPolicy
+	if r.MinDelayTarget < 1 || r.MinDelayTarget > MaxDelayTarget {
+		return fmt.Errorf("%w: minDelayTarget must be between 1 and %d", ErrInvalidParameter, MaxDelayTarget)
+	}
+	if r.MaxDelayTarget < r.MinDelayTarget || r.MaxDelayTarget > MaxDelayTarget {
+		return fmt.Errorf("%w: maxDelayTarget must be between minDelayTarget and %d", ErrInvalidParameter, MaxDelayTarget)
+	}
+	if r.NumRetries < 0 || r.NumRetries > MaxNumRetries {
+		return fmt.Errorf("%w: numRetries must be between 0 and %d", ErrInvalidParameter, MaxNumRetries)
+	}
+	switch r.BackoffFunction {
+	case BackoffLinear, BackoffArithmetic, BackoffGeometric, BackoffExponential:
+	default:
+		return fmt.Errorf("%w: unknown backoffFunction %q", ErrInvalidParameter, r.BackoffFunction)
+	}
+	if p.ThrottlePolicy != nil && p.ThrottlePolicy.MaxReceivesPerSecond < 1 {
+		return fmt.Errorf("%w: maxReceivesPerSecond must be at least 1", ErrInvalidParameter)
+	}
+	return nil
+}
+
+// String returns the JSON form stored in the consumer metadata.
+func (p DeliveryPolicy) String() string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+// MaxDeliver is the number of delivery attempts: the first one and every retry.
+func (p DeliveryPolicy) MaxDeliver() int {
+	return p.HealthyRetryPolicy.NumRetries + 1
+}
+
+// MaxReceivesPerSecond returns the delivery rate cap, 0 when unthrottled.
+func (p DeliveryPolicy) MaxReceivesPerSecond() int {
+	if p.ThrottlePolicy == nil {
+		return 0
+	}
+	return p.ThrottlePolicy.MaxReceivesPerSecond
+}
+
+// RetryDelay returns the delay before the given retry, counted from 1.
+func (p DeliveryPolicy) RetryDelay(retry int) time.Duration {
+	r := p.HealthyRetryPolicy
+	lo, hi := float64(r.MinDelayTarget), float64(r.MaxDelayTarget)
+	if r.NumRetries <= 1 || retry <= 1 {
+		return time.Duration(lo) * time.Second
+	}
+	if retry > r.NumRetries {
+		return time.Duration(hi) * time.Second
+	}
+
+	frac := float64(retry-1) / float64(r.NumRetries-1)
+	var d float64
+	switch r.BackoffFunction {
+	case BackoffArithmetic:
+		d = lo + (hi-lo)*frac*frac
+	case BackoffGeometric:
+		d = math.Min(hi, lo*math.Pow(2, float64(retry-1)))
+	case BackoffExponential:
+		d = lo * math.Pow(hi/lo, frac)
+	default:
+		d = lo + (hi-lo)*frac
+	}
+	return time.Duration(math.Round(d)) * time.Second
+}
+
+// Delays returns the delay before every retry, in order.
+func (p DeliveryPolicy) Delays() []time.Duration {
+	delays := make([]time.Duration, p.HealthyRetryPolicy.NumRetries)
+	for i := range delays {
+		delays[i] = p.RetryDelay(i + 1)
+	}
+	return delays
+}
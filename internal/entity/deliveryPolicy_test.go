@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDeliveryPolicy(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{`{"healthyRetryPolicy":{"minDelayTarget":1,"maxDelayTarget":60,"numRetries":5,"backoffFunction":"exponential"}}`, true},
+		{`{"healthyRetryPolicy":{"minDelayTarget":20,"maxDelayTarget":20,"numRetries":3}}`, true},
+		{`{"healthyRetryPolicy":{"minDelayTarget":1,"maxDelayTarget":1,"numRetries":0},"throttlePolicy":{"maxReceivesPerSecond":5}}`, true},
+		{`{"healthyRetryPolicy":{"minDelayTarget":0,"maxDelayTarget":60,"numRetries":5}}`, false},
+		{`{"healthyRetryPolicy":{"minDelayTarget":10,"maxDelayTarget":5,"numRetries":5}}`, false},
+		{`{"healthyRetryPolicy":{"minDelayTarget":1,"maxDelayTarget":3601,"numRetries":5}}`, false},
+		{`{"healthyRetryPolicy":{"minDelayTarget":1,"maxDelayTarget":60,"numRetries":101}}`, false},
+		{`{"healthyRetryPolicy":{"minDelayTarget":1,"maxDelayTarget":60,"numRetries":5,"backoffFunction":"random"}}`, false},
+		{`{"healthyRetryPolicy":{"minDelayTarget":1,"maxDelayTarget":60,"numRetries":5},"throttlePolicy":{"maxReceivesPerSecond":0}}`, false},
+		{`not json`, false},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseDeliveryPolicy(tt.value)
+		if tt.ok {
+			assert.NoError(t, err, tt.value)
+		} else {
+			assert.True(t, errors.Is(err, ErrInvalidParameter), tt.value)
+		}
+	}
+}
+
+func TestDeliveryPolicyDelays(t *testing.T) {
+	policy := func(fn string) DeliveryPolicy {
+		return DeliveryPolicy{HealthyRetryPolicy: RetryPolicy{MinDelayTarget: 1, MaxDelayTarget: 16, NumRetries: 5, BackoffFunction: fn}}
+	}
+	s := time.Second
+
+	assert.Equal(t, []time.Duration{1 * s, 5 * s, 9 * s, 12 * s, 16 * s}, policy(BackoffLinear).Delays())
+	assert.Equal(t, []time.Duration{1 * s, 2 * s, 5 * s, 9 * s, 16 * s}, policy(BackoffArithmetic).Delays())
+	assert.Equal(t, []time.Duration{1 * s, 2 * s, 4 * s, 8 * s, 16 * s}, policy(BackoffGeometric).Delays())
+	assert.Equal(t, []time.Duration{1 * s, 2 * s, 4 * s, 8 * s, 16 * s}, policy(BackoffExponential).Delays())
+
+	p := policy(BackoffLinear)
+	assert.Equal(t, 6, p.MaxDeliver())
+	assert.Equal(t, 16*s, p.RetryDelay(9))
+}
@@ -1,5 +1,7 @@
 package entity
 
+import "errors"
+
 // Error represents the error structure returned by SCP SNS.
 type Error struct {
 	Type    string `json:"Type"`
@@ -30,6 +32,15 @@ var (
 		},
 	}
 
+	Conflict = ErrorResponse{
+		HTTPCode: 409,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "ResourceConflict",
+			Message: "Indicates that the requested resource already exists with different attributes.",
+		},
+	}
+
 	InternalError = ErrorResponse{
 		HTTPCode: 500,
 		Error: Error{
@@ -48,6 +59,15 @@ var (
 		},
 	}
 
+	InvalidSubject = ErrorResponse{
+		HTTPCode: 400,
+		Error: Error{
+			Type:    "Sender",
+			Code:    "InvalidSubject",
+			Message: "Indicates that the publish subject does not belong to the subject hierarchy of the topic.",
+		},
+	}
+
 	NotFound = ErrorResponse{
 		HTTPCode: 404,
 		Error: Error{
@@ -57,3 +77,31 @@ var (
 		},
 	}
 )
+
+// Errors returned by the service layer. ErrorResponseOf translates them into the
+// matching ErrorResponse; any other error is reported as InternalError.
+var (
+	ErrInvalidParameter = errors.New("invalid parameter")
+	ErrNotFound         = errors.New("resource not found")
+	ErrAuthorization    = errors.New("access denied")
+	ErrConflict         = errors.New("resource conflict")
+	ErrInvalidSubject   = errors.New("subject not owned by topic")
+)
+
+// ErrorResponseOf maps a service error to the ErrorResponse returned by the HTTP and NATS APIs
+func ErrorResponseOf(err error) ErrorResponse {
+	switch {
+	case errors.Is(err, ErrInvalidSubject):
+		return InvalidSubject
+	case errors.Is(err, ErrInvalidParameter):
+		return InvalidParameter
+	case errors.Is(err, ErrNotFound):
+		return NotFound
+	case errors.Is(err, ErrAuthorization):
+		return AuthorizationError
+	case errors.Is(err, ErrConflict):
+		return Conflict
+	default:
+		return InternalError
+	}
+}
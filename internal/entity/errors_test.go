@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponseOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorResponse
+	}{
+		{fmt.Errorf("%w: subject orders.eu", ErrInvalidSubject), InvalidSubject},
+		{fmt.Errorf("%w: bad name", ErrInvalidParameter), InvalidParameter},
+		{fmt.Errorf("%w: topic orders", ErrNotFound), NotFound},
+		{fmt.Errorf("%w: not owned", ErrAuthorization), AuthorizationError},
+		{fmt.Errorf("%w: exists", ErrConflict), Conflict},
+		{errors.New("nats: timeout"), InternalError},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ErrorResponseOf(tt.err), "%v", tt.err)
+	}
+}
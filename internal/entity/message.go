@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// PublishInput is a message accepted by the publish action.
+type PublishInput struct {
+	TopicName              string
+	Message                string
+	Subject                string
+	MessageGroupId         string
+	MessageDeduplicationId string
+	MessageAttributes      map[string]MessageAttributeValue
+}
+
+// HeaderMessageId carries the MessageId returned by publish to subscribers.
+const HeaderMessageId = "Sns-Message-Id"
+
+// NATS message headers carrying the FIFO publish parameters.
+// The deduplication id is also sent as Nats-Msg-Id so JetStream drops duplicates.
+const (
+	HeaderMessageGroupId         = "Sns-Message-Group-Id"
+	HeaderMessageDeduplicationId = "Sns-Message-Deduplication-Id"
+)
+
+// FifoDeduplicationWindow is how long JetStream remembers the deduplication ids of a FIFO topic.
+const FifoDeduplicationWindow = 5 * time.Minute
+
+var fifoIdPattern = regexp.MustCompile(`^[A-Za-z0-9!"#$%&'()*+,\-./:;<=>?@\[\\\]^_` + "`" + `{|}~]{1,128}$`)
+
+// ValidateFifoId checks a MessageGroupId or MessageDeduplicationId.
+func ValidateFifoId(field, id string) error {
+	if !fifoIdPattern.MatchString(id) {
+		return fmt.Errorf("%w: %s must be 1-128 alphanumeric or punctuation characters", ErrInvalidParameter, field)
+	}
+	return nil
+}
+
+// HeaderAttributePrefix prefixes the NATS headers carrying the message attributes,
+// one header per attribute.
+const HeaderAttributePrefix = "Sns-Attr-"
+
+// FilterAttributes returns the message attributes of the headers in the form evaluated by
+// filter policies: Number attributes become numbers and String.Array attributes arrays.
+// Binary attributes are never matched.
+func FilterAttributes(header map[string][]string) map[string]any {
+	attrs := make(map[string]any)
+	for name, v := range MessageAttributesFromHeader(header) {
+		switch v.DataType {
+		case AttrTypeBinary:
+		case AttrTypeNumber:
+			if n, err := strconv.ParseFloat(v.StringValue, 64); err == nil {
+				attrs[name] = n
+			}
+		case AttrTypeStringArray:
+			var values []any
+			if err := json.Unmarshal([]byte(v.StringValue), &values); err == nil {
+				attrs[name] = values
+			}
+		default:
+			attrs[name] = v.StringValue
+		}
+	}
+	return attrs
+}
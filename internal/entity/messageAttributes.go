@@ -0,0 +1,157 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Message attribute data types.
+const (
+	AttrTypeString      = "String"
+	AttrTypeNumber      = "Number"
+	AttrTypeBinary      = "Binary"
+	AttrTypeStringArray = "String.Array" // JSON array of strings, numbers, booleans and nulls
+)
+
+// Message attribute limits.
+const (
+	MaxMessageAttributes     = 10
+	MaxMessageAttributeName  = 256
+	MaxMessageAttributeValue = 1e9 // absolute value of a Number attribute
+)
+
+// HeaderAttributeTypePrefix prefixes the NATS headers carrying the data type of each
+// message attribute. Messages published without it are read as String attributes.
+const HeaderAttributeTypePrefix = "Sns-Type-"
+
+var attributeNamePattern = regexp.MustCompile(`^[A-Za-z0-9_\-.]+$`)
+
+// MessageAttributeValue is a message attribute given on publish. Binary values are base64 in JSON.
+type MessageAttributeValue struct {
+	DataType    string `json:"DataType"`
+	StringValue string `json:"StringValue,omitempty"`
+	BinaryValue []byte `json:"BinaryValue,omitempty"`
+}
+
+// ValidateMessageAttributes checks the names, types and values of the message attributes.
+func ValidateMessageAttributes(attrs map[string]MessageAttributeValue) error {
+	if len(attrs) > MaxMessageAttributes {
+		return fmt.Errorf("%w: at most %d message attributes are allowed", ErrInvalidParameter, MaxMessageAttributes)
+	}
+	for name, v := range attrs {
+		if err := validateAttributeName(name); err != nil {
+			return err
+		}
+		if err := v.validate(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateAttributeName(name string) error {
+	switch {
+	case len(name) == 0 || len(name) > MaxMessageAttributeName:
+		return fmt.Errorf("%w: message attribute name must be 1-%d characters", ErrInvalidParameter, MaxMessageAttributeName)
+	case !attributeNamePattern.MatchString(name):
+		return fmt.Errorf("%w: message attribute name %q may only contain alphanumerics, '_', '-' and '.'", ErrInvalidParameter, name)
+	case strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, ".."):
+		return fmt.Errorf("%w: message attribute name %q must not start or end with '.' or contain '..'", ErrInvalidParameter, name)
+	case strings.HasPrefix(strings.ToLower(name), "sns."):
+		return fmt.Errorf("%w: message attribute name %q uses the reserved prefix SNS.", ErrInvalidParameter, name)
+	}
+	return nil
+}
+
+func (v MessageAttributeValue) validate(name string) error {
+	if v.DataType == AttrTypeBinary {
+		if len(v.BinaryValue) == 0 || v.StringValue != "" {
+			return fmt.Errorf("%w: message attribute %s needs a BinaryValue only", ErrInvalidParameter, name)
+		}
+		return nil
+	}
+	if v.StringValue == "" || len(v.BinaryValue) > 0 {
+		return fmt.Errorf("%w: message attribute %s needs a StringValue only", ErrInvalidParameter, name)
+	}
+	// header values cannot span lines
+	if strings.ContainsAny(v.StringValue, "\r\n") {
+		return fmt.Errorf("%w: message attribute %s must not contain line breaks", ErrInvalidParameter, name)
+	}
+
+	switch v.DataType {
+	case AttrTypeString:
+	case AttrTypeNumber:
+		n, err := strconv.ParseFloat(v.StringValue, 64)
+		if err != nil || math.IsNaN(n) || math.Abs(n) > MaxMessageAttributeValue {
+			return fmt.Errorf("%w: message attribute %s must be a number between -%g and %g", ErrInvalidParameter, name, MaxMessageAttributeValue, MaxMessageAttributeValue)
+		}
+	case AttrTypeStringArray:
+		var values []any
+		if err := json.Unmarshal([]byte(v.StringValue), &values); err != nil {
+			return fmt.Errorf("%w: message attribute %s must be a JSON array", ErrInvalidParameter, name)
+		}
+		for _, elem := range values {
+			switch elem.(type) {
+			case string, float64, bool, nil:
+			default:
+				return fmt.Errorf("%w: message attribute %s may only hold strings, numbers, booleans and nulls", ErrInvalidParameter, name)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: message attribute %s has unsupported DataType %q", ErrInvalidParameter, name, v.DataType)
+	}
+	return nil
+}
+
+// headerValue is the attribute value as written in the NATS header
+func (v MessageAttributeValue) headerValue() string {
+	if v.DataType == AttrTypeBinary {
+		return base64.StdEncoding.EncodeToString(v.BinaryValue)
+	}
+	return v.StringValue
+}
+
+// SetMessageAttributeHeaders writes the attributes as Sns-Attr-<name> and Sns-Type-<name> headers.
+// Header keys keep the attribute name case.
+func SetMessageAttributeHeaders(header map[string][]string, attrs map[string]MessageAttributeValue) {
+	for name, v := range attrs {
+		header[HeaderAttributePrefix+name] = []string{v.headerValue()}
+		header[HeaderAttributeTypePrefix+name] = []string{v.DataType}
+	}
+}
+
+// MessageAttributesFromHeader reads the message attributes back from the NATS headers.
+func MessageAttributesFromHeader(header map[string][]string) map[string]MessageAttributeValue {
+	var attrs map[string]MessageAttributeValue
+	for key, values := range header {
+		name, ok := strings.CutPrefix(key, HeaderAttributePrefix)
+		if !ok || name == "" || len(values) == 0 {
+			continue
+		}
+		v := MessageAttributeValue{DataType: AttrTypeString, StringValue: values[0]}
+		if types := header[HeaderAttributeTypePrefix+name]; len(types) > 0 {
+			v.DataType = types[0]
+		} else if len(values) > 1 {
+			// attributes published directly to NATS with repeated headers
+			b, _ := json.Marshal(values)
+			v = MessageAttributeValue{DataType: AttrTypeStringArray, StringValue: string(b)}
+		}
+		if v.DataType == AttrTypeBinary {
+			b, err := base64.StdEncoding.DecodeString(values[0])
+			if err != nil {
+				continue
+			}
+			v = MessageAttributeValue{DataType: AttrTypeBinary, BinaryValue: b}
+		}
+		if attrs == nil {
+			attrs = make(map[string]MessageAttributeValue)
+		}
+		attrs[name] = v
+	}
+	return attrs
+}
@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMessageAttributes(t *testing.T) {
+	valid := map[string]MessageAttributeValue{
+		"store":     {DataType: AttrTypeString, StringValue: "example_corp"},
+		"price.usd": {DataType: AttrTypeNumber, StringValue: "-12.5"},
+		"thumb":     {DataType: AttrTypeBinary, BinaryValue: []byte{0, 1, 2}},
+		"sports":    {DataType: AttrTypeStringArray, StringValue: `["rugby", 3, true, null]`},
+	}
+	assert.NoError(t, ValidateMessageAttributes(valid))
+	assert.NoError(t, ValidateMessageAttributes(nil))
+
+	tests := map[string]MessageAttributeValue{
+		"":           {DataType: AttrTypeString, StringValue: "a"},
+		"with space": {DataType: AttrTypeString, StringValue: "a"},
+		".store":     {DataType: AttrTypeString, StringValue: "a"},
+		"a..b":       {DataType: AttrTypeString, StringValue: "a"},
+		"SNS.store":  {DataType: AttrTypeString, StringValue: "a"},
+		"empty":      {DataType: AttrTypeString},
+		"newline":    {DataType: AttrTypeString, StringValue: "a\nb"},
+		"number":     {DataType: AttrTypeNumber, StringValue: "ten"},
+		"big":        {DataType: AttrTypeNumber, StringValue: "1e10"},
+		"binary":     {DataType: AttrTypeBinary, StringValue: "AAEC"},
+		"array":      {DataType: AttrTypeStringArray, StringValue: `"rugby"`},
+		"nested":     {DataType: AttrTypeStringArray, StringValue: `[["rugby"]]`},
+		"type":       {DataType: "Number.Integer", StringValue: "1"},
+	}
+	for name, v := range tests {
+		err := ValidateMessageAttributes(map[string]MessageAttributeValue{name: v})
+		assert.ErrorIs(t, err, ErrInvalidParameter, name)
+	}
+
+	tooMany := make(map[string]MessageAttributeValue)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"} {
+		tooMany[name] = MessageAttributeValue{DataType: AttrTypeString, StringValue: "x"}
+	}
+	assert.ErrorIs(t, ValidateMessageAttributes(tooMany), ErrInvalidParameter)
+}
+
+func TestMessageAttributeHeaders(t *testing.T) {
+	attrs := map[string]MessageAttributeValue{
+		"Store":  {DataType: AttrTypeString, StringValue: "example_corp"},
+		"price":  {DataType: AttrTypeNumber, StringValue: "100"},
+		"thumb":  {DataType: AttrTypeBinary, BinaryValue: []byte{0, 1, 2}},
+		"sports": {DataType: AttrTypeStringArray, StringValue: `["rugby",3]`},
+	}
+	header := map[string][]string{HeaderMessageId: {"id"}}
+	SetMessageAttributeHeaders(header, attrs)
+
+	assert.Equal(t, []string{"example_corp"}, header["Sns-Attr-Store"])
+	assert.Equal(t, []string{"AAEC"}, header["Sns-Attr-thumb"])
+	assert.Equal(t, attrs, MessageAttributesFromHeader(header))
+
+	assert.Equal(t, map[string]any{
+		"Store":  "example_corp",
+		"price":  float64(100),
+		"sports": []any{"rugby", float64(3)},
+	}, FilterAttributes(header))
+}
+
+func TestMessageAttributesFromUntypedHeader(t *testing.T) {
+	header := map[string][]string{
+		"Sns-Attr-store": {"example_corp"},
+		"Sns-Attr-sport": {"rugby", "soccer"},
+	}
+
+	assert.Equal(t, map[string]MessageAttributeValue{
+		"store": {DataType: AttrTypeString, StringValue: "example_corp"},
+		"sport": {DataType: AttrTypeStringArray, StringValue: `["rugby","soccer"]`},
+	}, MessageAttributesFromHeader(header))
+	assert.Equal(t, map[string]any{"store": "example_corp", "sport": []any{"rugby", "soccer"}}, FilterAttributes(header))
+	assert.Nil(t, MessageAttributesFromHeader(map[string][]string{HeaderMessageId: {"id"}}))
+}
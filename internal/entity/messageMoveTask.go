@@ -0,0 +1,57 @@
+package entity
+
+import "fmt"
+
+// Message move task statuses.
+const (
+	MoveTaskRunning    = "RUNNING"
+	MoveTaskCompleted  = "COMPLETED"
+	MoveTaskCancelling = "CANCELLING"
+	MoveTaskCancelled  = "CANCELLED"
+	MoveTaskFailed     = "FAILED"
+)
+
+// Message move task limits.
+const (
+	MaxMoveTaskRate     = 500 // messages per second
+	DefaultMoveTaskRate = 100
+)
+
+// MessageMoveTask moves the messages of a dead-letter topic back to their original topic,
+// or to the destination topic when one is given. Progress is recorded as the last moved
+// stream sequence so that a restarted task resumes where it stopped.
+type MessageMoveTask struct {
+	TaskHandle                        string `json:"TaskHandle"`
+	Status                            string `json:"Status"`
+	SourceSrn                         string `json:"SourceSrn"`
+	DestinationSrn                    string `json:"DestinationSrn,omitempty"`
+	MaxNumberOfMessagesPerSecond      int    `json:"MaxNumberOfMessagesPerSecond"`
+	ApproximateNumberOfMessagesMoved  int64  `json:"ApproximateNumberOfMessagesMoved"`
+	ApproximateNumberOfMessagesToMove int64  `json:"ApproximateNumberOfMessagesToMove"`
+	FailureReason                     string `json:"FailureReason,omitempty"`
+	StartedTimestamp                  int64  `json:"StartedTimestamp"` // unix milliseconds
+
+	Account      string `json:"-"`
+	LastSequence uint64 `json:"-"` // last source sequence moved or skipped
+	EndSequence  uint64 `json:"-"` // last source sequence when the task started
+}
+
+// Finished reports whether the task reached a final status.
+func (t MessageMoveTask) Finished() bool {
+	return t.Status == MoveTaskCompleted || t.Status == MoveTaskCancelled || t.Status == MoveTaskFailed
+}
+
+// StartMessageMoveTaskInput is a task requested by the startMessageMoveTask action.
+type StartMessageMoveTaskInput struct {
+	SourceSrn                    SRN
+	DestinationSrn               *SRN // nil to move every message back to its original topic
+	MaxNumberOfMessagesPerSecond int  // 0 for DefaultMoveTaskRate
+}
+
+// ValidateMoveTaskRate checks the MaxNumberOfMessagesPerSecond of a task.
+func ValidateMoveTaskRate(rate int) error {
+	if rate < 0 || rate > MaxMoveTaskRate {
+		return fmt.Errorf("%w: MaxNumberOfMessagesPerSecond must be between 1 and %d", ErrInvalidParameter, MaxMoveTaskRate)
+	}
+	return nil
+}
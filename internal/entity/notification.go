@@ -0,0 +1,55 @@
+package entity
+
+import "time"
+
+// NotificationVersion is the layout version of the Notification envelope. Fields are only
+// ever added within a version; removing or changing a field requires a new version.
+const NotificationVersion = "1"
+
+// NotificationTimeFormat is the UTC millisecond timestamp format of the envelope.
+const NotificationTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// Notification is the JSON envelope a message is wrapped in when it is delivered,
+// unless the subscription enables RawMessageDelivery.
+type Notification struct {
+	Version           string                           `json:"Version"`
+	Type              string                           `json:"Type"`
+	MessageId         string                           `json:"MessageId"`
+	TopicSrn          string                           `json:"TopicSrn"`
+	Subject           string                           `json:"Subject,omitempty"` // relative subject the message was published on
+	Message           string                           `json:"Message"`
+	Timestamp         string                           `json:"Timestamp"`
+	MessageGroupId    string                           `json:"MessageGroupId,omitempty"` // FIFO topics only
+	SequenceNumber    string                           `json:"SequenceNumber,omitempty"` // FIFO topics only, the stream sequence
+	MessageAttributes map[string]NotificationAttribute `json:"MessageAttributes,omitempty"`
+	Token             string                           `json:"Token,omitempty"`        // SubscriptionConfirmation only
+	SubscribeURL      string                           `json:"SubscribeURL,omitempty"` // SubscriptionConfirmation only
+
+	// Set when signing is configured, see pkg/signature
+	SignatureVersion string `json:"SignatureVersion,omitempty"`
+	Signature        string `json:"Signature,omitempty"`
+	SigningCertURL   string `json:"SigningCertURL,omitempty"`
+}
+
+// NotificationAttribute is a message attribute in the envelope. Binary values are base64.
+type NotificationAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+// NotificationTimestamp formats a publish time for the envelope.
+func NotificationTimestamp(t time.Time) string {
+	return t.UTC().Format(NotificationTimeFormat)
+}
+
+// NotificationAttributes converts publish attributes to their envelope form.
+func NotificationAttributes(attrs map[string]MessageAttributeValue) map[string]NotificationAttribute {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make(map[string]NotificationAttribute, len(attrs))
+	for name, v := range attrs {
+		out[name] = NotificationAttribute{Type: v.DataType, Value: v.headerValue()}
+	}
+	return out
+}
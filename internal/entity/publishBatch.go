@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultMaxBatchEntries is the publishBatch entry limit when publish.maxBatchEntries is not set, as in SNS.
+const DefaultMaxBatchEntries = 10
+
+var batchEntryIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,80}$`)
+
+// PublishBatchInput is a request of the publishBatch action. Every entry is published to TopicName.
+type PublishBatchInput struct {
+	TopicName string
+	Entries   []PublishBatchEntry
+}
+
+// PublishBatchEntry is one message of a batch. Id is chosen by the caller and unique in the batch.
+type PublishBatchEntry struct {
+	Id                     string
+	Message                string
+	Subject                string
+	MessageGroupId         string
+	MessageDeduplicationId string
+	MessageAttributes      map[string]MessageAttributeValue
+}
+
+// PublishInput returns the entry as a single publish to the topic.
+func (e PublishBatchEntry) PublishInput(topicName string) PublishInput {
+	return PublishInput{
+		TopicName:              topicName,
+		Message:                e.Message,
+		Subject:                e.Subject,
+		MessageGroupId:         e.MessageGroupId,
+		MessageDeduplicationId: e.MessageDeduplicationId,
+		MessageAttributes:      e.MessageAttributes,
+	}
+}
+
+// PublishBatchEntryResult is the outcome of one entry: the MessageId, or the error that failed it.
+type PublishBatchEntryResult struct {
+	Id        string
+	MessageId string
+	Err       error
+}
+
+// ValidatePublishBatch checks the batch as a whole. Errors of single entries are reported per entry instead.
+func ValidatePublishBatch(input PublishBatchInput, maxEntries int) error {
+	if input.TopicName == "" {
+		return fmt.Errorf("%w: missing required fields", ErrInvalidParameter)
+	}
+	if len(input.Entries) == 0 {
+		return fmt.Errorf("%w: the batch has no entries", ErrInvalidParameter)
+	}
+	if len(input.Entries) > maxEntries {
+		return fmt.Errorf("%w: the batch has %d entries, at most %d are allowed", ErrInvalidParameter, len(input.Entries), maxEntries)
+	}
+	ids := make(map[string]bool, len(input.Entries))
+	for _, e := range input.Entries {
+		if !batchEntryIdPattern.MatchString(e.Id) {
+			return fmt.Errorf("%w: entry id must be 1-80 alphanumeric, hyphen or underscore characters", ErrInvalidParameter)
+		}
+		if ids[e.Id] {
+			return fmt.Errorf("%w: entry id %s is not distinct", ErrInvalidParameter, e.Id)
+		}
+		ids[e.Id] = true
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePublishBatch(t *testing.T) {
+	entries := []PublishBatchEntry{{Id: "a-1"}, {Id: "b_2"}}
+	assert.NoError(t, ValidatePublishBatch(PublishBatchInput{TopicName: "orders", Entries: entries}, 10))
+
+	invalid := []PublishBatchInput{
+		{Entries: entries},
+		{TopicName: "orders"},
+		{TopicName: "orders", Entries: []PublishBatchEntry{{Id: "a"}, {Id: "b"}, {Id: "c"}}},
+		{TopicName: "orders", Entries: []PublishBatchEntry{{Id: "a"}, {Id: "a"}}},
+		{TopicName: "orders", Entries: []PublishBatchEntry{{Id: ""}}},
+		{TopicName: "orders", Entries: []PublishBatchEntry{{Id: "a.b"}}},
+	}
+	for _, input := range invalid {
+		assert.ErrorIs(t, ValidatePublishBatch(input, 2), ErrInvalidParameter, input)
+	}
+}
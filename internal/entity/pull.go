@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// PullConsumerPrefix names the durable consumers created by the receive action,
+// so that they never collide with subscription consumers (uuid names).
+const PullConsumerPrefix = "pull-"
+
+// DefaultPullConsumer is used when receive is called without a ConsumerName.
+const DefaultPullConsumer = "default"
+
+// MaxAckTokens is the most tokens a single ack or nak settles.
+const MaxAckTokens = 100
+
+var pullConsumerPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// PullInput is a request of the receive action.
+type PullInput struct {
+	ConsumerName        string // DefaultPullConsumer when empty
+	MaxNumberOfMessages int    // 1 when 0
+	WaitTimeSeconds     int    // long polling, 0 returns at once
+}
+
+// PullMessage is a message returned by receive. AckToken settles it with the ack and nak actions.
+type PullMessage struct {
+	AckToken          string                           `json:"AckToken"`
+	MessageId         string                           `json:"MessageId"`
+	Subject           string                           `json:"Subject"` // relative to the account subject space
+	Message           string                           `json:"Message"`
+	SequenceNumber    uint64                           `json:"SequenceNumber"`
+	Timestamp         string                           `json:"Timestamp"`
+	ReceiveCount      uint64                           `json:"ReceiveCount"`
+	MessageGroupId    string                           `json:"MessageGroupId,omitempty"`
+	MessageAttributes map[string]MessageAttributeValue `json:"MessageAttributes,omitempty"`
+}
+
+// PullConsumerName returns the durable name of the named pull consumer.
+func PullConsumerName(name string) (string, error) {
+	if name == "" {
+		name = DefaultPullConsumer
+	}
+	if !pullConsumerPattern.MatchString(name) {
+		return "", fmt.Errorf("%w: consumer name must be 1-64 alphanumeric, hyphen or underscore characters", ErrInvalidParameter)
+	}
+	return PullConsumerPrefix + name, nil
+}
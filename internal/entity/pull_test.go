@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullConsumerName(t *testing.T) {
+	name, err := PullConsumerName("")
+	assert.NoError(t, err)
+	assert.Equal(t, "pull-default", name)
+
+	name, err = PullConsumerName("worker_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "pull-worker_1", name)
+
+	for _, invalid := range []string{"a.b", "a*", "a b", string(make([]byte, 65))} {
+		_, err := PullConsumerName(invalid)
+		assert.ErrorIs(t, err, ErrInvalidParameter, invalid)
+	}
+}
@@ -0,0 +1,113 @@
+package entity
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProtocolQueue subscribes a pull queue read with receiveMessage instead of a webhook.
+// The endpoint is the queue name.
+const ProtocolQueue = "queue"
+
+// Queue limits.
+const (
+	MaxReceiveMessages    = 10
+	MaxReceiveWaitSeconds = 20
+	MaxVisibilityTimeout  = 43200 // seconds
+)
+
+var queueNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,80}$`)
+
+// ReceiveMessageInput is a request of the receiveMessage action.
+type ReceiveMessageInput struct {
+	MaxNumberOfMessages int // 1 when 0
+	WaitTimeSeconds     int // long polling, 0 returns at once
+}
+
+// QueueMessage is a message returned by receiveMessage. Body is the Notification envelope,
+// or the published message when the subscription enables RawMessageDelivery.
+type QueueMessage struct {
+	MessageId         string                           `json:"MessageId"`
+	ReceiptHandle     string                           `json:"ReceiptHandle"`
+	Body              string                           `json:"Body"`
+	Attributes        map[string]string                `json:"Attributes"`
+	MessageAttributes map[string]MessageAttributeValue `json:"MessageAttributes,omitempty"`
+}
+
+// QueueMessage attribute names.
+const (
+	QueueAttrReceiveCount   = "ApproximateReceiveCount"
+	QueueAttrSentTimestamp  = "SentTimestamp" // unix milliseconds
+	QueueAttrSequenceNumber = "SequenceNumber"
+)
+
+// JetStream acknowledgements published to the ack subject of a ReceiptHandle.
+const (
+	AckAck  = "+ACK"  // processed, the message is not delivered again
+	AckNak  = "-NAK"  // redeliver now
+	AckTerm = "+TERM" // never deliver again
+)
+
+// AckNakWithDelay redelivers the message once the delay has passed, instead of the AckWait
+func AckNakWithDelay(delay time.Duration) string {
+	return fmt.Sprintf(`%s {"delay": %d}`, AckNak, delay.Nanoseconds())
+}
+
+// ReceiptHandle identifies a received message by the JetStream ack subject of its delivery.
+type ReceiptHandle struct {
+	Subject   string // ack reply subject the settlement is published to
+	Stream    string
+	Consumer  string
+	Delivered uint64
+	StreamSeq uint64
+}
+
+// EncodeReceiptHandle wraps the ack reply subject of a delivered message.
+func EncodeReceiptHandle(replySubject string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(replySubject))
+}
+
+// ParseReceiptHandle decodes a receipt handle. The ack subject is
+// $JS.ACK.<stream>.<consumer>.<delivered>.<sseq>.<cseq>.<tm>.<pending>, newer servers add
+// the domain and account hash after ACK and a random token at the end.
+func ParseReceiptHandle(handle string) (ReceiptHandle, error) {
+	invalid := fmt.Errorf("%w: invalid ReceiptHandle", ErrInvalidParameter)
+	b, err := base64.RawURLEncoding.DecodeString(handle)
+	if err != nil {
+		return ReceiptHandle{}, invalid
+	}
+	subject := string(b)
+	if strings.ContainsAny(subject, " \t\r\n*>") {
+		return ReceiptHandle{}, invalid
+	}
+
+	tokens := strings.Split(subject, ".")
+	if len(tokens) < 9 || tokens[0] != "$JS" || tokens[1] != "ACK" {
+		return ReceiptHandle{}, invalid
+	}
+	switch {
+	case len(tokens) == 9:
+	case len(tokens) >= 11:
+		tokens = tokens[2:] // drop the domain and account hash
+	default:
+		return ReceiptHandle{}, invalid
+	}
+	delivered, err1 := strconv.ParseUint(tokens[4], 10, 64)
+	seq, err2 := strconv.ParseUint(tokens[5], 10, 64)
+	if err1 != nil || err2 != nil || tokens[2] == "" || tokens[3] == "" {
+		return ReceiptHandle{}, invalid
+	}
+	return ReceiptHandle{Subject: subject, Stream: tokens[2], Consumer: tokens[3], Delivered: delivered, StreamSeq: seq}, nil
+}
+
+// ValidateQueueName checks the endpoint of a queue subscription.
+func ValidateQueueName(name string) error {
+	if !queueNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: queue name must be 1-80 alphanumeric, hyphen or underscore characters", ErrInvalidParameter)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReceiptHandle(t *testing.T) {
+	v1 := "$JS.ACK.SNS_acct_orders.sub1.2.17.5.1767322000000000000.3"
+	h, err := ParseReceiptHandle(EncodeReceiptHandle(v1))
+	assert.NoError(t, err)
+	assert.Equal(t, ReceiptHandle{Subject: v1, Stream: "SNS_acct_orders", Consumer: "sub1", Delivered: 2, StreamSeq: 17}, h)
+
+	v2 := "$JS.ACK.hub.ABCDEF.SNS_acct_orders.sub1.1.18.6.1767322000000000000.0.xyz"
+	h, err = ParseReceiptHandle(EncodeReceiptHandle(v2))
+	assert.NoError(t, err)
+	assert.Equal(t, "SNS_acct_orders", h.Stream)
+	assert.Equal(t, "sub1", h.Consumer)
+	assert.Equal(t, uint64(18), h.StreamSeq)
+}
+
+func TestParseReceiptHandleInvalid(t *testing.T) {
+	for _, subject := range []string{
+		"",
+		"$JS.ACK.stream.consumer",
+		"$JS.API.stream.consumer.1.2.3.4.5",
+		"$JS.ACK.stream.consumer.x.2.3.4.5",
+		"$JS.ACK.stream.*.1.2.3.4.5",
+		"$JS.ACK.stream.consumer.1.2.3.4.5.6",
+	} {
+		_, err := ParseReceiptHandle(EncodeReceiptHandle(subject))
+		assert.ErrorIs(t, err, ErrInvalidParameter, subject)
+	}
+	_, err := ParseReceiptHandle("not base64!")
+	assert.ErrorIs(t, err, ErrInvalidParameter)
+}
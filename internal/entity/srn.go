@@ -0,0 +1,107 @@
+package entity
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const srnPrefix = "srn:scp:sns:"
+
+var (
+	regionPattern         = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+	subscriptionIdPattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+)
+
+// SRN identifies a topic or subscription:
+//
+//	srn:scp:sns:<region>:<account>:<topic>
+//	srn:scp:sns:<region>:<account>:<topic>:<subscription id>
+type SRN struct {
+	Region       string
+	Account      string
+	Topic        string
+	Subscription string // empty for topic SRNs
+}
+
+// NewTopicSRN returns the SRN of the account's topic.
+func NewTopicSRN(region, account, topic string) SRN {
+	return SRN{Region: region, Account: account, Topic: topic}
+}
+
+// ParseSRN parses and validates a topic or subscription SRN.
+func ParseSRN(s string) (SRN, error) {
+	rest, ok := strings.CutPrefix(s, srnPrefix)
+	if !ok {
+		return SRN{}, fmt.Errorf("%w: srn must start with %s", ErrInvalidParameter, srnPrefix)
+	}
+
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return SRN{}, fmt.Errorf("%w: srn must be %s<region>:<account>:<topic>[:<subscription>]", ErrInvalidParameter, srnPrefix)
+	}
+
+	srn := SRN{Region: parts[0], Account: parts[1], Topic: parts[2]}
+	if len(parts) == 4 {
+		srn.Subscription = parts[3]
+	}
+	if err := srn.validate(); err != nil {
+		return SRN{}, err
+	}
+	return srn, nil
+}
+
+func (s SRN) validate() error {
+	if !regionPattern.MatchString(s.Region) {
+		return fmt.Errorf("%w: invalid srn region %q", ErrInvalidParameter, s.Region)
+	}
+	if err := ValidateAccountID(s.Account); err != nil {
+		return err
+	}
+	if err := ValidateTopicName(s.Topic); err != nil {
+		return err
+	}
+	if s.Subscription != "" && !subscriptionIdPattern.MatchString(s.Subscription) {
+		return fmt.Errorf("%w: invalid srn subscription id %q", ErrInvalidParameter, s.Subscription)
+	}
+	return nil
+}
+
+// String formats the SRN.
+func (s SRN) String() string {
+	var sb strings.Builder
+	sb.Grow(len(srnPrefix) + len(s.Region) + len(s.Account) + len(s.Topic) + len(s.Subscription) + 3)
+	sb.WriteString(srnPrefix)
+	sb.WriteString(s.Region)
+	sb.WriteByte(':')
+	sb.WriteString(s.Account)
+	sb.WriteByte(':')
+	sb.WriteString(s.Topic)
+	if s.Subscription != "" {
+		sb.WriteByte(':')
+		sb.WriteString(s.Subscription)
+	}
+	return sb.String()
+}
+
+// IsSubscription reports whether the SRN refers to a subscription.
+func (s SRN) IsSubscription() bool {
+	return s.Subscription != ""
+}
+
+// TopicSRN returns the SRN of the topic, dropping the subscription part.
+func (s SRN) TopicSRN() SRN {
+	return SRN{Region: s.Region, Account: s.Account, Topic: s.Topic}
+}
+
+// CheckOwner rejects SRNs of another region with ErrInvalidParameter and
+// SRNs owned by another account with ErrAuthorization.
+func (s SRN) CheckOwner(region, account string) error {
+	if s.Region != region {
+		return fmt.Errorf("%w: srn region %s does not match %s", ErrInvalidParameter, s.Region, region)
+	}
+	if s.Account != account {
+		return fmt.Errorf("%w: %s is not owned by account %s", ErrAuthorization, s, account)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSRN(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    SRN
+		wantErr bool
+	}{
+		{"topic", "srn:scp:sns:kr-west1:acct1:orders", SRN{Region: "kr-west1", Account: "acct1", Topic: "orders"}, false},
+		{"fifo topic", "srn:scp:sns:kr-west1:acct1:orders.fifo", SRN{Region: "kr-west1", Account: "acct1", Topic: "orders.fifo"}, false},
+		{"subscription", "srn:scp:sns:kr-west1:acct1:orders:3f1c", SRN{Region: "kr-west1", Account: "acct1", Topic: "orders", Subscription: "3f1c"}, false},
+		{"wrong prefix", "arn:aws:sns:kr-west1:acct1:orders", SRN{}, true},
+		{"missing topic", "srn:scp:sns:kr-west1:acct1", SRN{}, true},
+		{"empty region", "srn:scp:sns::acct1:orders", SRN{}, true},
+		{"invalid account", "srn:scp:sns:kr-west1:acct_1:orders", SRN{}, true},
+		{"invalid topic", "srn:scp:sns:kr-west1:acct1:or ders", SRN{}, true},
+		{"too many parts", "srn:scp:sns:kr-west1:acct1:orders:sub:extra", SRN{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSRN(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidParameter)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.input, got.String())
+		})
+	}
+}
+
+func TestSRNCheckOwner(t *testing.T) {
+	srn := NewTopicSRN("kr-west1", "acct1", "orders")
+
+	assert.NoError(t, srn.CheckOwner("kr-west1", "acct1"))
+	assert.True(t, errors.Is(srn.CheckOwner("kr-east1", "acct1"), ErrInvalidParameter))
+	assert.True(t, errors.Is(srn.CheckOwner("kr-west1", "acct2"), ErrAuthorization))
+}
@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// StreamConsumerPrefix names the ephemeral consumers of live SSE and WebSocket streams.
+const StreamConsumerPrefix = "stream-"
+
+// StreamEvent is a message pushed to a live stream client. SequenceNumber is the
+// stream sequence, sent as the event id that Last-Event-ID resumes after.
+type StreamEvent struct {
+	SequenceNumber    uint64                           `json:"SequenceNumber"`
+	MessageId         string                           `json:"MessageId"`
+	Subject           string                           `json:"Subject"` // relative to the account subject space
+	Message           string                           `json:"Message"`
+	Timestamp         string                           `json:"Timestamp"`
+	MessageGroupId    string                           `json:"MessageGroupId,omitempty"`
+	MessageAttributes map[string]MessageAttributeValue `json:"MessageAttributes,omitempty"`
+}
+
+// ParseLastEventId parses the sequence a stream resumes after, 0 when empty (new messages only).
+func ParseLastEventId(id string) (uint64, error) {
+	if id == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: Last-Event-ID must be a stream sequence number", ErrInvalidParameter)
+	}
+	return seq, nil
+}
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLastEventId(t *testing.T) {
+	seq, err := ParseLastEventId("")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), seq)
+
+	seq, err = ParseLastEventId("42")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(42), seq)
+
+	for _, invalid := range []string{"-1", "abc", "1.5"} {
+		_, err := ParseLastEventId(invalid)
+		assert.ErrorIs(t, err, ErrInvalidParameter, invalid)
+	}
+}
@@ -0,0 +1,99 @@
+package entity
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type Subscription struct {
+	SubscriptionSrn     string `json:"SubscriptionSrn"`
+	TopicSrn            string `json:"TopicSrn"`
+	Owner               string `json:"Owner"`
+	Protocol            string `json:"Protocol"`
+	Endpoint            string `json:"Endpoint"`
+	PendingConfirmation bool   `json:"PendingConfirmation"`
+}
+
+// SubscribeInput is a subscription requested by the subscribe action.
+type SubscribeInput struct {
+	Protocol   string
+	Endpoint   string
+	Attributes map[string]string
+}
+
+// Subscription protocols.
+const (
+	ProtocolHTTP  = "http"
+	ProtocolHTTPS = "https"
+)
+
+// Consumer metadata keys holding the subscription record. The topic stream
+// and the account are recorded with MetaAccount and MetaTopic.
+const (
+	MetaProtocol = "sns.protocol"
+	MetaEndpoint = "sns.endpoint"
+	MetaOwner    = "sns.owner"
+
+	MetaStatus         = "sns.status"          // SubscriptionPendingConfirmation until confirmed
+	MetaConfirmToken   = "sns.confirm_token"   // one-time token sent in the SubscriptionConfirmation
+	MetaConfirmExpires = "sns.confirm_expires" // RFC 3339 time the pending subscription is deleted
+	MetaConfirmedSeq   = "sns.confirmed_seq"   // last stream sequence when the subscription was confirmed
+)
+
+// SubscriptionPendingConfirmation marks a subscription whose endpoint has not presented the
+// confirmation token yet. Subscriptions without a status are confirmed.
+const SubscriptionPendingConfirmation = "PendingConfirmation"
+
+// DefaultConfirmationExpiry is how long an unconfirmed subscription is kept.
+const DefaultConfirmationExpiry = 72 * time.Hour
+
+// NeedsConfirmation reports whether subscriptions of the protocol must be confirmed by the endpoint.
+func NeedsConfirmation(protocol string) bool {
+	return protocol == ProtocolHTTP || protocol == ProtocolHTTPS
+}
+
+// IsPendingConfirmation reports whether the consumer metadata is an unconfirmed subscription.
+func IsPendingConfirmation(md map[string]string) bool {
+	return md[MetaStatus] == SubscriptionPendingConfirmation
+}
+
+// ConfirmedSequence returns the last stream sequence published before the subscription was
+// confirmed. Those messages were published while it was pending and are not delivered.
+func ConfirmedSequence(md map[string]string) uint64 {
+	seq, _ := strconv.ParseUint(md[MetaConfirmedSeq], 10, 64)
+	return seq
+}
+
+// ConfirmationExpired reports whether an unconfirmed subscription outlived its expiry.
+func ConfirmationExpired(md map[string]string, now time.Time) bool {
+	expires, err := time.Parse(time.RFC3339, md[MetaConfirmExpires])
+	return err != nil || now.After(expires)
+}
+
+// SubscribeURL is the link of the SubscriptionConfirmation that confirms the subscription.
+// baseURL is the public URL of the API version, for example http://localhost:8080/v1.
+func SubscribeURL(baseURL string, topic SRN, token string) string {
+	q := url.Values{}
+	q.Set("Action", "confirmSubscription")
+	q.Set("TopicSrn", topic.String())
+	q.Set("Token", token)
+	return fmt.Sprintf("%s/%s/%s?%s", baseURL, url.PathEscape(topic.Account), url.PathEscape(topic.Topic), q.Encode())
+}
+
+// ValidateEndpoint checks that the endpoint can be used with the protocol.
+func ValidateEndpoint(protocol, endpoint string) error {
+	switch protocol {
+	case ProtocolHTTP, ProtocolHTTPS:
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Scheme != protocol || u.Host == "" {
+			return fmt.Errorf("%w: endpoint must be an absolute %s:// URL", ErrInvalidParameter, protocol)
+		}
+		return nil
+	case ProtocolQueue:
+		return ValidateQueueName(endpoint)
+	default:
+		return fmt.Errorf("%w: unsupported protocol %q", ErrInvalidParameter, protocol)
+	}
+}
@@ -0,0 +1,87 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Subscription attribute names accepted by subscribe, getSubscriptionAttributes and setSubscriptionAttributes.
+const (
+	AttrDeliveryPolicy     = "DeliveryPolicy"     // retry schedule and throttling of http/https deliveries
+	AttrRedrivePolicy      = "RedrivePolicy"      // dead-letter topic of messages that exhausted their deliveries
+	AttrFilterPolicy       = "FilterPolicy"       // only messages that match are delivered
+	AttrFilterPolicyScope  = "FilterPolicyScope"  // MessageAttributes or MessageBody
+	AttrRawMessageDelivery = "RawMessageDelivery" // true to deliver the message body without the Notification envelope
+	AttrVisibilityTimeout  = "VisibilityTimeout"  // queue only, seconds a received message stays invisible (AckWait)
+
+	// Read-only attributes
+	AttrSubscriptionSrn     = "SubscriptionSrn"
+	AttrProtocol            = "Protocol"
+	AttrEndpoint            = "Endpoint"
+	AttrPendingConfirmation = "PendingConfirmation" // true until the endpoint confirms the subscription
+)
+
+// Consumer metadata keys holding the subscription attributes as JSON.
+const (
+	MetaDeliveryPolicy     = "sns.delivery_policy"
+	MetaRedrivePolicy      = "sns.redrive_policy"
+	MetaFilterPolicy       = "sns.filter_policy"
+	MetaFilterPolicyScope  = "sns.filter_policy_scope"
+	MetaRawMessageDelivery = "sns.raw_message_delivery"
+	MetaVisibilityTimeout  = "sns.visibility_timeout"
+)
+
+// FilterPolicyScope values. The FilterPolicy matches the message attributes unless the scope is MessageBody.
+const (
+	FilterScopeMessageAttributes = "MessageAttributes"
+	FilterScopeMessageBody       = "MessageBody"
+)
+
+// SubscriptionAttributeMeta maps the settable subscription attributes to their metadata key.
+var SubscriptionAttributeMeta = map[string]string{
+	AttrDeliveryPolicy:     MetaDeliveryPolicy,
+	AttrRedrivePolicy:      MetaRedrivePolicy,
+	AttrFilterPolicy:       MetaFilterPolicy,
+	AttrFilterPolicyScope:  MetaFilterPolicyScope,
+	AttrRawMessageDelivery: MetaRawMessageDelivery,
+	AttrVisibilityTimeout:  MetaVisibilityTimeout,
+}
+
+// ReadOnlySubscriptionAttributes are reported by getSubscriptionAttributes but can never be set.
+var ReadOnlySubscriptionAttributes = map[string]bool{
+	AttrSubscriptionSrn:     true,
+	AttrTopicSrn:            true,
+	AttrOwner:               true,
+	AttrProtocol:            true,
+	AttrEndpoint:            true,
+	AttrPendingConfirmation: true,
+}
+
+// RedrivePolicy is the RedrivePolicy subscription attribute.
+//
+//	{"deadLetterTargetSrn": "srn:scp:sns:<region>:<account>:<topic>"}
+type RedrivePolicy struct {
+	DeadLetterTargetSrn string `json:"deadLetterTargetSrn"`
+}
+
+// ParseRedrivePolicy decodes a RedrivePolicy attribute value and returns it with the dead-letter topic.
+func ParseRedrivePolicy(value string) (RedrivePolicy, SRN, error) {
+	var p RedrivePolicy
+	if err := json.Unmarshal([]byte(value), &p); err != nil {
+		return RedrivePolicy{}, SRN{}, fmt.Errorf("%w: RedrivePolicy is not valid JSON", ErrInvalidParameter)
+	}
+	target, err := ParseSRN(p.DeadLetterTargetSrn)
+	if err != nil {
+		return RedrivePolicy{}, SRN{}, err
+	}
+	if target.IsSubscription() {
+		return RedrivePolicy{}, SRN{}, fmt.Errorf("%w: deadLetterTargetSrn must be a topic srn", ErrInvalidParameter)
+	}
+	return p, target, nil
+}
+
+// String returns the JSON form stored in the consumer metadata.
+func (p RedrivePolicy) String() string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
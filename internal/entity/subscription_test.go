@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeURL(t *testing.T) {
+	topic := NewTopicSRN("kr-west1", "acct", "orders")
+	got := SubscribeURL("http://localhost:8080/v1", topic, "abc")
+	assert.Equal(t, "http://localhost:8080/v1/acct/orders?Action=confirmSubscription&Token=abc&TopicSrn=srn%3Ascp%3Asns%3Akr-west1%3Aacct%3Aorders", got)
+}
+
+func TestConfirmationExpired(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	md := map[string]string{MetaStatus: SubscriptionPendingConfirmation, MetaConfirmExpires: "2026-01-02T03:04:06Z"}
+
+	assert.True(t, IsPendingConfirmation(md))
+	assert.False(t, ConfirmationExpired(md, now))
+	assert.True(t, ConfirmationExpired(md, now.Add(2*time.Second)))
+	assert.True(t, ConfirmationExpired(map[string]string{}, now))
+}
+
+func TestConfirmedSequence(t *testing.T) {
+	assert.EqualValues(t, 42, ConfirmedSequence(map[string]string{MetaConfirmedSeq: "42"}))
+	assert.Zero(t, ConfirmedSequence(map[string]string{}))
+}
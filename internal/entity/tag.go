@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Tag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+// TopicFilter narrows listTopics down to the topics carrying a tag.
+// An empty TagValue matches any value of TagKey.
+type TopicFilter struct {
+	TagKey   string
+	TagValue string
+}
+
+// MetaTagPrefix prefixes the stream metadata keys holding the topic tags.
+const MetaTagPrefix = "sns.tag."
+
+// Tag limits, following the SNS quotas.
+const (
+	MaxTagsPerResource = 50
+	MaxTagKeyLength    = 128
+	MaxTagValueLength  = 256
+	reservedTagPrefix  = "scp:"
+)
+
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// ValidateTagKey checks the length, characters and reserved prefix of a tag key.
+func ValidateTagKey(key string) error {
+	if key == "" || len(key) > MaxTagKeyLength || !tagPattern.MatchString(key) {
+		return fmt.Errorf("%w: tag key must be 1-%d letters, numbers, spaces or _.:/=+-@", ErrInvalidParameter, MaxTagKeyLength)
+	}
+	if strings.HasPrefix(strings.ToLower(key), reservedTagPrefix) {
+		return fmt.Errorf("%w: tag key prefix %s is reserved", ErrInvalidParameter, reservedTagPrefix)
+	}
+	return nil
+}
+
+// ValidateTags checks every tag and rejects duplicate keys.
+func ValidateTags(tags []Tag) error {
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if err := ValidateTagKey(tag.Key); err != nil {
+			return err
+		}
+		if len(tag.Value) > MaxTagValueLength || !tagPattern.MatchString(tag.Value) {
+			return fmt.Errorf("%w: tag value must be 0-%d letters, numbers, spaces or _.:/=+-@", ErrInvalidParameter, MaxTagValueLength)
+		}
+		if seen[tag.Key] {
+			return fmt.Errorf("%w: duplicate tag key %s", ErrInvalidParameter, tag.Key)
+		}
+		seen[tag.Key] = true
+	}
+	return nil
+}
+
+// TagsFromMetadata extracts the tags stored in stream metadata, sorted by key.
+func TagsFromMetadata(metadata map[string]string) []Tag {
+	tags := make([]Tag, 0)
+	for k, v := range metadata {
+		if key, ok := strings.CutPrefix(k, MetaTagPrefix); ok {
+			tags = append(tags, Tag{Key: key, Value: v})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return tags
+}
+
+// Matches reports whether the metadata carries the tag selected by the filter.
+func (f TopicFilter) Matches(metadata map[string]string) bool {
+	if f.TagKey == "" {
+		return true
+	}
+	value, ok := metadata[MetaTagPrefix+f.TagKey]
+	return ok && (f.TagValue == "" || value == f.TagValue)
+}
@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []Tag
+		wantErr bool
+	}{
+		{name: "valid", tags: []Tag{{Key: "team", Value: "payments"}, {Key: "cost center", Value: ""}}},
+		{name: "longest key and value", tags: []Tag{{Key: strings.Repeat("k", MaxTagKeyLength), Value: strings.Repeat("v", MaxTagValueLength)}}},
+		{name: "key too long", tags: []Tag{{Key: strings.Repeat("k", MaxTagKeyLength+1)}}, wantErr: true},
+		{name: "value too long", tags: []Tag{{Key: "team", Value: strings.Repeat("v", MaxTagValueLength+1)}}, wantErr: true},
+		{name: "empty key", tags: []Tag{{Key: "", Value: "payments"}}, wantErr: true},
+		{name: "invalid character", tags: []Tag{{Key: "team", Value: "pay;ments"}}, wantErr: true},
+		{name: "reserved prefix", tags: []Tag{{Key: "SCP:owner", Value: "acct"}}, wantErr: true},
+		{name: "duplicate key", tags: []Tag{{Key: "team", Value: "a"}, {Key: "team", Value: "b"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTags(tt.tags)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidParameter)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTagsFromMetadata(t *testing.T) {
+	metadata := map[string]string{
+		MetaAccount:                 "acct",
+		MetaTopic:                   "orders",
+		MetaTagPrefix + "team":      "payments",
+		MetaTagPrefix + MetaAccount: "other",
+		"_nats.req.level":           "1",
+	}
+	// only the keys under the tag prefix are tags, so a tag can never shadow the owner
+	assert.Equal(t, []Tag{{Key: "sns.account", Value: "other"}, {Key: "team", Value: "payments"}}, TagsFromMetadata(metadata))
+}
+
+func TestTopicFilterMatches(t *testing.T) {
+	metadata := map[string]string{MetaTagPrefix + "team": "payments"}
+
+	assert.True(t, TopicFilter{}.Matches(metadata))
+	assert.True(t, TopicFilter{TagKey: "team"}.Matches(metadata))
+	assert.True(t, TopicFilter{TagKey: "team", TagValue: "payments"}.Matches(metadata))
+	assert.False(t, TopicFilter{TagKey: "team", TagValue: "orders"}.Matches(metadata))
+	assert.False(t, TopicFilter{TagKey: "env"}.Matches(metadata))
+	assert.False(t, TopicFilter{TagKey: "team"}.Matches(map[string]string{"team": "payments"}))
+}
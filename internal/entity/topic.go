@@ -1,5 +1,145 @@
 package entity
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
 type Topic struct {
 	TopicSrn string `json:"TopicSrn"`
 }
+
+// CreateTopicInput is a topic requested by the createTopic action.
+// Subjects are relative to the account namespace and default to the topic name.
+type CreateTopicInput struct {
+	Name       string
+	Subjects   []string
+	Attributes map[string]string
+	Tags       []Tag
+}
+
+// Stream metadata keys used to record which account owns a topic stream.
+const (
+	MetaAccount = "sns.account"
+	MetaTopic   = "sns.topic"
+)
+
+// Stream metadata keys describing FIFO topics.
+const (
+	MetaFifo                      = "sns.fifo"
+	MetaContentBasedDeduplication = "sns.content_dedup"
+)
+
+// FifoSuffix is the mandatory name suffix of FIFO topics.
+const FifoSuffix = ".fifo"
+
+// SubjectRoot is the first subject token of every topic stream. Each account
+// owns the "sns.data.<account>.>" subject space, so topics of different
+// accounts never capture each other's messages.
+const SubjectRoot = "sns.data"
+
+var (
+	accountIDPattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+	topicNamePattern = regexp.MustCompile(`^([A-Za-z0-9_-]{1,256}|[A-Za-z0-9_-]{1,251}\.fifo)$`)
+)
+
+// ValidateAccountID checks that the account id can be used as a stream name prefix and subject token.
+func ValidateAccountID(account string) error {
+	if !accountIDPattern.MatchString(account) {
+		return fmt.Errorf("%w: account id must be 1-64 alphanumeric characters or hyphens", ErrInvalidParameter)
+	}
+	return nil
+}
+
+// ValidateTopicName checks the SNS topic naming rules. Standard topic names cannot end
+// in _fifo, StreamName would map them to the stream of the FIFO topic of the same stem.
+func ValidateTopicName(name string) error {
+	if !topicNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: topic name must be 1-256 alphanumeric characters, hyphens or underscores, with an optional .fifo suffix", ErrInvalidParameter)
+	}
+	if strings.HasSuffix(name, streamFifoSuffix) {
+		return fmt.Errorf("%w: standard topic names cannot end in %s", ErrInvalidParameter, streamFifoSuffix)
+	}
+	return nil
+}
+
+// IsFifoTopic reports whether the topic name denotes a FIFO topic.
+func IsFifoTopic(name string) bool {
+	return strings.HasSuffix(name, FifoSuffix)
+}
+
+// streamFifoSuffix replaces FifoSuffix in stream names
+const streamFifoSuffix = "_fifo"
+
+// StreamName returns the JetStream stream name backing the account's topic.
+// Account ids never contain '_', so the first '_' always separates the two parts.
+// Stream names cannot contain '.', so the .fifo suffix is stored as _fifo.
+func StreamName(account, topic string) string {
+	return account + "_" + strings.ReplaceAll(topic, ".", "_")
+}
+
+// AccountSubjectPrefix returns the subject prefix owned by the account, including the trailing dot.
+func AccountSubjectPrefix(account string) string {
+	return SubjectRoot + "." + account + "."
+}
+
+// AccountSubject maps a subject relative to the account namespace to the subject stored in JetStream.
+func AccountSubject(account, subject string) string {
+	return AccountSubjectPrefix(account) + subject
+}
+
+// ValidatePublishSubject checks that the subject is a literal NATS subject.
+func ValidatePublishSubject(subject string) error {
+	if subject == "" || strings.ContainsAny(subject, "*> \t\r\n") {
+		return fmt.Errorf("%w: subject must be a literal subject without wildcards or whitespace", ErrInvalidParameter)
+	}
+	for _, token := range strings.Split(subject, ".") {
+		if token == "" {
+			return fmt.Errorf("%w: subject must not contain empty tokens", ErrInvalidParameter)
+		}
+	}
+	return nil
+}
+
+// MaxTopicSubjects limits the number of subject patterns a topic can own.
+const MaxTopicSubjects = 10
+
+// ValidateTopicSubject checks a subject pattern owned by the topic.
+// Patterns are rooted at the topic name, so that topics of an account never claim each
+// other's subjects. Wildcards must be whole tokens and '>' may only be the last token.
+func ValidateTopicSubject(topic, pattern string) error {
+	if pattern == "" || strings.ContainsAny(pattern, " \t\r\n") {
+		return fmt.Errorf("%w: topic subject must not be empty or contain whitespace", ErrInvalidParameter)
+	}
+	if pattern != topic && !strings.HasPrefix(pattern, topic+".") {
+		return fmt.Errorf("%w: topic subject %s must be %s or start with %s.", ErrInvalidParameter, pattern, topic, topic)
+	}
+	tokens := strings.Split(pattern, ".")
+	for i, token := range tokens {
+		switch {
+		case token == "":
+			return fmt.Errorf("%w: topic subject %s has an empty token", ErrInvalidParameter, pattern)
+		case token == ">" && i != len(tokens)-1:
+			return fmt.Errorf("%w: '>' must be the last token of topic subject %s", ErrInvalidParameter, pattern)
+		case token != "*" && token != ">" && strings.ContainsAny(token, "*>"):
+			return fmt.Errorf("%w: wildcards must be whole tokens in topic subject %s", ErrInvalidParameter, pattern)
+		}
+	}
+	return nil
+}
+
+// SubjectMatches reports whether the literal subject is captured by the pattern.
+func SubjectMatches(pattern, subject string) bool {
+	pTokens := strings.Split(pattern, ".")
+	sTokens := strings.Split(subject, ".")
+	for i, p := range pTokens {
+		if p == ">" {
+			return len(sTokens) > i
+		}
+		if i >= len(sTokens) || (p != "*" && p != sTokens[i]) {
+			return false
+		}
+	}
+	return len(pTokens) == len(sTokens)
+}
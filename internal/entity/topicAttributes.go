@@ -0,0 +1,37 @@
+package entity
+
+// Topic attribute names accepted by createTopic, getTopicAttributes and setTopicAttributes.
+const (
+	AttrRetentionPeriod    = "RetentionPeriod"    // seconds a message is kept (StreamConfig.MaxAge)
+	AttrMaximumMessageSize = "MaximumMessageSize" // bytes (StreamConfig.MaxMsgSize)
+	AttrMaxMessages        = "MaxMessages"        // -1 for unlimited (StreamConfig.MaxMsgs)
+	AttrMaxBytes           = "MaxBytes"           // -1 for unlimited (StreamConfig.MaxBytes)
+	AttrStorageType        = "StorageType"        // File or Memory (StreamConfig.Storage)
+	AttrReplicas           = "Replicas"           // 1-5 (StreamConfig.Replicas)
+	AttrDiscardPolicy      = "DiscardPolicy"      // Old or New (StreamConfig.Discard)
+
+	AttrFifoTopic                 = "FifoTopic"                 // true for .fifo topics
+	AttrContentBasedDeduplication = "ContentBasedDeduplication" // FIFO only, dedup id is the SHA-256 of the message
+
+	// Read-only attributes
+	AttrTopicSrn = "TopicSrn"
+	AttrOwner    = "Owner"
+	AttrMessages = "Messages"
+	AttrBytes    = "Bytes"
+	AttrSubjects = "Subjects" // comma separated subject patterns owned by the topic
+)
+
+// ImmutableTopicAttributes can only be chosen when the topic is created.
+var ImmutableTopicAttributes = map[string]bool{
+	AttrStorageType: true,
+	AttrFifoTopic:   true,
+}
+
+// ReadOnlyTopicAttributes are reported by getTopicAttributes but can never be set.
+var ReadOnlyTopicAttributes = map[string]bool{
+	AttrTopicSrn: true,
+	AttrOwner:    true,
+	AttrMessages: true,
+	AttrBytes:    true,
+	AttrSubjects: true,
+}
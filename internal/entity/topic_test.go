@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubjectMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		want    bool
+	}{
+		{"orders", "orders", true},
+		{"orders", "orders.created", false},
+		{"orders.>", "orders.created", true},
+		{"orders.>", "orders.eu.created", true},
+		{"orders.>", "orders", false},
+		{"orders.*.created", "orders.eu.created", true},
+		{"orders.*.created", "orders.eu.deleted", false},
+		{"orders.*", "orders.eu.created", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SubjectMatches(tt.pattern, tt.subject), "%s ~ %s", tt.pattern, tt.subject)
+	}
+}
+
+func TestValidateTopicSubject(t *testing.T) {
+	for _, pattern := range []string{"orders", "orders.>", "orders.*.created"} {
+		assert.NoError(t, ValidateTopicSubject("orders", pattern), pattern)
+	}
+	for _, pattern := range []string{"", "orders..created", "orders.>.created", "orders.eu*", "or ders", "orders."} {
+		assert.ErrorIs(t, ValidateTopicSubject("orders", pattern), ErrInvalidParameter, pattern)
+	}
+	// subjects outside the topic name would capture the subjects of other topics
+	for _, pattern := range []string{"*", ">", "payments", "payments.>", "orders-eu", "ordersx.>", "*.created"} {
+		assert.ErrorIs(t, ValidateTopicSubject("orders", pattern), ErrInvalidParameter, pattern)
+	}
+	assert.NoError(t, ValidateTopicSubject("orders.fifo", "orders.fifo.>"))
+	assert.ErrorIs(t, ValidateTopicSubject("orders.fifo", "orders.>"), ErrInvalidParameter)
+}
+
+func TestValidateTopicName(t *testing.T) {
+	for _, name := range []string{"orders", "orders-eu", "orders_eu", "orders.fifo", "orders_fifo.fifo"} {
+		assert.NoError(t, ValidateTopicName(name), name)
+	}
+	for _, name := range []string{"", "orders.eu", "orders_fifo", "orders.fifo.fifo"} {
+		assert.ErrorIs(t, ValidateTopicName(name), ErrInvalidParameter, name)
+	}
+}
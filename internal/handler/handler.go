@@ -6,22 +6,50 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-func AccountBaseHandlers(topicSvc service.TopicService) map[string]func() echo.HandlerFunc {
+func AccountBaseHandlers(topicSvc service.TopicService, messageMoveSvc service.MessageMoveService) map[string]func() echo.HandlerFunc {
 	topicHandler := NewTopicHandler(topicSvc)
+	messageMoveHandler := NewMessageMoveHandler(messageMoveSvc)
 
 	return map[string]func() echo.HandlerFunc{
-		"createTopic": topicHandler.Create,
-		"listTopics":  topicHandler.List,
+		"createTopic":           topicHandler.Create,
+		"listTopics":            topicHandler.List,
+		"startMessageMoveTask":  messageMoveHandler.Start,
+		"listMessageMoveTasks":  messageMoveHandler.List,
+		"cancelMessageMoveTask": messageMoveHandler.Cancel,
 	}
 }
 
-func AccountTopicBaseHandlers(topicSvc service.TopicService, publishSvc service.PublishService) map[string]func() echo.HandlerFunc {
+func AccountTopicBaseHandlers(topicSvc service.TopicService, publishSvc service.PublishService, subscriptionSvc service.SubscriptionService, queueSvc service.QueueService, pullSvc service.PullService, streamSvc service.StreamService) map[string]func() echo.HandlerFunc {
 	topicHandler := NewTopicHandler(topicSvc)
 	publishHandler := NewPublishHandler(publishSvc)
+	subscriptionHandler := NewSubscriptionHandler(subscriptionSvc)
+	queueHandler := NewQueueHandler(queueSvc)
+	pullHandler := NewPullHandler(pullSvc)
+	streamHandler := NewStreamHandler(streamSvc)
 
 	return map[string]func() echo.HandlerFunc{
-		"deleteTopic":  topicHandler.Delete,
-		"publish":      publishHandler.Publish,
-		"publishCheck": publishHandler.CheckAckStatus,
+		"deleteTopic":               topicHandler.Delete,
+		"getTopicAttributes":        topicHandler.GetAttributes,
+		"setTopicAttributes":        topicHandler.SetAttributes,
+		"tagResource":               topicHandler.TagResource,
+		"untagResource":             topicHandler.UntagResource,
+		"listTagsForResource":       topicHandler.ListTagsForResource,
+		"publish":                   publishHandler.Publish,
+		"publishBatch":              publishHandler.PublishBatch,
+		"publishCheck":              publishHandler.CheckAckStatus,
+		"subscribe":                 subscriptionHandler.Subscribe,
+		"unsubscribe":               subscriptionHandler.Unsubscribe,
+		"confirmSubscription":       subscriptionHandler.Confirm,
+		"listSubscriptionsByTopic":  subscriptionHandler.ListByTopic,
+		"getSubscriptionAttributes": subscriptionHandler.GetAttributes,
+		"setSubscriptionAttributes": subscriptionHandler.SetAttributes,
+		"receiveMessage":            queueHandler.Receive,
+		"deleteMessage":             queueHandler.Delete,
+		"changeMessageVisibility":   queueHandler.ChangeVisibility,
+		"receive":                   pullHandler.Receive,
+		"ack":                       pullHandler.Ack,
+		"nak":                       pullHandler.Nak,
+		"stream":                    streamHandler.SSE,
+		"streamWebSocket":           streamHandler.WebSocket,
 	}
 }
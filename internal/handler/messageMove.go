@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"nats/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type MessageMoveHandler struct {
+	svc service.MessageMoveService
+}
+
+func NewMessageMoveHandler(svc service.MessageMoveService) *MessageMoveHandler {
+	return &MessageMoveHandler{svc: svc}
+}
+
+type StartMessageMoveTaskRequest struct {
+	SourceSrn                    string `json:"SourceSrn" validate:"required"`
+	DestinationSrn               string `json:"DestinationSrn"`
+	MaxNumberOfMessagesPerSecond int    `json:"MaxNumberOfMessagesPerSecond"`
+}
+
+type StartMessageMoveTaskResult struct {
+	TaskHandle string `json:"TaskHandle"`
+}
+
+type StartMessageMoveTaskResponse struct {
+	StartMessageMoveTaskResult StartMessageMoveTaskResult `json:"StartMessageMoveTaskResult"`
+	ResponseMetadata           entity.ResponseMetadata    `json:"ResponseMetadata"`
+}
+
+type ListMessageMoveTasksRequest struct {
+	SourceSrn  string `json:"SourceSrn" validate:"required"`
+	MaxResults int    `json:"MaxResults"`
+}
+
+type ListMessageMoveTasksResult struct {
+	Results []entity.MessageMoveTask `json:"Results"`
+}
+
+type ListMessageMoveTasksResponse struct {
+	ListMessageMoveTasksResult ListMessageMoveTasksResult `json:"ListMessageMoveTasksResult"`
+	ResponseMetadata           entity.ResponseMetadata    `json:"ResponseMetadata"`
+}
+
+type CancelMessageMoveTaskRequest struct {
+	TaskHandle string `json:"TaskHandle" validate:"required"`
+}
+
+type CancelMessageMoveTaskResult struct {
+	ApproximateNumberOfMessagesMoved int64 `json:"ApproximateNumberOfMessagesMoved"`
+}
+
+type CancelMessageMoveTaskResponse struct {
+	CancelMessageMoveTaskResult CancelMessageMoveTaskResult `json:"CancelMessageMoveTaskResult"`
+	ResponseMetadata            entity.ResponseMetadata     `json:"ResponseMetadata"`
+}
+
+func (h *MessageMoveHandler) Start() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req StartMessageMoveTaskRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid startMessageMoveTask request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		input := entity.StartMessageMoveTaskInput{MaxNumberOfMessagesPerSecond: req.MaxNumberOfMessagesPerSecond}
+		source, err := entity.ParseSRN(req.SourceSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid source topic reference", zap.String("sourceSrn", req.SourceSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+		input.SourceSrn = source
+		if req.DestinationSrn != "" {
+			dest, err := entity.ParseSRN(req.DestinationSrn)
+			if err != nil {
+				logs.GetLogger(ctx).Warn("Invalid destination topic reference", zap.String("destinationSrn", req.DestinationSrn), zap.Error(err))
+				return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+			}
+			input.DestinationSrn = &dest
+		}
+
+		handle, err := h.svc.StartMessageMoveTask(ctx, c.Param("accountid"), input)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to start message move task", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Message move task started", zap.String("taskHandle", handle))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, StartMessageMoveTaskResponse{
+			StartMessageMoveTaskResult: StartMessageMoveTaskResult{TaskHandle: handle}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *MessageMoveHandler) List() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ListMessageMoveTasksRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid listMessageMoveTasks request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		source, err := entity.ParseSRN(req.SourceSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid source topic reference", zap.String("sourceSrn", req.SourceSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		tasks, err := h.svc.ListMessageMoveTasks(ctx, c.Param("accountid"), source, req.MaxResults)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Message move task list lookup failed", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ListMessageMoveTasksResponse{
+			ListMessageMoveTasksResult: ListMessageMoveTasksResult{Results: tasks}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *MessageMoveHandler) Cancel() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req CancelMessageMoveTaskRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid cancelMessageMoveTask request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		moved, err := h.svc.CancelMessageMoveTask(ctx, c.Param("accountid"), req.TaskHandle)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to cancel message move task", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Message move task cancel requested", zap.String("taskHandle", req.TaskHandle))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, CancelMessageMoveTaskResponse{
+			CancelMessageMoveTaskResult: CancelMessageMoveTaskResult{ApproximateNumberOfMessagesMoved: moved}, ResponseMetadata: meta,
+		})
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"nats/internal/context/logs"
+	"nats/internal/entity"
 	"nats/internal/service"
 	"net/http"
 
@@ -18,9 +19,13 @@ func NewPublishHandler(svc service.PublishService) *PublishHandler {
 }
 
 type PublishRequest struct {
-	TopicName string `json:"topicName"`
-	Message   string `json:"message"`
-	Subject   string `json:"subject"`
+	TopicName              string `json:"topicName"`
+	Message                string `json:"message"`
+	Subject                string `json:"subject"`
+	MessageGroupId         string `json:"messageGroupId"`
+	MessageDeduplicationId string `json:"messageDeduplicationId"`
+
+	MessageAttributes map[string]entity.MessageAttributeValue `json:"messageAttributes"`
 }
 
 type PublishResponse struct {
@@ -38,10 +43,18 @@ func (h *PublishHandler) Publish() echo.HandlerFunc {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
 		}
 
-		msgID, err := h.svc.PublishAsyncMessage(ctx, req.TopicName, req.Message, req.Subject)
+		msgID, err := h.svc.PublishAsyncMessage(ctx, c.Param("accountid"), entity.PublishInput{
+			TopicName:              req.TopicName,
+			Message:                req.Message,
+			Subject:                req.Subject,
+			MessageGroupId:         req.MessageGroupId,
+			MessageDeduplicationId: req.MessageDeduplicationId,
+			MessageAttributes:      req.MessageAttributes,
+		})
 		if err != nil {
 			logger.Error("메시지 발행 실패", zap.Error(err))
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
 		}
 
 		logger.Info("메시지 발행 성공", zap.String("messageId", msgID))
@@ -49,6 +62,94 @@ func (h *PublishHandler) Publish() echo.HandlerFunc {
 	}
 }
 
+type PublishBatchRequest struct {
+	TopicName string                     `json:"topicName"`
+	Entries   []PublishBatchRequestEntry `json:"entries"`
+}
+
+type PublishBatchRequestEntry struct {
+	Id                     string `json:"id"`
+	Message                string `json:"message"`
+	Subject                string `json:"subject"`
+	MessageGroupId         string `json:"messageGroupId"`
+	MessageDeduplicationId string `json:"messageDeduplicationId"`
+
+	MessageAttributes map[string]entity.MessageAttributeValue `json:"messageAttributes"`
+}
+
+type PublishBatchResultEntry struct {
+	Id        string `json:"Id"`
+	MessageId string `json:"MessageId"`
+}
+
+type BatchResultErrorEntry struct {
+	Id          string `json:"Id"`
+	Code        string `json:"Code"`
+	Message     string `json:"Message"`
+	SenderFault bool   `json:"SenderFault"`
+}
+
+type PublishBatchResult struct {
+	Successful []PublishBatchResultEntry `json:"Successful"`
+	Failed     []BatchResultErrorEntry   `json:"Failed"`
+}
+
+type PublishBatchResponse struct {
+	PublishBatchResult PublishBatchResult      `json:"PublishBatchResult"`
+	ResponseMetadata   entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+// PublishBatch answers 200 when the batch is accepted, even if some entries failed
+func (h *PublishHandler) PublishBatch() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		logger := logs.GetLogger(ctx)
+
+		var req PublishBatchRequest
+		if err := c.Bind(&req); err != nil {
+			logger.Warn("Invalid publishBatch request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		input := entity.PublishBatchInput{TopicName: req.TopicName, Entries: make([]entity.PublishBatchEntry, 0, len(req.Entries))}
+		for _, e := range req.Entries {
+			input.Entries = append(input.Entries, entity.PublishBatchEntry{
+				Id:                     e.Id,
+				Message:                e.Message,
+				Subject:                e.Subject,
+				MessageGroupId:         e.MessageGroupId,
+				MessageDeduplicationId: e.MessageDeduplicationId,
+				MessageAttributes:      e.MessageAttributes,
+			})
+		}
+
+		results, err := h.svc.PublishBatch(ctx, c.Param("accountid"), input)
+		if err != nil {
+			logger.Error("Failed to publish batch", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		result := PublishBatchResult{Successful: []PublishBatchResultEntry{}, Failed: []BatchResultErrorEntry{}}
+		for _, r := range results {
+			if r.Err == nil {
+				result.Successful = append(result.Successful, PublishBatchResultEntry{Id: r.Id, MessageId: r.MessageId})
+				continue
+			}
+			resp := entity.ErrorResponseOf(r.Err)
+			entry := BatchResultErrorEntry{Id: r.Id, Code: resp.Error.Code, Message: resp.Error.Message, SenderFault: resp.HTTPCode < 500}
+			if entry.SenderFault {
+				entry.Message = r.Err.Error()
+			}
+			result.Failed = append(result.Failed, entry)
+		}
+
+		logger.Info("Batch published", zap.Int("successful", len(result.Successful)), zap.Int("failed", len(result.Failed)))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, PublishBatchResponse{PublishBatchResult: result, ResponseMetadata: meta})
+	}
+}
+
 func (h *PublishHandler) CheckAckStatus() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
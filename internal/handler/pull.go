@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"nats/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type PullHandler struct {
+	svc service.PullService
+}
+
+func NewPullHandler(svc service.PullService) *PullHandler {
+	return &PullHandler{svc: svc}
+}
+
+type ReceiveRequest struct {
+	TopicSrn            string `json:"TopicSrn" validate:"required"`
+	ConsumerName        string `json:"ConsumerName"`
+	MaxNumberOfMessages int    `json:"MaxNumberOfMessages"`
+	WaitTimeSeconds     int    `json:"WaitTimeSeconds"`
+}
+
+type ReceiveResult struct {
+	Messages []entity.PullMessage `json:"Messages"`
+}
+
+type ReceiveResponse struct {
+	ReceiveResult    ReceiveResult           `json:"ReceiveResult"`
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type SettleRequest struct {
+	TopicSrn  string   `json:"TopicSrn" validate:"required"`
+	AckTokens []string `json:"AckTokens" validate:"required"`
+}
+
+type SettleResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+func (h *PullHandler) Receive() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ReceiveRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid receive request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.TopicSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("topicSrn", req.TopicSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		messages, err := h.svc.Receive(ctx, c.Param("accountid"), srn, entity.PullInput{
+			ConsumerName:        req.ConsumerName,
+			MaxNumberOfMessages: req.MaxNumberOfMessages,
+			WaitTimeSeconds:     req.WaitTimeSeconds,
+		})
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to receive messages", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ReceiveResponse{ReceiveResult: ReceiveResult{Messages: messages}, ResponseMetadata: meta})
+	}
+}
+
+func (h *PullHandler) Ack() echo.HandlerFunc {
+	return h.settle("ack", h.svc.Ack)
+}
+
+func (h *PullHandler) Nak() echo.HandlerFunc {
+	return h.settle("nak", h.svc.Nak)
+}
+
+func (h *PullHandler) settle(action string, settle func(ctx context.Context, account string, topic entity.SRN, tokens []string) error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req SettleRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid "+action+" request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.TopicSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("topicSrn", req.TopicSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := settle(ctx, c.Param("accountid"), srn, req.AckTokens); err != nil {
+			logs.GetLogger(ctx).Error("Failed to "+action+" messages", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, SettleResponse{ResponseMetadata: meta})
+	}
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"nats/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type QueueHandler struct {
+	svc service.QueueService
+}
+
+func NewQueueHandler(svc service.QueueService) *QueueHandler {
+	return &QueueHandler{svc: svc}
+}
+
+type ReceiveMessageRequest struct {
+	SubscriptionSrn     string `json:"SubscriptionSrn" validate:"required"`
+	MaxNumberOfMessages int    `json:"MaxNumberOfMessages"`
+	WaitTimeSeconds     int    `json:"WaitTimeSeconds"`
+}
+
+type ReceiveMessageResult struct {
+	Messages []entity.QueueMessage `json:"Messages"`
+}
+
+type ReceiveMessageResponse struct {
+	ReceiveMessageResult ReceiveMessageResult    `json:"ReceiveMessageResult"`
+	ResponseMetadata     entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type DeleteMessageRequest struct {
+	SubscriptionSrn string `json:"SubscriptionSrn" validate:"required"`
+	ReceiptHandle   string `json:"ReceiptHandle" validate:"required"`
+}
+
+type DeleteMessageResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type ChangeMessageVisibilityRequest struct {
+	SubscriptionSrn   string `json:"SubscriptionSrn" validate:"required"`
+	ReceiptHandle     string `json:"ReceiptHandle" validate:"required"`
+	VisibilityTimeout int    `json:"VisibilityTimeout"`
+}
+
+type ChangeMessageVisibilityResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+func (h *QueueHandler) Receive() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ReceiveMessageRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid receiveMessage request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.SubscriptionSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid subscription reference", zap.String("subscriptionSrn", req.SubscriptionSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		messages, err := h.svc.ReceiveMessages(ctx, c.Param("accountid"), srn, entity.ReceiveMessageInput{
+			MaxNumberOfMessages: req.MaxNumberOfMessages,
+			WaitTimeSeconds:     req.WaitTimeSeconds,
+		})
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to receive messages", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ReceiveMessageResponse{
+			ReceiveMessageResult: ReceiveMessageResult{Messages: messages}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *QueueHandler) Delete() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req DeleteMessageRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid deleteMessage request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.SubscriptionSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid subscription reference", zap.String("subscriptionSrn", req.SubscriptionSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.DeleteMessage(ctx, c.Param("accountid"), srn, req.ReceiptHandle); err != nil {
+			logs.GetLogger(ctx).Error("Failed to delete message", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, DeleteMessageResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *QueueHandler) ChangeVisibility() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ChangeMessageVisibilityRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid changeMessageVisibility request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.SubscriptionSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid subscription reference", zap.String("subscriptionSrn", req.SubscriptionSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.ChangeMessageVisibility(ctx, c.Param("accountid"), srn, req.ReceiptHandle, req.VisibilityTimeout); err != nil {
+			logs.GetLogger(ctx).Error("Failed to change message visibility", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ChangeMessageVisibilityResponse{ResponseMetadata: meta})
+	}
+}
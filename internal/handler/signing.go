@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"fmt"
+	"nats/internal/entity"
+	"nats/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SigningCertPath is the path of the signing certificate below the API version
+const SigningCertPath = "/signing-cert.pem"
+
+type SigningHandler struct {
+	signer *service.NotificationSigner
+}
+
+// NewSigningHandler serves the certificate of the signer, signer is nil when signing is disabled
+func NewSigningHandler(signer *service.NotificationSigner) *SigningHandler {
+	return &SigningHandler{signer: signer}
+}
+
+// Certificate returns the PEM certificate receivers verify notification signatures with
+func (h *SigningHandler) Certificate() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.signer == nil {
+			resp := entity.ErrorResponseOf(fmt.Errorf("%w: notification signing is disabled", entity.ErrNotFound))
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+		return c.Blob(http.StatusOK, "application/x-pem-file", h.signer.CertificatePEM())
+	}
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"nats/internal/context/logs"
+	"nats/internal/context/metrics"
+	"nats/internal/entity"
+	"nats/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+// streamKeepAlive is how often an idle SSE stream sends a comment so that proxies keep it open
+const streamKeepAlive = 15 * time.Second
+
+// HeaderLastEventID is sent by EventSource when it reconnects
+const HeaderLastEventID = "Last-Event-ID"
+
+type StreamHandler struct {
+	svc service.StreamService
+}
+
+func NewStreamHandler(svc service.StreamService) *StreamHandler {
+	return &StreamHandler{svc: svc}
+}
+
+// StreamRequest is read from the query string, browsers cannot send a body with
+// EventSource or WebSocket. The Last-Event-ID header takes precedence over LastEventId.
+type StreamRequest struct {
+	TopicSrn    string `query:"TopicSrn" validate:"required"`
+	LastEventId string `query:"LastEventId"`
+}
+
+// SSE streams the topic as text/event-stream. The event id is the stream sequence.
+func (h *StreamHandler) SSE() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		ts, err := h.open(c)
+		if err != nil {
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+		defer ts.Close()
+
+		metrics.ActiveStreams.WithLabelValues("sse").Inc()
+		defer metrics.ActiveStreams.WithLabelValues("sse").Dec()
+
+		w := c.Response()
+		w.Header().Set(echo.HeaderContentType, "text/event-stream")
+		w.Header().Set(echo.HeaderCacheControl, "no-cache")
+		w.Header().Set(echo.HeaderConnection, "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		w.Flush()
+
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return nil
+				}
+			case event := <-ts.Events():
+				data, err := json.Marshal(event)
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.SequenceNumber, entity.MessageTypeNotification, data); err != nil {
+					return nil
+				}
+			}
+			w.Flush()
+		}
+	}
+}
+
+// WebSocket streams the topic as one JSON text frame per message. Frames sent by the
+// client are ignored; reading them is how a disconnect is noticed.
+func (h *StreamHandler) WebSocket() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ts, err := h.open(c)
+		if err != nil {
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+		defer ts.Close()
+
+		// websocket.Server without Handshake accepts any Origin; non-browser clients send none
+		websocket.Server{Handler: func(ws *websocket.Conn) {
+			metrics.ActiveStreams.WithLabelValues("websocket").Inc()
+			defer metrics.ActiveStreams.WithLabelValues("websocket").Dec()
+
+			ctx, cancel := context.WithCancel(c.Request().Context())
+			defer cancel()
+			go func() {
+				defer cancel()
+				var discard string
+				for websocket.Message.Receive(ws, &discard) == nil {
+				}
+			}()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-ts.Events():
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						logs.GetLogger(ctx).Debug("WebSocket stream closed", zap.Error(err))
+						return
+					}
+				}
+			}
+		}}.ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+}
+
+// open parses the request and attaches the consumer of the stream
+func (h *StreamHandler) open(c echo.Context) (service.TopicStream, error) {
+	ctx := c.Request().Context()
+
+	var req StreamRequest
+	if err := c.Bind(&req); err != nil {
+		logs.GetLogger(ctx).Error("Invalid stream request parameter", zap.Error(err))
+		return nil, entity.ErrInvalidParameter
+	}
+	if err := c.Validate(&req); err != nil {
+		logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+		return nil, entity.ErrInvalidParameter
+	}
+
+	srn, err := entity.ParseSRN(req.TopicSrn)
+	if err != nil {
+		logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("topicSrn", req.TopicSrn), zap.Error(err))
+		return nil, entity.ErrInvalidParameter
+	}
+	lastEventId := req.LastEventId
+	if id := c.Request().Header.Get(HeaderLastEventID); id != "" {
+		lastEventId = id
+	}
+	after, err := entity.ParseLastEventId(lastEventId)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := h.svc.Open(ctx, c.Param("accountid"), srn, after)
+	if err != nil {
+		logs.GetLogger(ctx).Error("Failed to open topic stream", zap.Error(err))
+		return nil, err
+	}
+	return ts, nil
+}
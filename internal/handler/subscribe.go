@@ -0,0 +1,307 @@
+package handler
+
+import (
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"nats/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type SubscriptionHandler struct {
+	svc service.SubscriptionService
+}
+
+func NewSubscriptionHandler(svc service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{svc: svc}
+}
+
+type SubscribeRequest struct {
+	TopicSrn   string            `json:"TopicSrn" validate:"required"`
+	Protocol   string            `json:"Protocol" validate:"required"`
+	Endpoint   string            `json:"Endpoint" validate:"required"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+type SubscribeResult struct {
+	SubscriptionSrn string `json:"SubscriptionSrn"`
+}
+
+type SubscribeResponse struct {
+	SubscribeResult  SubscribeResult         `json:"SubscribeResult"`
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type UnsubscribeRequest struct {
+	SubscriptionSrn string `json:"SubscriptionSrn" validate:"required"`
+}
+
+type UnsubscribeResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type GetSubscriptionAttributesRequest struct {
+	SubscriptionSrn string `json:"SubscriptionSrn" validate:"required"`
+}
+
+type GetSubscriptionAttributesResult struct {
+	Attributes map[string]string `json:"Attributes"`
+}
+
+type GetSubscriptionAttributesResponse struct {
+	GetSubscriptionAttributesResult GetSubscriptionAttributesResult `json:"GetSubscriptionAttributesResult"`
+	ResponseMetadata                entity.ResponseMetadata         `json:"ResponseMetadata"`
+}
+
+type SetSubscriptionAttributesRequest struct {
+	SubscriptionSrn string `json:"SubscriptionSrn" validate:"required"`
+	AttributeName   string `json:"AttributeName" validate:"required"`
+	AttributeValue  string `json:"AttributeValue"`
+}
+
+type SetSubscriptionAttributesResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+// ConfirmSubscriptionRequest is also bound from the query of the SubscribeURL
+type ConfirmSubscriptionRequest struct {
+	TopicSrn string `json:"TopicSrn" query:"TopicSrn" validate:"required"`
+	Token    string `json:"Token" query:"Token" validate:"required"`
+}
+
+type ConfirmSubscriptionResult struct {
+	SubscriptionSrn string `json:"SubscriptionSrn"`
+}
+
+type ConfirmSubscriptionResponse struct {
+	ConfirmSubscriptionResult ConfirmSubscriptionResult `json:"ConfirmSubscriptionResult"`
+	ResponseMetadata          entity.ResponseMetadata   `json:"ResponseMetadata"`
+}
+
+type ListSubscriptionsByTopicRequest struct {
+	TopicSrn string `json:"TopicSrn" validate:"required"`
+}
+
+type ListSubscriptionsByTopicResult struct {
+	Subscriptions []entity.Subscription `json:"Subscriptions"`
+}
+
+type ListSubscriptionsByTopicResponse struct {
+	ListSubscriptionsByTopicResult ListSubscriptionsByTopicResult `json:"ListSubscriptionsByTopicResult"`
+	ResponseMetadata               entity.ResponseMetadata        `json:"ResponseMetadata"`
+}
+
+func (h *SubscriptionHandler) Subscribe() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req SubscribeRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid subscribe request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.TopicSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("topicSrn", req.TopicSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		sub, err := h.svc.Subscribe(ctx, c.Param("accountid"), srn, entity.SubscribeInput{
+			Protocol:   req.Protocol,
+			Endpoint:   req.Endpoint,
+			Attributes: req.Attributes,
+		})
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to subscribe", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Subscription created", zap.String("subscriptionSrn", sub.SubscriptionSrn))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, SubscribeResponse{
+			SubscribeResult: SubscribeResult{SubscriptionSrn: sub.SubscriptionSrn}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *SubscriptionHandler) Unsubscribe() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req UnsubscribeRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid unsubscribe request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.SubscriptionSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid subscription reference", zap.String("subscriptionSrn", req.SubscriptionSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.Unsubscribe(ctx, c.Param("accountid"), srn); err != nil {
+			logs.GetLogger(ctx).Error("Failed to unsubscribe", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Subscription deleted", zap.String("subscriptionSrn", req.SubscriptionSrn))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, UnsubscribeResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *SubscriptionHandler) ListByTopic() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ListSubscriptionsByTopicRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid listSubscriptionsByTopic request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.TopicSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("topicSrn", req.TopicSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		subs, err := h.svc.ListSubscriptionsByTopic(ctx, c.Param("accountid"), srn)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Subscription list lookup failed", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Return subscription list", zap.Int("count", len(subs)))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ListSubscriptionsByTopicResponse{
+			ListSubscriptionsByTopicResult: ListSubscriptionsByTopicResult{Subscriptions: subs}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *SubscriptionHandler) GetAttributes() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req GetSubscriptionAttributesRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid getSubscriptionAttributes request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.SubscriptionSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid subscription reference", zap.String("subscriptionSrn", req.SubscriptionSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		attrs, err := h.svc.GetSubscriptionAttributes(ctx, c.Param("accountid"), srn)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to get subscription attributes", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, GetSubscriptionAttributesResponse{
+			GetSubscriptionAttributesResult: GetSubscriptionAttributesResult{Attributes: attrs}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *SubscriptionHandler) SetAttributes() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req SetSubscriptionAttributesRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid setSubscriptionAttributes request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.SubscriptionSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid subscription reference", zap.String("subscriptionSrn", req.SubscriptionSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.SetSubscriptionAttributes(ctx, c.Param("accountid"), srn, req.AttributeName, req.AttributeValue); err != nil {
+			logs.GetLogger(ctx).Error("Failed to set subscription attributes", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Subscription attribute updated", zap.String("subscriptionSrn", req.SubscriptionSrn), zap.String("attribute", req.AttributeName))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, SetSubscriptionAttributesResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *SubscriptionHandler) Confirm() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ConfirmSubscriptionRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid confirmSubscription request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.TopicSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("topicSrn", req.TopicSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		sub, err := h.svc.ConfirmSubscription(ctx, srn, req.Token)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to confirm subscription", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Subscription confirmed", zap.String("subscriptionSrn", sub.SubscriptionSrn))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ConfirmSubscriptionResponse{
+			ConfirmSubscriptionResult: ConfirmSubscriptionResult{SubscriptionSrn: sub.SubscriptionSrn}, ResponseMetadata: meta,
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type TagResourceRequest struct {
+	ResourceSrn string       `json:"ResourceSrn" validate:"required"`
+	Tags        []entity.Tag `json:"Tags" validate:"required"`
+}
+
+type TagResourceResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type UntagResourceRequest struct {
+	ResourceSrn string   `json:"ResourceSrn" validate:"required"`
+	TagKeys     []string `json:"TagKeys" validate:"required"`
+}
+
+type UntagResourceResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
+}
+
+type ListTagsForResourceRequest struct {
+	ResourceSrn string `json:"ResourceSrn" validate:"required"`
+}
+
+type ListTagsForResourceResult struct {
+	Tags []entity.Tag `json:"Tags"`
+}
+
+type ListTagsForResourceResponse struct {
+	ListTagsForResourceResult ListTagsForResourceResult `json:"ListTagsForResourceResult"`
+	ResponseMetadata          entity.ResponseMetadata   `json:"ResponseMetadata"`
+}
+
+func (h *TopicHandler) TagResource() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req TagResourceRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid tagResource request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.ResourceSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("resourceSrn", req.ResourceSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.TagResource(ctx, c.Param("accountid"), srn, req.Tags); err != nil {
+			logs.GetLogger(ctx).Error("Failed to tag topic", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Topic tagged", zap.String("topic", srn.Topic), zap.Int("count", len(req.Tags)))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, TagResourceResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *TopicHandler) UntagResource() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req UntagResourceRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid untagResource request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.ResourceSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("resourceSrn", req.ResourceSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.UntagResource(ctx, c.Param("accountid"), srn, req.TagKeys); err != nil {
+			logs.GetLogger(ctx).Error("Failed to untag topic", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Topic untagged", zap.String("topic", srn.Topic), zap.Int("count", len(req.TagKeys)))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, UntagResourceResponse{ResponseMetadata: meta})
+	}
+}
+
+func (h *TopicHandler) ListTagsForResource() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req ListTagsForResourceRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid listTagsForResource request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.ResourceSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("resourceSrn", req.ResourceSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		tags, err := h.svc.ListTagsForResource(ctx, c.Param("accountid"), srn)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to list topic tags", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, ListTagsForResourceResponse{
+			ListTagsForResourceResult: ListTagsForResourceResult{Tags: tags}, ResponseMetadata: meta,
+		})
+	}
+}
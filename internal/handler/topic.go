@@ -5,7 +5,7 @@ import (
 	"nats/internal/entity"
 	"nats/internal/service"
 	"net/http"
-	"strings"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
@@ -20,7 +20,10 @@ func NewTopicHandler(svc service.TopicService) *TopicHandler {
 }
 
 type CreateTopicRequest struct {
-	Name string `json:"Name" validate:"required"`
+	Name       string            `json:"Name" validate:"required"`
+	Subjects   []string          `json:"Subjects"`
+	Attributes map[string]string `json:"Attributes"`
+	Tags       []entity.Tag      `json:"Tags"`
 }
 
 type CreateTopicResponse struct {
@@ -37,7 +40,31 @@ type DeleteTopicResponse struct {
 }
 
 type ListTopicsResponse struct {
-	Topics []entity.Topic `json:"topics"`
+	Topics    []entity.Topic `json:"topics"`
+	NextToken string         `json:"NextToken,omitempty"`
+}
+
+type GetTopicAttributesRequest struct {
+	TopicSrn string `json:"TopicSrn" validate:"required"`
+}
+
+type GetTopicAttributesResult struct {
+	Attributes map[string]string `json:"Attributes"`
+}
+
+type GetTopicAttributesResponse struct {
+	GetTopicAttributesResult GetTopicAttributesResult `json:"GetTopicAttributesResult"`
+	ResponseMetadata         entity.ResponseMetadata  `json:"ResponseMetadata"`
+}
+
+type SetTopicAttributesRequest struct {
+	TopicSrn       string `json:"TopicSrn" validate:"required"`
+	AttributeName  string `json:"AttributeName" validate:"required"`
+	AttributeValue string `json:"AttributeValue"`
+}
+
+type SetTopicAttributesResponse struct {
+	ResponseMetadata entity.ResponseMetadata `json:"ResponseMetadata"`
 }
 
 func (h *TopicHandler) Create() echo.HandlerFunc {
@@ -55,10 +82,16 @@ func (h *TopicHandler) Create() echo.HandlerFunc {
 			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
 		}
 
-		result, err := h.svc.CreateTopic(ctx, req.Name, c.Param("accountid"))
+		result, err := h.svc.CreateTopic(ctx, c.Param("accountid"), entity.CreateTopicInput{
+			Name:       req.Name,
+			Subjects:   req.Subjects,
+			Attributes: req.Attributes,
+			Tags:       req.Tags,
+		})
 		if err != nil {
 			logs.GetLogger(ctx).Error("Failed to create stream", zap.Error(err))
-			return c.JSON(entity.InternalError.HTTPCode, entity.InternalError.Error)
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
 		}
 		logs.GetLogger(ctx).Info("Stream creation success", zap.String("topic", req.Name))
 		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
@@ -83,19 +116,19 @@ func (h *TopicHandler) Delete() echo.HandlerFunc {
 			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
 		}
 
-		parts := strings.Split(req.TopicSrn, ":")
-		name := parts[len(parts)-1]
-
-		if name == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "missing 'name' parameter"})
+		srn, err := entity.ParseSRN(req.TopicSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("topicSrn", req.TopicSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
 		}
 
-		if err := h.svc.DeleteTopic(ctx, name); err != nil {
+		if err := h.svc.DeleteTopic(ctx, c.Param("accountid"), srn); err != nil {
 			logs.GetLogger(ctx).Error("Failed to delete stream", zap.Error(err))
-			return c.JSON(entity.InternalError.HTTPCode, entity.InternalError.Error)
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
 		}
 
-		logs.GetLogger(ctx).Info("Stream deletion success", zap.String("topic", name))
+		logs.GetLogger(ctx).Info("Stream deletion success", zap.String("topic", srn.Topic))
 		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
 		return c.JSON(http.StatusOK, DeleteTopicResponse{ResponseMetadata: meta})
 	}
@@ -105,13 +138,93 @@ func (h *TopicHandler) List() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
 
-		topics, err := h.svc.ListTopics(ctx, c.Param("accountid"))
+		maxResults := 0
+		if v := c.QueryParam("MaxResults"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				logs.GetLogger(ctx).Error("Invalid listTopics MaxResults", zap.String("maxResults", v), zap.Error(err))
+				return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+			}
+			maxResults = n
+		}
+
+		filter := entity.TopicFilter{TagKey: c.QueryParam("TagKey"), TagValue: c.QueryParam("TagValue")}
+		topics, nextToken, err := h.svc.ListTopics(ctx, c.Param("accountid"), filter, c.QueryParam("NextToken"), maxResults)
 		if err != nil {
 			logs.GetLogger(ctx).Error("Topic list lookup failed", zap.Error(err))
-			return c.JSON(entity.InternalError.HTTPCode, entity.InternalError.Error)
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
 		}
 
 		logs.GetLogger(ctx).Info("Return topic list", zap.Int("count", len(topics)))
-		return c.JSON(http.StatusOK, ListTopicsResponse{Topics: topics})
+		return c.JSON(http.StatusOK, ListTopicsResponse{Topics: topics, NextToken: nextToken})
+	}
+}
+
+func (h *TopicHandler) GetAttributes() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req GetTopicAttributesRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid getTopicAttributes request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.TopicSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("topicSrn", req.TopicSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		attrs, err := h.svc.GetTopicAttributes(ctx, c.Param("accountid"), srn)
+		if err != nil {
+			logs.GetLogger(ctx).Error("Failed to get topic attributes", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, GetTopicAttributesResponse{
+			GetTopicAttributesResult: GetTopicAttributesResult{Attributes: attrs}, ResponseMetadata: meta,
+		})
+	}
+}
+
+func (h *TopicHandler) SetAttributes() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var req SetTopicAttributesRequest
+		if err := c.Bind(&req); err != nil {
+			logs.GetLogger(ctx).Error("Invalid setTopicAttributes request parameter", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := c.Validate(&req); err != nil {
+			logs.GetLogger(ctx).Error("Required parameter is missing", zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		srn, err := entity.ParseSRN(req.TopicSrn)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Invalid topic reference", zap.String("topicSrn", req.TopicSrn), zap.Error(err))
+			return c.JSON(entity.InvalidParameter.HTTPCode, entity.InvalidParameter.Error)
+		}
+
+		if err := h.svc.SetTopicAttributes(ctx, c.Param("accountid"), srn, req.AttributeName, req.AttributeValue); err != nil {
+			logs.GetLogger(ctx).Error("Failed to set topic attributes", zap.Error(err))
+			resp := entity.ErrorResponseOf(err)
+			return c.JSON(resp.HTTPCode, resp.Error)
+		}
+
+		logs.GetLogger(ctx).Info("Topic attribute updated", zap.String("topic", srn.Topic), zap.String("attribute", req.AttributeName))
+		meta := entity.ResponseMetadata{RequestId: c.Response().Header().Get(echo.HeaderXRequestID)}
+		return c.JSON(http.StatusOK, SetTopicAttributesResponse{ResponseMetadata: meta})
 	}
 }
@@ -2,6 +2,7 @@ package valkey
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"nats/pkg/config"
@@ -13,8 +14,22 @@ type ValkeyClient interface {
 	Shutdown(ctx context.Context)
 	GetValue(ctx context.Context, key string) (string, error)
 	SetValueWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error
+	DeleteValue(ctx context.Context, key string) error
+	SetValue(ctx context.Context, key string, value string) error
+	SetValueIfAbsent(ctx context.Context, key string, value string, ttl time.Duration) (bool, error)
+	ExpireIfValue(ctx context.Context, key string, value string, ttl time.Duration) (bool, error)
+	DeleteIfValue(ctx context.Context, key string, value string) (bool, error)
+	AddSetMember(ctx context.Context, key string, member string) error
+	RemoveSetMember(ctx context.Context, key string, member string) error
+	GetSetMembers(ctx context.Context, key string) ([]string, error)
 }
 
+// compare-and-set scripts, used for leases held by a single owner
+var (
+	expireIfValueScript = valkey.NewLuaScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) end return 0`)
+	deleteIfValueScript = valkey.NewLuaScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`)
+)
+
 type valkeyClient struct {
 	client valkey.Client
 }
@@ -35,10 +50,53 @@ func (v *valkeyClient) Shutdown(ctx context.Context) {
 	v.client.Close()
 }
 
+// GetValue returns an empty string for a missing key
 func (v *valkeyClient) GetValue(ctx context.Context, key string) (string, error) {
-	return v.client.Do(ctx, v.client.B().Get().Key(key).Build()).ToString()
+	value, err := v.client.Do(ctx, v.client.B().Get().Key(key).Build()).ToString()
+	if valkey.IsValkeyNil(err) {
+		return "", nil
+	}
+	return value, err
 }
 
 func (v *valkeyClient) SetValueWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error {
 	return v.client.Do(ctx, v.client.B().Set().Key(key).Value(value).Ex(ttl).Build()).Error()
 }
+
+func (v *valkeyClient) DeleteValue(ctx context.Context, key string) error {
+	return v.client.Do(ctx, v.client.B().Del().Key(key).Build()).Error()
+}
+
+func (v *valkeyClient) SetValue(ctx context.Context, key string, value string) error {
+	return v.client.Do(ctx, v.client.B().Set().Key(key).Value(value).Build()).Error()
+}
+
+func (v *valkeyClient) SetValueIfAbsent(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	err := v.client.Do(ctx, v.client.B().Set().Key(key).Value(value).Nx().Px(ttl).Build()).Error()
+	if valkey.IsValkeyNil(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (v *valkeyClient) ExpireIfValue(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	n, err := expireIfValueScript.Exec(ctx, v.client, []string{key}, []string{value, strconv.FormatInt(ttl.Milliseconds(), 10)}).AsInt64()
+	return n == 1, err
+}
+
+func (v *valkeyClient) DeleteIfValue(ctx context.Context, key string, value string) (bool, error) {
+	n, err := deleteIfValueScript.Exec(ctx, v.client, []string{key}, []string{value}).AsInt64()
+	return n == 1, err
+}
+
+func (v *valkeyClient) AddSetMember(ctx context.Context, key string, member string) error {
+	return v.client.Do(ctx, v.client.B().Sadd().Key(key).Member(member).Build()).Error()
+}
+
+func (v *valkeyClient) RemoveSetMember(ctx context.Context, key string, member string) error {
+	return v.client.Do(ctx, v.client.B().Srem().Key(key).Member(member).Build()).Error()
+}
+
+func (v *valkeyClient) GetSetMembers(ctx context.Context, key string) ([]string, error) {
+	return v.client.Do(ctx, v.client.B().Smembers().Key(key).Build()).AsStrSlice()
+}
@@ -0,0 +1,26 @@
+package natsapi
+
+import "github.com/nats-io/nats.go/micro"
+
+// headerCarrier reads and writes the trace context in NATS headers. NATS header keys
+// are case sensitive, so http.Header canonicalization cannot be used.
+type headerCarrier micro.Headers
+
+func (c headerCarrier) Get(key string) string {
+	if v := c[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = []string{value}
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
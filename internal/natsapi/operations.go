@@ -0,0 +1,199 @@
+package natsapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"nats/internal/entity"
+)
+
+// Request and result bodies use the JSON field names of the HTTP API.
+
+type CreateTopicRequest struct {
+	Name       string            `json:"Name"`
+	Subjects   []string          `json:"Subjects"`
+	Attributes map[string]string `json:"Attributes"`
+	Tags       []entity.Tag      `json:"Tags"`
+}
+
+type CreateTopicResult struct {
+	CreateTopicResult entity.Topic `json:"CreateTopicResult"`
+}
+
+type TopicRequest struct {
+	TopicSrn string `json:"TopicSrn"`
+}
+
+type ListTopicsRequest struct {
+	NextToken  string `json:"NextToken"`
+	MaxResults int    `json:"MaxResults"`
+	TagKey     string `json:"TagKey"`
+	TagValue   string `json:"TagValue"`
+}
+
+type ListTopicsResult struct {
+	Topics    []entity.Topic `json:"topics"`
+	NextToken string         `json:"NextToken,omitempty"`
+}
+
+type GetTopicAttributesResult struct {
+	Attributes map[string]string `json:"Attributes"`
+}
+
+type SetTopicAttributesRequest struct {
+	TopicSrn       string `json:"TopicSrn"`
+	AttributeName  string `json:"AttributeName"`
+	AttributeValue string `json:"AttributeValue"`
+}
+
+type PublishRequest struct {
+	TopicName              string `json:"topicName"`
+	Message                string `json:"message"`
+	Subject                string `json:"subject"`
+	MessageGroupId         string `json:"messageGroupId"`
+	MessageDeduplicationId string `json:"messageDeduplicationId"`
+
+	MessageAttributes map[string]entity.MessageAttributeValue `json:"messageAttributes"`
+}
+
+type PublishResult struct {
+	MessageID string `json:"messageId"`
+}
+
+type PublishCheckRequest struct {
+	MessageID string `json:"messageId"`
+}
+
+type PublishCheckResult struct {
+	Status string `json:"status"`
+}
+
+type emptyResult struct{}
+
+func (s *server) createTopic(ctx context.Context, account string, data []byte) (any, error) {
+	var req CreateTopicRequest
+	if err := decode(data, &req); err != nil {
+		return nil, err
+	}
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: Name is required", entity.ErrInvalidParameter)
+	}
+	topic, err := s.topicSvc.CreateTopic(ctx, account, entity.CreateTopicInput{
+		Name:       req.Name,
+		Subjects:   req.Subjects,
+		Attributes: req.Attributes,
+		Tags:       req.Tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return CreateTopicResult{CreateTopicResult: topic}, nil
+}
+
+func (s *server) deleteTopic(ctx context.Context, account string, data []byte) (any, error) {
+	srn, err := decodeTopic(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.topicSvc.DeleteTopic(ctx, account, srn); err != nil {
+		return nil, err
+	}
+	return emptyResult{}, nil
+}
+
+func (s *server) listTopics(ctx context.Context, account string, data []byte) (any, error) {
+	var req ListTopicsRequest
+	if err := decode(data, &req); err != nil {
+		return nil, err
+	}
+	filter := entity.TopicFilter{TagKey: req.TagKey, TagValue: req.TagValue}
+	topics, nextToken, err := s.topicSvc.ListTopics(ctx, account, filter, req.NextToken, req.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	return ListTopicsResult{Topics: topics, NextToken: nextToken}, nil
+}
+
+func (s *server) getTopicAttributes(ctx context.Context, account string, data []byte) (any, error) {
+	srn, err := decodeTopic(data)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := s.topicSvc.GetTopicAttributes(ctx, account, srn)
+	if err != nil {
+		return nil, err
+	}
+	return GetTopicAttributesResult{Attributes: attrs}, nil
+}
+
+func (s *server) setTopicAttributes(ctx context.Context, account string, data []byte) (any, error) {
+	var req SetTopicAttributesRequest
+	if err := decode(data, &req); err != nil {
+		return nil, err
+	}
+	if req.AttributeName == "" {
+		return nil, fmt.Errorf("%w: AttributeName is required", entity.ErrInvalidParameter)
+	}
+	srn, err := entity.ParseSRN(req.TopicSrn)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.topicSvc.SetTopicAttributes(ctx, account, srn, req.AttributeName, req.AttributeValue); err != nil {
+		return nil, err
+	}
+	return emptyResult{}, nil
+}
+
+func (s *server) publish(ctx context.Context, account string, data []byte) (any, error) {
+	var req PublishRequest
+	if err := decode(data, &req); err != nil {
+		return nil, err
+	}
+	id, err := s.publishSvc.PublishAsyncMessage(ctx, account, entity.PublishInput{
+		TopicName:              req.TopicName,
+		Message:                req.Message,
+		Subject:                req.Subject,
+		MessageGroupId:         req.MessageGroupId,
+		MessageDeduplicationId: req.MessageDeduplicationId,
+		MessageAttributes:      req.MessageAttributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return PublishResult{MessageID: id}, nil
+}
+
+// publishCheck looks up the ack status by the message id, which is not scoped by account
+func (s *server) publishCheck(ctx context.Context, _ string, data []byte) (any, error) {
+	var req PublishCheckRequest
+	if err := decode(data, &req); err != nil {
+		return nil, err
+	}
+	if req.MessageID == "" {
+		return nil, fmt.Errorf("%w: messageId is required", entity.ErrInvalidParameter)
+	}
+	status, err := s.publishSvc.CheckAckStatus(ctx, req.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: message id %s", entity.ErrNotFound, req.MessageID)
+	}
+	return PublishCheckResult{Status: status}, nil
+}
+
+// decode reads the JSON request body; an empty body is an empty request
+func decode(data []byte, v any) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: invalid JSON request", entity.ErrInvalidParameter)
+	}
+	return nil
+}
+
+func decodeTopic(data []byte) (entity.SRN, error) {
+	var req TopicRequest
+	if err := decode(data, &req); err != nil {
+		return entity.SRN{}, err
+	}
+	return entity.ParseSRN(req.TopicSrn)
+}
@@ -0,0 +1,136 @@
+package natsapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"nats/internal/context/logs"
+	"nats/internal/context/traces"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/internal/service"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/micro"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+// SubjectRoot prefixes the request subjects, sns.api.<account>.<operation>.
+// Topic streams only capture sns.data.>, so requests are never stored.
+const SubjectRoot = "sns.api"
+
+// ServiceName is the micro service name reported by $SRV.PING, $SRV.INFO and $SRV.STATS
+const ServiceName = "sns"
+
+// ServiceVersion is the version reported by $SRV.INFO
+const ServiceVersion = "1.0.0"
+
+// requestTimeout bounds the handling of a single request
+const requestTimeout = 30 * time.Second
+
+// Server exposes the topic and publish operations as a NATS micro service.
+// Every instance joins the same queue group, so a request is handled once.
+type Server interface {
+	Start() error
+	Stop()
+}
+
+type server struct {
+	ctx        context.Context
+	natsRepo   repo.NatsRepo
+	topicSvc   service.TopicService
+	publishSvc service.PublishService
+	svc        micro.Service
+}
+
+func NewServer(ctx context.Context, natsRepo repo.NatsRepo, topicSvc service.TopicService, publishSvc service.PublishService) Server {
+	return &server{ctx: ctx, natsRepo: natsRepo, topicSvc: topicSvc, publishSvc: publishSvc}
+}
+
+// Start registers the service and its endpoints. A service missing an endpoint is stopped,
+// so that the instance never answers only part of the API.
+func (s *server) Start() error {
+	svc, err := s.natsRepo.AddMicroService(s.ctx, micro.Config{
+		Name:        ServiceName,
+		Version:     ServiceVersion,
+		Description: "Topic and publish API, " + SubjectRoot + ".<account>.<operation>",
+		ErrorHandler: func(_ micro.Service, err *micro.NATSError) {
+			logs.GetLogger(s.ctx).Error("NATS API subscription error", zap.String("subject", err.Subject), zap.String("error", err.Description))
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("register NATS API service: %w", err)
+	}
+
+	endpoints := map[string]func(context.Context, string, []byte) (any, error){
+		"createTopic":        s.createTopic,
+		"deleteTopic":        s.deleteTopic,
+		"listTopics":         s.listTopics,
+		"getTopicAttributes": s.getTopicAttributes,
+		"setTopicAttributes": s.setTopicAttributes,
+		"publish":            s.publish,
+		"publishCheck":       s.publishCheck,
+	}
+	for op, fn := range endpoints {
+		subject := SubjectRoot + ".*." + op
+		if err := svc.AddEndpoint(op, s.handler(op, fn), micro.WithEndpointSubject(subject)); err != nil {
+			_ = svc.Stop()
+			return fmt.Errorf("add NATS API endpoint %s: %w", subject, err)
+		}
+	}
+	s.svc = svc
+	return nil
+}
+
+// Stop drains the endpoint subscriptions
+func (s *server) Stop() {
+	if s.svc != nil {
+		_ = s.svc.Stop()
+	}
+}
+
+// handler resolves the account from the subject, continues the trace of the caller from
+// the request headers and responds with the JSON result, or a micro error carrying the
+// HTTP status code and the same error body as the HTTP API
+func (s *server) handler(op string, fn func(context.Context, string, []byte) (any, error)) micro.HandlerFunc {
+	return func(req micro.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(s.ctx, headerCarrier(req.Headers()))
+		ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+		ctx, span := traces.StartSpan(ctx, "natsapi."+op)
+		defer span.End()
+		ctx = logs.WithFields(ctx, zap.String("subject", req.Subject()))
+
+		account, err := accountOf(req.Subject())
+		if err == nil {
+			var result any
+			if result, err = fn(ctx, account, req.Data()); err == nil {
+				if err := req.RespondJSON(result); err != nil {
+					logs.GetLogger(ctx).Warn("Failed to respond to NATS API request", zap.Error(err))
+				}
+				return
+			}
+		}
+
+		logs.GetLogger(ctx).Error("NATS API request failed", zap.String("operation", op), zap.Error(err))
+		resp := entity.ErrorResponseOf(err)
+		body, _ := json.Marshal(resp.Error)
+		if err := req.Error(fmt.Sprint(resp.HTTPCode), resp.Error.Code, body); err != nil {
+			logs.GetLogger(ctx).Warn("Failed to respond to NATS API request", zap.Error(err))
+		}
+	}
+}
+
+// accountOf returns the account token of sns.api.<account>.<operation>
+func accountOf(subject string) (string, error) {
+	tokens := strings.Split(subject, ".")
+	if len(tokens) != 4 {
+		return "", fmt.Errorf("%w: subject must be %s.<account>.<operation>", entity.ErrInvalidParameter, SubjectRoot)
+	}
+	if err := entity.ValidateAccountID(tokens[2]); err != nil {
+		return "", err
+	}
+	return tokens[2], nil
+}
@@ -0,0 +1,145 @@
+package natsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/internal/service"
+	"testing"
+
+	"github.com/nats-io/nats.go/micro"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRequest struct {
+	micro.Request
+	subject string
+	data    []byte
+	headers micro.Headers
+
+	result  any
+	errCode string
+	errBody []byte
+}
+
+func (r *fakeRequest) Subject() string        { return r.subject }
+func (r *fakeRequest) Data() []byte           { return r.data }
+func (r *fakeRequest) Headers() micro.Headers { return r.headers }
+
+func (r *fakeRequest) RespondJSON(v any, _ ...micro.RespondOpt) error {
+	r.result = v
+	return nil
+}
+
+func (r *fakeRequest) Error(code, _ string, data []byte, _ ...micro.RespondOpt) error {
+	r.errCode, r.errBody = code, data
+	return nil
+}
+
+type fakeTopicService struct {
+	service.TopicService
+	account string
+	input   entity.CreateTopicInput
+	err     error
+}
+
+func (f *fakeTopicService) CreateTopic(_ context.Context, account string, input entity.CreateTopicInput) (entity.Topic, error) {
+	f.account, f.input = account, input
+	return entity.Topic{TopicSrn: "srn:scp:sns:kr-west1:" + account + ":" + input.Name}, f.err
+}
+
+func TestHandler_CreateTopic(t *testing.T) {
+	topics := &fakeTopicService{}
+	s := &server{ctx: context.Background(), topicSvc: topics}
+
+	req := &fakeRequest{subject: "sns.api.acct.createTopic", data: []byte(`{"Name": "orders"}`), headers: micro.Headers{}}
+	s.handler("createTopic", s.createTopic)(req)
+
+	assert.Equal(t, "acct", topics.account)
+	assert.Equal(t, "orders", topics.input.Name)
+	assert.Equal(t, CreateTopicResult{CreateTopicResult: entity.Topic{TopicSrn: "srn:scp:sns:kr-west1:acct:orders"}}, req.result)
+}
+
+func TestHandler_Errors(t *testing.T) {
+	topics := &fakeTopicService{err: fmt.Errorf("%w: exists", entity.ErrConflict)}
+	s := &server{ctx: context.Background(), topicSvc: topics}
+
+	req := &fakeRequest{subject: "sns.api.acct.createTopic", data: []byte(`{"Name": "orders"}`), headers: micro.Headers{}}
+	s.handler("createTopic", s.createTopic)(req)
+	assert.Equal(t, "409", req.errCode)
+	var body entity.Error
+	assert.NoError(t, json.Unmarshal(req.errBody, &body))
+	assert.Equal(t, entity.Conflict.Error.Code, body.Code)
+
+	req = &fakeRequest{subject: "sns.api.acct.createTopic", data: []byte(`{`), headers: micro.Headers{}}
+	s.handler("createTopic", s.createTopic)(req)
+	assert.Equal(t, "400", req.errCode)
+
+	req = &fakeRequest{subject: "sns.api.bad!acct.createTopic", data: []byte(`{"Name": "orders"}`), headers: micro.Headers{}}
+	s.handler("createTopic", s.createTopic)(req)
+	assert.Equal(t, "400", req.errCode)
+}
+
+func TestHeaderCarrier(t *testing.T) {
+	c := headerCarrier(micro.Headers{})
+	c.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", c.Get("traceparent"))
+	assert.Equal(t, "", c.Get("Traceparent"))
+	assert.Equal(t, []string{"traceparent"}, c.Keys())
+}
+
+// fakeMicroRepo registers fakeMicroService, or fails with err
+type fakeMicroRepo struct {
+	repo.NatsRepo
+	svc *fakeMicroService
+	err error
+}
+
+func (r *fakeMicroRepo) AddMicroService(context.Context, micro.Config) (micro.Service, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.svc, nil
+}
+
+// fakeMicroService fails to add the endpoint named failOn
+type fakeMicroService struct {
+	micro.Service
+	failOn    string
+	endpoints []string
+	stopped   bool
+}
+
+func (s *fakeMicroService) AddEndpoint(name string, _ micro.Handler, _ ...micro.EndpointOpt) error {
+	if name == s.failOn {
+		return errors.New("nats: invalid subject")
+	}
+	s.endpoints = append(s.endpoints, name)
+	return nil
+}
+
+func (s *fakeMicroService) Stop() error {
+	s.stopped = true
+	return nil
+}
+
+func TestServer_Start(t *testing.T) {
+	svc := &fakeMicroService{}
+	s := NewServer(context.Background(), &fakeMicroRepo{svc: svc}, nil, nil)
+	assert.NoError(t, s.Start())
+	assert.Len(t, svc.endpoints, 7)
+	s.Stop()
+	assert.True(t, svc.stopped)
+
+	s = NewServer(context.Background(), &fakeMicroRepo{err: errors.New("nats: connection closed")}, nil, nil)
+	assert.Error(t, s.Start())
+
+	// a service missing an endpoint is not left running
+	svc = &fakeMicroService{failOn: "publish"}
+	s = NewServer(context.Background(), &fakeMicroRepo{svc: svc}, nil, nil)
+	assert.Error(t, s.Start())
+	assert.True(t, svc.stopped)
+}
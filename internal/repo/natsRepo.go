@@ -2,19 +2,57 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"nats/internal/infra/nats"
 
+	gonats "github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nats.go/micro"
 )
 
 type NatsRepo interface {
-	PublishAsyncMessage(ctx context.Context, message, subject string) (jetstream.PubAckFuture, error)
+	PublishAsyncMessage(ctx context.Context, msg *gonats.Msg) (jetstream.PubAckFuture, error)
+	PublishAsyncMessages(ctx context.Context, msgs []*gonats.Msg) ([]jetstream.PubAckFuture, error)
 
-	CreateStream(ctx context.Context, name string) (jetstream.Stream, error)
+	CreateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error)
+	UpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error)
 	DeleteStream(ctx context.Context, name string) error
-	ListStreamNames(ctx context.Context) (<-chan string, error)
+	GetStreamInfo(ctx context.Context, name string) (*jetstream.StreamInfo, error)
+	ListStreams(ctx context.Context, subject string) ([]*jetstream.StreamInfo, error)
+	ListStreamsPage(ctx context.Context, subject string, offset int) (StreamPage, error)
+
+	CreateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (*jetstream.ConsumerInfo, error)
+	UpdateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (*jetstream.ConsumerInfo, error)
+	DeleteConsumer(ctx context.Context, stream, name string) error
+	GetConsumerInfo(ctx context.Context, stream, name string) (*jetstream.ConsumerInfo, error)
+	GetConsumer(ctx context.Context, stream, name string) (jetstream.Consumer, error)
+	ListConsumers(ctx context.Context, stream string) ([]*jetstream.ConsumerInfo, error)
+
+	GetMessage(ctx context.Context, stream string, seq uint64) (*jetstream.RawStreamMsg, error)
+	DeleteMessage(ctx context.Context, stream string, seq uint64) error
+	OrderedConsumer(ctx context.Context, stream string, startSeq uint64) (jetstream.Consumer, error)
+	SettleMessage(ctx context.Context, ackSubject, ack string) error
+	AddMicroService(ctx context.Context, cfg micro.Config) (micro.Service, error)
+}
+
+// StreamPage is one page of the JetStream stream listing, ordered by stream name
+type StreamPage struct {
+	Streams []*jetstream.StreamInfo `json:"streams"`
+	Total   int                     `json:"total"`
+	Offset  int                     `json:"offset"`
+	Limit   int                     `json:"limit"`
+}
+
+type streamListRequest struct {
+	Offset  int    `json:"offset"`
+	Subject string `json:"subject,omitempty"`
+}
+
+type streamListResponse struct {
+	StreamPage
+	Error *jetstream.APIError `json:"error,omitempty"`
 }
 
 type natsRepo struct {
@@ -25,38 +63,46 @@ func NewNatsRepo(jsClient nats.JetStreamPool) NatsRepo {
 	return &natsRepo{jsClient: jsClient}
 }
 
-func (s *natsRepo) PublishAsyncMessage(ctx context.Context, message, subject string) (jetstream.PubAckFuture, error) {
+func (s *natsRepo) PublishAsyncMessage(ctx context.Context, msg *gonats.Msg) (jetstream.PubAckFuture, error) {
 	js, err := s.jsClient.GetJetStream(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return js.PublishAsync(subject, []byte(message))
+	return js.PublishMsgAsync(msg)
 }
 
-func (s *natsRepo) CreateStream(ctx context.Context, name string) (jetstream.Stream, error) {
-	streamCfg := jetstream.StreamConfig{
-		Name:              name,
-		Subjects:          []string{name},
-		Storage:           jetstream.FileStorage,
-		Replicas:          1,
-		Retention:         jetstream.LimitsPolicy,
-		Discard:           jetstream.DiscardOld,
-		MaxMsgs:           -1,
-		MaxMsgsPerSubject: -1,
-		MaxBytes:          -1,
-		MaxAge:            96 * time.Hour,
-		MaxMsgSize:        262144,
-		Duplicates:        0,
-		AllowRollup:       false,
-		DenyDelete:        false,
-		DenyPurge:         false,
+// PublishAsyncMessages publishes the messages in order on a single pooled connection, so that
+// JetStream stores them in that order. On error the futures of the messages published so far are returned.
+func (s *natsRepo) PublishAsyncMessages(ctx context.Context, msgs []*gonats.Msg) ([]jetstream.PubAckFuture, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	futures := make([]jetstream.PubAckFuture, 0, len(msgs))
+	for _, msg := range msgs {
+		future, err := js.PublishMsgAsync(msg)
+		if err != nil {
+			return futures, err
+		}
+		futures = append(futures, future)
 	}
+	return futures, nil
+}
 
+func (s *natsRepo) CreateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
 	js, err := s.jsClient.GetJetStream(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return js.CreateStream(ctx, streamCfg)
+	return js.CreateStream(ctx, cfg)
+}
+
+func (s *natsRepo) UpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return js.UpdateStream(ctx, cfg)
 }
 
 func (s *natsRepo) DeleteStream(ctx context.Context, name string) error {
@@ -67,11 +113,201 @@ func (s *natsRepo) DeleteStream(ctx context.Context, name string) error {
 	return js.DeleteStream(ctx, name)
 }
 
-func (s *natsRepo) ListStreamNames(ctx context.Context) (<-chan string, error) {
+func (s *natsRepo) GetStreamInfo(ctx context.Context, name string) (*jetstream.StreamInfo, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := js.Stream(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return stream.CachedInfo(), nil
+}
+
+// ListStreams returns the streams capturing subjects that match the given subject filter
+func (s *natsRepo) ListStreams(ctx context.Context, subject string) ([]*jetstream.StreamInfo, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lister := js.ListStreams(ctx, jetstream.WithStreamListSubject(subject))
+
+	var infos []*jetstream.StreamInfo
+	for info := range lister.Info() {
+		infos = append(infos, info)
+	}
+	if err := lister.Err(); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// ListStreamsPage requests a single page of the stream listing starting at offset.
+// jetstream.ListStreams always walks every page, so the STREAM.LIST API is called directly.
+func (s *natsRepo) ListStreamsPage(ctx context.Context, subject string, offset int) (StreamPage, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return StreamPage{}, err
+	}
+
+	req, err := json.Marshal(streamListRequest{Offset: offset, Subject: subject})
+	if err != nil {
+		return StreamPage{}, err
+	}
+
+	prefix := js.Options().APIPrefix
+	if prefix == "" {
+		prefix = jetstream.DefaultAPIPrefix
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	msg, err := js.Conn().RequestWithContext(ctx, prefix+"STREAM.LIST", req)
+	if err != nil {
+		return StreamPage{}, err
+	}
+
+	var resp streamListResponse
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return StreamPage{}, err
+	}
+	if resp.Error != nil {
+		return StreamPage{}, resp.Error
+	}
+	return resp.StreamPage, nil
+}
+
+func (s *natsRepo) CreateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (*jetstream.ConsumerInfo, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := js.CreateConsumer(ctx, stream, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return consumer.CachedInfo(), nil
+}
+
+func (s *natsRepo) UpdateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (*jetstream.ConsumerInfo, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := js.UpdateConsumer(ctx, stream, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return consumer.CachedInfo(), nil
+}
+
+func (s *natsRepo) DeleteConsumer(ctx context.Context, stream, name string) error {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return err
+	}
+	return js.DeleteConsumer(ctx, stream, name)
+}
+
+func (s *natsRepo) GetConsumerInfo(ctx context.Context, stream, name string) (*jetstream.ConsumerInfo, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := js.Consumer(ctx, stream, name)
+	if err != nil {
+		return nil, err
+	}
+	return consumer.CachedInfo(), nil
+}
+
+func (s *natsRepo) GetConsumer(ctx context.Context, stream, name string) (jetstream.Consumer, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return js.Consumer(ctx, stream, name)
+}
+
+func (s *natsRepo) ListConsumers(ctx context.Context, stream string) ([]*jetstream.ConsumerInfo, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st, err := js.Stream(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	lister := st.ListConsumers(ctx)
+
+	var infos []*jetstream.ConsumerInfo
+	for info := range lister.Info() {
+		infos = append(infos, info)
+	}
+	if err := lister.Err(); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+func (s *natsRepo) GetMessage(ctx context.Context, stream string, seq uint64) (*jetstream.RawStreamMsg, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st, err := js.Stream(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetMsg(ctx, seq)
+}
+
+func (s *natsRepo) DeleteMessage(ctx context.Context, stream string, seq uint64) error {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return err
+	}
+	st, err := js.Stream(ctx, stream)
+	if err != nil {
+		return err
+	}
+	return st.DeleteMsg(ctx, seq)
+}
+
+// OrderedConsumer reads the stream in order from startSeq without creating a durable consumer
+func (s *natsRepo) OrderedConsumer(ctx context.Context, stream string, startSeq uint64) (jetstream.Consumer, error) {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return js.OrderedConsumer(ctx, stream, jetstream.OrderedConsumerConfig{
+		DeliverPolicy: jetstream.DeliverByStartSequencePolicy,
+		OptStartSeq:   startSeq,
+	})
+}
+
+// SettleMessage publishes an acknowledgement to the ack reply subject
+// of a delivered message. +ACK waits for the server to confirm it.
+func (s *natsRepo) SettleMessage(ctx context.Context, ackSubject, ack string) error {
+	js, err := s.jsClient.GetJetStream(ctx)
+	if err != nil {
+		return err
+	}
+	if err := js.Conn().Publish(ackSubject, []byte(ack)); err != nil {
+		return err
+	}
+	return js.Conn().FlushWithContext(ctx)
+}
+
+// AddMicroService registers a NATS micro service on one of the pooled connections
+func (s *natsRepo) AddMicroService(ctx context.Context, cfg micro.Config) (micro.Service, error) {
 	js, err := s.jsClient.GetJetStream(ctx)
 	if err != nil {
 		return nil, err
 	}
-	lister := js.StreamNames(ctx)
-	return lister.Name(), nil
+	return micro.AddService(js.Conn(), cfg)
 }
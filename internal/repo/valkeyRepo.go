@@ -14,8 +14,31 @@ import (
 type ValkeyRepo interface {
 	StoreAckResult(ctx context.Context, id string, result entity.AckResult) error
 	GetAckStatus(ctx context.Context, id string) (string, error)
+
+	StoreDeliveryError(ctx context.Context, key string, reason string) error
+	GetDeliveryError(ctx context.Context, key string) (string, error)
+	DeleteDeliveryError(ctx context.Context, key string) error
+
+	StoreMoveTask(ctx context.Context, task entity.MessageMoveTask) error
+	GetMoveTask(ctx context.Context, handle string) (entity.MessageMoveTask, bool, error)
+	ListMoveTasks(ctx context.Context, account string) ([]entity.MessageMoveTask, error)
+	ListActiveMoveTasks(ctx context.Context) ([]entity.MessageMoveTask, error)
+	RequestMoveTaskCancel(ctx context.Context, handle string) error
+	MoveTaskCancelRequested(ctx context.Context, handle string) (bool, error)
+	AcquireMoveTaskLease(ctx context.Context, handle, owner string, ttl time.Duration) (bool, error)
+	RenewMoveTaskLease(ctx context.Context, handle, owner string, ttl time.Duration) (bool, error)
+	ReleaseMoveTaskLease(ctx context.Context, handle, owner string) error
+	ClaimMoveTaskSource(ctx context.Context, source, handle string) (bool, error)
+	GetMoveTaskSourceClaim(ctx context.Context, source string) (string, error)
+	ReleaseMoveTaskSource(ctx context.Context, source, handle string) error
+
+	ClaimConfirmation(ctx context.Context, token string, ttl time.Duration) (bool, error)
+	ReleaseConfirmation(ctx context.Context, token string) error
 }
 
+// deliveryErrorTTL keeps the last delivery error of a message between two retries
+const deliveryErrorTTL = 24 * time.Hour
+
 type valkeyRepo struct {
 	valkeyClient valkey.ValkeyClient
 }
@@ -40,3 +63,174 @@ func (s *valkeyRepo) StoreAckResult(ctx context.Context, id string, result entit
 func (s *valkeyRepo) GetAckStatus(ctx context.Context, id string) (string, error) {
 	return s.valkeyClient.GetValue(ctx, id)
 }
+
+func (s *valkeyRepo) StoreDeliveryError(ctx context.Context, key string, reason string) error {
+	err := s.valkeyClient.SetValueWithTTL(ctx, key, reason, deliveryErrorTTL)
+	if err != nil {
+		logs.GetLogger(ctx).Warn("Failed to save delivery error", zap.String("key", key), zap.Error(err))
+	}
+	return err
+}
+
+func (s *valkeyRepo) GetDeliveryError(ctx context.Context, key string) (string, error) {
+	return s.valkeyClient.GetValue(ctx, key)
+}
+
+func (s *valkeyRepo) DeleteDeliveryError(ctx context.Context, key string) error {
+	return s.valkeyClient.DeleteValue(ctx, key)
+}
+
+// Message move tasks are stored as JSON under moveTaskKey, indexed per account and,
+// while they run, in the active set scanned by the dispatchers. The source key holds the
+// handle of the unfinished task of a source topic.
+const (
+	moveTaskActiveKey   = "sns:movetasks:active"
+	moveTaskFinishedTTL = 14 * 24 * time.Hour
+)
+
+func moveTaskKey(handle string) string         { return "sns:movetask:" + handle }
+func moveTaskCancelKey(handle string) string   { return "sns:movetask:" + handle + ":cancel" }
+func moveTaskLeaseKey(handle string) string    { return "sns:movetask:" + handle + ":lease" }
+func moveTaskAccountKey(account string) string { return "sns:movetasks:" + account }
+func moveTaskSourceKey(source string) string   { return "sns:movetasks:source:" + source }
+
+// moveTaskRecord adds the internal progress fields to the stored task
+type moveTaskRecord struct {
+	entity.MessageMoveTask
+	Account      string `json:"account"`
+	LastSequence uint64 `json:"lastSequence"`
+	EndSequence  uint64 `json:"endSequence"`
+}
+
+func (s *valkeyRepo) StoreMoveTask(ctx context.Context, task entity.MessageMoveTask) error {
+	bytes, err := json.Marshal(moveTaskRecord{
+		MessageMoveTask: task,
+		Account:         task.Account,
+		LastSequence:    task.LastSequence,
+		EndSequence:     task.EndSequence,
+	})
+	if err != nil {
+		return err
+	}
+
+	if task.Finished() {
+		if err := s.valkeyClient.SetValueWithTTL(ctx, moveTaskKey(task.TaskHandle), string(bytes), moveTaskFinishedTTL); err != nil {
+			return err
+		}
+		_ = s.valkeyClient.DeleteValue(ctx, moveTaskCancelKey(task.TaskHandle))
+		_ = s.ReleaseMoveTaskSource(ctx, task.SourceSrn, task.TaskHandle)
+		return s.valkeyClient.RemoveSetMember(ctx, moveTaskActiveKey, task.TaskHandle)
+	}
+
+	if err := s.valkeyClient.SetValue(ctx, moveTaskKey(task.TaskHandle), string(bytes)); err != nil {
+		return err
+	}
+	// the claim of the source outlives moveTaskFinishedTTL while the task keeps saving progress
+	_, _ = s.valkeyClient.ExpireIfValue(ctx, moveTaskSourceKey(task.SourceSrn), task.TaskHandle, moveTaskFinishedTTL)
+	if err := s.valkeyClient.AddSetMember(ctx, moveTaskAccountKey(task.Account), task.TaskHandle); err != nil {
+		return err
+	}
+	return s.valkeyClient.AddSetMember(ctx, moveTaskActiveKey, task.TaskHandle)
+}
+
+// GetMoveTask returns false when the task does not exist (anymore)
+func (s *valkeyRepo) GetMoveTask(ctx context.Context, handle string) (entity.MessageMoveTask, bool, error) {
+	value, err := s.valkeyClient.GetValue(ctx, moveTaskKey(handle))
+	if err != nil || value == "" {
+		return entity.MessageMoveTask{}, false, err
+	}
+
+	var rec moveTaskRecord
+	if err := json.Unmarshal([]byte(value), &rec); err != nil {
+		return entity.MessageMoveTask{}, false, err
+	}
+	task := rec.MessageMoveTask
+	task.Account, task.LastSequence, task.EndSequence = rec.Account, rec.LastSequence, rec.EndSequence
+	return task, true, nil
+}
+
+// ListMoveTasks returns the tasks of the account, dropping the expired ones from the index
+func (s *valkeyRepo) ListMoveTasks(ctx context.Context, account string) ([]entity.MessageMoveTask, error) {
+	handles, err := s.valkeyClient.GetSetMembers(ctx, moveTaskAccountKey(account))
+	if err != nil {
+		return nil, err
+	}
+	return s.getMoveTasks(ctx, moveTaskAccountKey(account), handles)
+}
+
+// ListActiveMoveTasks returns the tasks that are not finished, of every account
+func (s *valkeyRepo) ListActiveMoveTasks(ctx context.Context) ([]entity.MessageMoveTask, error) {
+	handles, err := s.valkeyClient.GetSetMembers(ctx, moveTaskActiveKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.getMoveTasks(ctx, moveTaskActiveKey, handles)
+}
+
+func (s *valkeyRepo) getMoveTasks(ctx context.Context, indexKey string, handles []string) ([]entity.MessageMoveTask, error) {
+	tasks := make([]entity.MessageMoveTask, 0, len(handles))
+	for _, handle := range handles {
+		task, ok, err := s.GetMoveTask(ctx, handle)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			_ = s.valkeyClient.RemoveSetMember(ctx, indexKey, handle)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (s *valkeyRepo) RequestMoveTaskCancel(ctx context.Context, handle string) error {
+	return s.valkeyClient.SetValueWithTTL(ctx, moveTaskCancelKey(handle), "1", moveTaskFinishedTTL)
+}
+
+func (s *valkeyRepo) MoveTaskCancelRequested(ctx context.Context, handle string) (bool, error) {
+	value, err := s.valkeyClient.GetValue(ctx, moveTaskCancelKey(handle))
+	return value != "", err
+}
+
+// AcquireMoveTaskLease makes owner the only runner of the task until the lease expires
+func (s *valkeyRepo) AcquireMoveTaskLease(ctx context.Context, handle, owner string, ttl time.Duration) (bool, error) {
+	return s.valkeyClient.SetValueIfAbsent(ctx, moveTaskLeaseKey(handle), owner, ttl)
+}
+
+// RenewMoveTaskLease extends the lease, false when owner lost it
+func (s *valkeyRepo) RenewMoveTaskLease(ctx context.Context, handle, owner string, ttl time.Duration) (bool, error) {
+	return s.valkeyClient.ExpireIfValue(ctx, moveTaskLeaseKey(handle), owner, ttl)
+}
+
+func (s *valkeyRepo) ReleaseMoveTaskLease(ctx context.Context, handle, owner string) error {
+	_, err := s.valkeyClient.DeleteIfValue(ctx, moveTaskLeaseKey(handle), owner)
+	return err
+}
+
+// ClaimMoveTaskSource makes the task the only unfinished task of the source topic.
+// The claim is released when the task is stored with a final status.
+func (s *valkeyRepo) ClaimMoveTaskSource(ctx context.Context, source, handle string) (bool, error) {
+	return s.valkeyClient.SetValueIfAbsent(ctx, moveTaskSourceKey(source), handle, moveTaskFinishedTTL)
+}
+
+// GetMoveTaskSourceClaim returns the handle of the task holding the source, empty when none does
+func (s *valkeyRepo) GetMoveTaskSourceClaim(ctx context.Context, source string) (string, error) {
+	return s.valkeyClient.GetValue(ctx, moveTaskSourceKey(source))
+}
+
+func (s *valkeyRepo) ReleaseMoveTaskSource(ctx context.Context, source, handle string) error {
+	_, err := s.valkeyClient.DeleteIfValue(ctx, moveTaskSourceKey(source), handle)
+	return err
+}
+
+func confirmationKey(token string) string { return "sns:confirm:" + token }
+
+// ClaimConfirmation makes the caller the only sender of the SubscriptionConfirmation carrying the token
+func (s *valkeyRepo) ClaimConfirmation(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+	return s.valkeyClient.SetValueIfAbsent(ctx, confirmationKey(token), "sent", ttl)
+}
+
+// ReleaseConfirmation lets the confirmation be sent again after a failed attempt
+func (s *valkeyRepo) ReleaseConfirmation(ctx context.Context, token string) error {
+	return s.valkeyClient.DeleteValue(ctx, confirmationKey(token))
+}
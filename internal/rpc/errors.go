@@ -0,0 +1,25 @@
+package rpc
+
+import (
+	"errors"
+	"nats/internal/entity"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError maps the service errors to gRPC status codes, as errorResponse does to HTTP codes
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, entity.ErrInvalidSubject), errors.Is(err, entity.ErrInvalidParameter):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, entity.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, entity.ErrAuthorization):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, entity.ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.Internal, entity.InternalError.Error.Message)
+	}
+}
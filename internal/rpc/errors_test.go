@@ -0,0 +1,27 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"nats/internal/entity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	cases := map[error]codes.Code{
+		fmt.Errorf("%w: bad", entity.ErrInvalidParameter): codes.InvalidArgument,
+		fmt.Errorf("%w: bad", entity.ErrInvalidSubject):   codes.InvalidArgument,
+		fmt.Errorf("%w: topic", entity.ErrNotFound):       codes.NotFound,
+		fmt.Errorf("%w: owner", entity.ErrAuthorization):  codes.PermissionDenied,
+		fmt.Errorf("%w: exists", entity.ErrConflict):      codes.AlreadyExists,
+		errors.New("nats: timeout"):                       codes.Internal,
+	}
+	for err, code := range cases {
+		assert.Equal(t, code, status.Code(statusError(err)), err.Error())
+	}
+	assert.NotContains(t, status.Convert(statusError(errors.New("nats: timeout"))).Message(), "nats")
+}
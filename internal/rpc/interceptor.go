@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"context"
+	"nats/internal/context/logs"
+	"nats/internal/context/metrics"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// headerRequestID is the metadata key a caller may set to correlate its logs with ours
+const headerRequestID = "x-request-id"
+
+// requestContext injects the logger with the request id, as the HTTP middleware does
+func requestContext(ctx context.Context, logger *zap.Logger, method string) context.Context {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(headerRequestID); len(v) > 0 {
+			requestID = v[0]
+		}
+	}
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	ctx = logs.WithLogger(ctx, logger)
+	return logs.WithFields(ctx, zap.String("request_id", requestID), zap.String("method", method))
+}
+
+func unaryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(requestContext(ctx, logger, info.FullMethod), req)
+		metrics.GrpcCallCounter.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return resp, err
+	}
+}
+
+func streamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: requestContext(ss.Context(), logger, info.FullMethod)})
+		metrics.GrpcCallCounter.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return err
+	}
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,164 @@
+package rpc
+
+import (
+	"context"
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"nats/internal/service"
+	"nats/pkg/api/snsv1"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer returns the gRPC server of the sns.v1 API. The trace context of the caller
+// is read from the gRPC metadata (W3C traceparent) by the otelgrpc stats handler.
+func NewServer(logger *zap.Logger, topicSvc service.TopicService, publishSvc service.PublishService, streamSvc service.StreamService) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryInterceptor(logger)),
+		grpc.ChainStreamInterceptor(streamInterceptor(logger)),
+	)
+	snsv1.RegisterSnsServer(s, &snsServer{topicSvc: topicSvc, publishSvc: publishSvc, streamSvc: streamSvc})
+	return s
+}
+
+type snsServer struct {
+	snsv1.UnimplementedSnsServer
+
+	topicSvc   service.TopicService
+	publishSvc service.PublishService
+	streamSvc  service.StreamService
+}
+
+func (s *snsServer) CreateTopic(ctx context.Context, req *snsv1.CreateTopicRequest) (*snsv1.CreateTopicResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	tags := make([]entity.Tag, 0, len(req.GetTags()))
+	for _, t := range req.GetTags() {
+		tags = append(tags, entity.Tag{Key: t.GetKey(), Value: t.GetValue()})
+	}
+
+	topic, err := s.topicSvc.CreateTopic(ctx, req.GetAccountId(), entity.CreateTopicInput{
+		Name:       req.GetName(),
+		Subjects:   req.GetSubjects(),
+		Attributes: req.GetAttributes(),
+		Tags:       tags,
+	})
+	if err != nil {
+		logs.GetLogger(ctx).Error("Failed to create stream", zap.Error(err))
+		return nil, statusError(err)
+	}
+	return &snsv1.CreateTopicResponse{TopicSrn: topic.TopicSrn}, nil
+}
+
+func (s *snsServer) DeleteTopic(ctx context.Context, req *snsv1.DeleteTopicRequest) (*snsv1.DeleteTopicResponse, error) {
+	srn, err := entity.ParseSRN(req.GetTopicSrn())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if err := s.topicSvc.DeleteTopic(ctx, req.GetAccountId(), srn); err != nil {
+		logs.GetLogger(ctx).Error("Failed to delete stream", zap.Error(err))
+		return nil, statusError(err)
+	}
+	return &snsv1.DeleteTopicResponse{}, nil
+}
+
+func (s *snsServer) ListTopics(ctx context.Context, req *snsv1.ListTopicsRequest) (*snsv1.ListTopicsResponse, error) {
+	filter := entity.TopicFilter{TagKey: req.GetTagKey(), TagValue: req.GetTagValue()}
+	topics, nextToken, err := s.topicSvc.ListTopics(ctx, req.GetAccountId(), filter, req.GetNextToken(), int(req.GetMaxResults()))
+	if err != nil {
+		logs.GetLogger(ctx).Error("Topic list lookup failed", zap.Error(err))
+		return nil, statusError(err)
+	}
+
+	resp := &snsv1.ListTopicsResponse{TopicSrns: make([]string, 0, len(topics)), NextToken: nextToken}
+	for _, t := range topics {
+		resp.TopicSrns = append(resp.TopicSrns, t.TopicSrn)
+	}
+	return resp, nil
+}
+
+func (s *snsServer) Publish(ctx context.Context, req *snsv1.PublishRequest) (*snsv1.PublishResponse, error) {
+	var attrs map[string]entity.MessageAttributeValue
+	if len(req.GetMessageAttributes()) > 0 {
+		attrs = make(map[string]entity.MessageAttributeValue, len(req.GetMessageAttributes()))
+		for name, v := range req.GetMessageAttributes() {
+			attrs[name] = entity.MessageAttributeValue{DataType: v.GetDataType(), StringValue: v.GetStringValue(), BinaryValue: v.GetBinaryValue()}
+		}
+	}
+
+	id, err := s.publishSvc.PublishAsyncMessage(ctx, req.GetAccountId(), entity.PublishInput{
+		TopicName:              req.GetTopicName(),
+		Message:                req.GetMessage(),
+		Subject:                req.GetSubject(),
+		MessageGroupId:         req.GetMessageGroupId(),
+		MessageDeduplicationId: req.GetMessageDeduplicationId(),
+		MessageAttributes:      attrs,
+	})
+	if err != nil {
+		logs.GetLogger(ctx).Error("Failed to publish message", zap.Error(err))
+		return nil, statusError(err)
+	}
+	return &snsv1.PublishResponse{MessageId: id}, nil
+}
+
+func (s *snsServer) PublishCheck(ctx context.Context, req *snsv1.PublishCheckRequest) (*snsv1.PublishCheckResponse, error) {
+	if req.GetMessageId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "message_id is required")
+	}
+	st, err := s.publishSvc.CheckAckStatus(ctx, req.GetMessageId())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "message id not found")
+	}
+	return &snsv1.PublishCheckResponse{Status: st}, nil
+}
+
+// Subscribe streams the topic through an ephemeral consumer that is deleted when the call ends
+func (s *snsServer) Subscribe(req *snsv1.SubscribeRequest, stream grpc.ServerStreamingServer[snsv1.Message]) error {
+	ctx := stream.Context()
+	srn, err := entity.ParseSRN(req.GetTopicSrn())
+	if err != nil {
+		return statusError(err)
+	}
+
+	ts, err := s.streamSvc.Open(ctx, req.GetAccountId(), srn, req.GetLastSequenceNumber())
+	if err != nil {
+		logs.GetLogger(ctx).Error("Failed to open topic stream", zap.Error(err))
+		return statusError(err)
+	}
+	defer ts.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-ts.Events():
+			if err := stream.Send(newMessage(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func newMessage(e entity.StreamEvent) *snsv1.Message {
+	m := &snsv1.Message{
+		SequenceNumber: e.SequenceNumber,
+		MessageId:      e.MessageId,
+		Subject:        e.Subject,
+		Message:        e.Message,
+		Timestamp:      e.Timestamp,
+		MessageGroupId: e.MessageGroupId,
+	}
+	if len(e.MessageAttributes) > 0 {
+		m.MessageAttributes = make(map[string]*snsv1.MessageAttributeValue, len(e.MessageAttributes))
+		for name, v := range e.MessageAttributes {
+			m.MessageAttributes[name] = &snsv1.MessageAttributeValue{DataType: v.DataType, StringValue: v.StringValue, BinaryValue: v.BinaryValue}
+		}
+	}
+	return m
+}
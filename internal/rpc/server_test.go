@@ -0,0 +1,164 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"nats/internal/entity"
+	"nats/internal/service"
+	"nats/pkg/api/snsv1"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeTopicService struct {
+	service.TopicService
+	account string
+	input   entity.CreateTopicInput
+	err     error
+}
+
+func (f *fakeTopicService) CreateTopic(_ context.Context, account string, input entity.CreateTopicInput) (entity.Topic, error) {
+	f.account, f.input = account, input
+	return entity.Topic{TopicSrn: "srn:scp:sns:kr-west1:" + account + ":" + input.Name}, f.err
+}
+
+type fakePublishService struct {
+	service.PublishService
+	account string
+	input   entity.PublishInput
+}
+
+func (f *fakePublishService) PublishAsyncMessage(_ context.Context, account string, input entity.PublishInput) (string, error) {
+	f.account, f.input = account, input
+	return "msg-1", nil
+}
+
+type fakeStreamService struct {
+	stream      *fakeTopicStream
+	account     string
+	topic       entity.SRN
+	lastEventId uint64
+}
+
+func (f *fakeStreamService) Open(_ context.Context, account string, topic entity.SRN, lastEventId uint64) (service.TopicStream, error) {
+	f.account, f.topic, f.lastEventId = account, topic, lastEventId
+	return f.stream, nil
+}
+
+type fakeTopicStream struct {
+	events chan entity.StreamEvent
+	closed chan struct{}
+}
+
+func (s *fakeTopicStream) Events() <-chan entity.StreamEvent { return s.events }
+func (s *fakeTopicStream) Close()                            { close(s.closed) }
+
+// newTestClient serves the services on an in-memory listener and returns a client of it
+func newTestClient(t *testing.T, topicSvc service.TopicService, publishSvc service.PublishService, streamSvc service.StreamService) snsv1.SnsClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(zap.NewNop(), topicSvc, publishSvc, streamSvc)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return snsv1.NewSnsClient(conn)
+}
+
+func TestServer_CreateTopic(t *testing.T) {
+	topics := &fakeTopicService{}
+	client := newTestClient(t, topics, nil, nil)
+
+	resp, err := client.CreateTopic(context.Background(), &snsv1.CreateTopicRequest{
+		AccountId:  "acct",
+		Name:       "orders",
+		Attributes: map[string]string{entity.AttrRetentionPeriod: "3600"},
+		Tags:       []*snsv1.Tag{{Key: "team", Value: "payments"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "srn:scp:sns:kr-west1:acct:orders", resp.GetTopicSrn())
+	assert.Equal(t, "acct", topics.account)
+	assert.Equal(t, "orders", topics.input.Name)
+	assert.Equal(t, "3600", topics.input.Attributes[entity.AttrRetentionPeriod])
+	assert.Equal(t, []entity.Tag{{Key: "team", Value: "payments"}}, topics.input.Tags)
+
+	_, err = client.CreateTopic(context.Background(), &snsv1.CreateTopicRequest{AccountId: "acct"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	topics.err = fmt.Errorf("%w: exists", entity.ErrConflict)
+	_, err = client.CreateTopic(context.Background(), &snsv1.CreateTopicRequest{AccountId: "acct", Name: "orders"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
+func TestServer_PublishWithAttributes(t *testing.T) {
+	publish := &fakePublishService{}
+	client := newTestClient(t, nil, publish, nil)
+
+	resp, err := client.Publish(context.Background(), &snsv1.PublishRequest{
+		AccountId: "acct",
+		TopicName: "orders",
+		Message:   "order created",
+		Subject:   "orders.eu.created",
+		MessageAttributes: map[string]*snsv1.MessageAttributeValue{
+			"store":  {DataType: "String", StringValue: "example_corp"},
+			"digest": {DataType: "Binary", BinaryValue: []byte{0x01, 0x02}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "msg-1", resp.GetMessageId())
+	assert.Equal(t, "acct", publish.account)
+	assert.Equal(t, "orders.eu.created", publish.input.Subject)
+	assert.Equal(t, map[string]entity.MessageAttributeValue{
+		"store":  {DataType: "String", StringValue: "example_corp"},
+		"digest": {DataType: "Binary", BinaryValue: []byte{0x01, 0x02}},
+	}, publish.input.MessageAttributes)
+}
+
+func TestServer_SubscribeClosesStream(t *testing.T) {
+	stream := &fakeTopicStream{events: make(chan entity.StreamEvent, 1), closed: make(chan struct{})}
+	streams := &fakeStreamService{stream: stream}
+	client := newTestClient(t, nil, nil, streams)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := client.Subscribe(ctx, &snsv1.SubscribeRequest{
+		AccountId:          "acct",
+		TopicSrn:           "srn:scp:sns:kr-west1:acct:orders",
+		LastSequenceNumber: 41,
+	})
+	assert.NoError(t, err)
+
+	stream.events <- entity.StreamEvent{SequenceNumber: 42, MessageId: "msg-1", Message: "hello"}
+	msg, err := sub.Recv()
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 42, msg.GetSequenceNumber())
+		assert.Equal(t, "hello", msg.GetMessage())
+	}
+	assert.Equal(t, "acct", streams.account)
+	assert.Equal(t, "orders", streams.topic.Topic)
+	assert.EqualValues(t, 41, streams.lastEventId)
+
+	// the consumer of the stream is removed once the client goes away
+	cancel()
+	select {
+	case <-stream.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("topic stream was not closed after the client cancelled")
+	}
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nats/internal/context/logs"
+	"nats/internal/context/metrics"
+	"nats/internal/context/traces"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"strconv"
+	"strings"
+	"time"
+
+	gonats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+// DeadLetterDispatcher copies messages that exhausted their deliveries to the dead-letter
+// topic named by the subscription RedrivePolicy
+type DeadLetterDispatcher interface {
+	Start() error
+	Stop()
+}
+
+const (
+	// deadLetterConsumer is the durable consumer sharing the advisories between API instances
+	deadLetterConsumer = "sns-dead-letter"
+	// advisoryMaxAge drops advisories that could not be handled for this long
+	advisoryMaxAge = 7 * 24 * time.Hour
+	// deadLetterMaxRetryDelay bounds the backoff of an advisory that failed to be handled
+	deadLetterMaxRetryDelay = time.Minute
+)
+
+type deadLetterDispatcher struct {
+	ctx        context.Context
+	natsRepo   repo.NatsRepo
+	valkeyRepo repo.ValkeyRepo
+	region     string
+	timeout    time.Duration
+	consume    jetstream.ConsumeContext
+}
+
+// NewDeadLetterDispatcher creates the dispatcher. ctx carries the logger used by the advisory handler.
+func NewDeadLetterDispatcher(ctx context.Context, natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo, cfg *config.Config) DeadLetterDispatcher {
+	return &deadLetterDispatcher{
+		ctx:        ctx,
+		natsRepo:   natsRepo,
+		valkeyRepo: valkeyRepo,
+		region:     cfg.Region,
+		timeout:    10 * time.Second,
+	}
+}
+
+// Start captures the max-deliveries advisories in the advisory stream and consumes them
+// through a durable consumer, so that an advisory is handled even if every API instance was
+// down or failed to copy the message when it was published
+func (d *deadLetterDispatcher) Start() error {
+	ctx, cancel := context.WithTimeout(d.ctx, d.timeout)
+	defer cancel()
+
+	if err := d.ensureAdvisoryStream(ctx); err != nil {
+		return fmt.Errorf("advisory stream: %w", err)
+	}
+	consumer, err := d.ensureAdvisoryConsumer(ctx)
+	if err != nil {
+		return fmt.Errorf("advisory consumer: %w", err)
+	}
+
+	logger := logs.GetLogger(d.ctx)
+	cc, err := consumer.Consume(d.handle, jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+		logger.Warn("Max deliveries advisory consume error", zap.Error(err))
+	}))
+	if err != nil {
+		return fmt.Errorf("consume advisories: %w", err)
+	}
+	d.consume = cc
+	return nil
+}
+
+// Stop stops consuming and waits for the advisory being handled
+func (d *deadLetterDispatcher) Stop() {
+	if d.consume != nil {
+		d.consume.Drain()
+		<-d.consume.Closed()
+	}
+}
+
+// ensureAdvisoryStream creates the advisory stream, or updates it when it already exists
+func (d *deadLetterDispatcher) ensureAdvisoryStream(ctx context.Context) error {
+	cfg := jetstream.StreamConfig{
+		Name:      entity.AdvisoryStreamName,
+		Subjects:  []string{entity.AdvisoryMaxDeliveries},
+		Retention: jetstream.WorkQueuePolicy,
+		Storage:   jetstream.FileStorage,
+		MaxAge:    advisoryMaxAge,
+	}
+	_, err := d.natsRepo.CreateStream(ctx, cfg)
+	if errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		_, err = d.natsRepo.UpdateStream(ctx, cfg)
+	}
+	return err
+}
+
+// ensureAdvisoryConsumer creates the durable consumer shared by the API instances
+func (d *deadLetterDispatcher) ensureAdvisoryConsumer(ctx context.Context) (jetstream.Consumer, error) {
+	cfg := jetstream.ConsumerConfig{
+		Durable:    deadLetterConsumer,
+		AckPolicy:  jetstream.AckExplicitPolicy,
+		AckWait:    2 * d.timeout,
+		MaxDeliver: -1,
+	}
+	_, err := d.natsRepo.CreateConsumer(ctx, entity.AdvisoryStreamName, cfg)
+	if errors.Is(err, jetstream.ErrConsumerExists) {
+		_, err = d.natsRepo.UpdateConsumer(ctx, entity.AdvisoryStreamName, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.natsRepo.GetConsumer(ctx, entity.AdvisoryStreamName, deadLetterConsumer)
+}
+
+// handle acks the advisory once the message copy is stored in the dead-letter topic.
+// A failed copy is retried with backoff; the Nats-Msg-Id of the copy makes the retries idempotent.
+func (d *deadLetterDispatcher) handle(msg jetstream.Msg) {
+	var adv entity.MaxDeliveriesAdvisory
+	if err := json.Unmarshal(msg.Data(), &adv); err != nil {
+		logs.GetLogger(d.ctx).Warn("Invalid max deliveries advisory", zap.Error(err))
+		_ = msg.Term()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, d.timeout)
+	defer cancel()
+	ctx, span := traces.StartSpan(ctx, "delivery.deadLetter")
+	defer span.End()
+
+	logger := logs.GetLogger(ctx).With(zap.String("stream", adv.Stream), zap.String("consumer", adv.Consumer), zap.Uint64("seq", adv.StreamSeq))
+	moved, err := d.moveToDeadLetter(ctx, adv)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) || errors.Is(err, jetstream.ErrMsgNotFound) {
+		// the subscription or the message is gone, there is nothing left to copy
+		logger.Warn("Dead-letter message no longer available", logs.WithTraceFields(ctx, zap.Error(err))...)
+		_ = msg.Term()
+		return
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		delay := deadLetterRetryDelay(msg)
+		logger.Error("Failed to copy message to dead-letter topic", logs.WithTraceFields(ctx, zap.Error(err), zap.Duration("retryIn", delay))...)
+		if err := msg.NakWithDelay(delay); err != nil {
+			logger.Warn("Failed to nak max deliveries advisory", zap.Error(err))
+		}
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		// the advisory is redelivered and the copy dropped by the dead-letter topic
+		logger.Warn("Failed to ack max deliveries advisory", zap.Error(err))
+	}
+	if moved {
+		logger.Info("Message copied to dead-letter topic", logs.WithTraceFields(ctx, zap.Uint64("deliveries", adv.Deliveries))...)
+	}
+}
+
+// deadLetterRetryDelay doubles the delay from one second with every failed attempt
+func deadLetterRetryDelay(msg jetstream.Msg) time.Duration {
+	attempts := uint64(1)
+	if md, err := msg.Metadata(); err == nil && md.NumDelivered > 0 {
+		attempts = md.NumDelivered
+	}
+	if attempts > 7 {
+		return deadLetterMaxRetryDelay
+	}
+	return min(time.Second<<(attempts-1), deadLetterMaxRetryDelay)
+}
+
+// moveToDeadLetter copies the message to the dead-letter topic of the subscription.
+// It returns false when the consumer is not a subscription with a RedrivePolicy.
+func (d *deadLetterDispatcher) moveToDeadLetter(ctx context.Context, adv entity.MaxDeliveriesAdvisory) (bool, error) {
+	ci, err := d.natsRepo.GetConsumerInfo(ctx, adv.Stream, adv.Consumer)
+	if err != nil {
+		return false, err
+	}
+	md := ci.Config.Metadata
+	if md[entity.MetaRedrivePolicy] == "" {
+		return false, nil
+	}
+	_, target, err := entity.ParseRedrivePolicy(md[entity.MetaRedrivePolicy])
+	if err != nil {
+		return false, err
+	}
+	info, err := findTopicStream(ctx, d.natsRepo, target.Account, target.Topic)
+	if err != nil {
+		return false, fmt.Errorf("dead-letter topic %s: %w", target, err)
+	}
+	subject, err := topicDefaultSubject(info, target)
+	if err != nil {
+		return false, err
+	}
+
+	raw, err := d.natsRepo.GetMessage(ctx, adv.Stream, adv.StreamSeq)
+	if err != nil {
+		return false, err
+	}
+
+	errKey := entity.DeliveryErrorKey(adv.Stream, adv.Consumer, adv.StreamSeq)
+	lastError, _ := d.valkeyRepo.GetDeliveryError(ctx, errKey)
+
+	topic := entity.NewTopicSRN(d.region, md[entity.MetaAccount], md[entity.MetaTopic])
+	sub := topic
+	sub.Subscription = ci.Name
+
+	dlq := gonats.NewMsg(subject)
+	dlq.Data = raw.Data
+	for key, values := range raw.Header {
+		if strings.HasPrefix(key, "Nats-") {
+			continue // JetStream publish options of the original message
+		}
+		dlq.Header[key] = values
+	}
+	dlq.Header.Set(entity.HeaderDeadLetterTopicSrn, topic.String())
+	dlq.Header.Set(entity.HeaderDeadLetterSubscriptionSrn, sub.String())
+	dlq.Header.Set(entity.HeaderDeadLetterSubject, raw.Subject)
+	dlq.Header.Set(entity.HeaderDeadLetterSequence, strconv.FormatUint(adv.StreamSeq, 10))
+	dlq.Header.Set(entity.HeaderDeadLetterAttempts, strconv.FormatUint(adv.Deliveries, 10))
+	if lastError != "" {
+		dlq.Header.Set(entity.HeaderDeadLetterLastError, lastError)
+	}
+	// advisories may be repeated, the dead-letter stream drops the copies
+	dlq.Header.Set(jetstream.MsgIDHeader, fmt.Sprintf("dlq:%s:%s:%d", adv.Stream, adv.Consumer, adv.StreamSeq))
+
+	future, err := d.natsRepo.PublishAsyncMessage(ctx, dlq)
+	if err != nil {
+		return false, err
+	}
+	select {
+	case <-future.Ok():
+	case err := <-future.Err():
+		return false, err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	metrics.DeliveryCounter.WithLabelValues(md[entity.MetaProtocol], "dead_lettered").Inc()
+	_ = d.valkeyRepo.DeleteDeliveryError(ctx, errKey)
+	return true, nil
+}
+
+// topicDefaultSubject picks the subject dead-lettered and redriven messages are published on:
+// the topic name when the topic owns it, otherwise its first subject with wildcards replaced by "dlq"
+func topicDefaultSubject(info *jetstream.StreamInfo, target entity.SRN) (string, error) {
+	if def := entity.AccountSubject(target.Account, target.Topic); ownsSubject(info.Config.Subjects, def) {
+		return def, nil
+	}
+	if len(info.Config.Subjects) == 0 {
+		return "", fmt.Errorf("topic %s has no subject", target)
+	}
+	tokens := strings.Split(info.Config.Subjects[0], ".")
+	for i, t := range tokens {
+		if t == "*" || t == ">" {
+			tokens[i] = "dlq"
+		}
+	}
+	return strings.Join(tokens, "."), nil
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"testing"
+	"time"
+
+	gonats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicDefaultSubject(t *testing.T) {
+	target := entity.NewTopicSRN("kr-west1", "acct", "orders-dlq")
+	tests := []struct {
+		subjects []string
+		want     string
+	}{
+		{[]string{"sns.data.acct.orders-dlq"}, "sns.data.acct.orders-dlq"},
+		{[]string{"sns.data.acct.>"}, "sns.data.acct.orders-dlq"},
+		{[]string{"sns.data.acct.failed.*"}, "sns.data.acct.failed.dlq"},
+		{[]string{"sns.data.acct.failed.>", "sns.data.acct.other"}, "sns.data.acct.failed.dlq"},
+	}
+
+	for _, tt := range tests {
+		info := &jetstream.StreamInfo{Config: jetstream.StreamConfig{Subjects: tt.subjects}}
+		got, err := topicDefaultSubject(info, target)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%v", tt.subjects)
+	}
+}
+
+// fakeDeadLetterRepo holds a subscription of acct/orders with a RedrivePolicy to acct/orders-dlq
+// and the message that exhausted its deliveries
+type fakeDeadLetterRepo struct {
+	repo.NatsRepo
+	consumerErr error
+	publishErr  error
+	msg         *jetstream.RawStreamMsg
+	published   []*gonats.Msg
+}
+
+func (r *fakeDeadLetterRepo) GetConsumerInfo(_ context.Context, stream, name string) (*jetstream.ConsumerInfo, error) {
+	if r.consumerErr != nil {
+		return nil, r.consumerErr
+	}
+	return &jetstream.ConsumerInfo{Stream: stream, Name: name, Config: jetstream.ConsumerConfig{
+		Durable: name,
+		Metadata: map[string]string{
+			entity.MetaAccount:       "acct",
+			entity.MetaTopic:         "orders",
+			entity.MetaProtocol:      entity.ProtocolHTTPS,
+			entity.MetaRedrivePolicy: `{"deadLetterTargetSrn":"srn:scp:sns:kr-west1:acct:orders-dlq"}`,
+		},
+	}}, nil
+}
+
+func (r *fakeDeadLetterRepo) GetStreamInfo(_ context.Context, name string) (*jetstream.StreamInfo, error) {
+	if name != entity.StreamName("acct", "orders-dlq") {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	return &jetstream.StreamInfo{Config: jetstream.StreamConfig{
+		Name:     name,
+		Subjects: []string{entity.AccountSubject("acct", "orders-dlq")},
+		Metadata: map[string]string{entity.MetaAccount: "acct", entity.MetaTopic: "orders-dlq"},
+	}}, nil
+}
+
+func (r *fakeDeadLetterRepo) GetMessage(context.Context, string, uint64) (*jetstream.RawStreamMsg, error) {
+	if r.msg == nil {
+		return nil, jetstream.ErrMsgNotFound
+	}
+	return r.msg, nil
+}
+
+func (r *fakeDeadLetterRepo) PublishAsyncMessage(_ context.Context, msg *gonats.Msg) (jetstream.PubAckFuture, error) {
+	if r.publishErr != nil {
+		return nil, r.publishErr
+	}
+	r.published = append(r.published, msg)
+	ok := make(chan *jetstream.PubAck, 1)
+	ok <- &jetstream.PubAck{}
+	return fakePubAckFuture{ok: ok}, nil
+}
+
+// fakeDeliveryErrorRepo keeps the last delivery error of each message
+type fakeDeliveryErrorRepo struct {
+	repo.ValkeyRepo
+	errors map[string]string
+}
+
+func (r *fakeDeliveryErrorRepo) GetDeliveryError(_ context.Context, key string) (string, error) {
+	return r.errors[key], nil
+}
+
+func (r *fakeDeliveryErrorRepo) DeleteDeliveryError(_ context.Context, key string) error {
+	delete(r.errors, key)
+	return nil
+}
+
+func newTestDeadLetter() (*deadLetterDispatcher, *fakeDeadLetterRepo, *fakeDeliveryErrorRepo) {
+	msg := &jetstream.RawStreamMsg{
+		Subject: entity.AccountSubject("acct", "orders") + ".created",
+		Data:    []byte("order created"),
+		Header: gonats.Header{
+			entity.HeaderMessageId:         []string{"msg-1"},
+			"Store":                        []string{"example_corp"},
+			jetstream.MsgIDHeader:          []string{"publish-1"},
+			jetstream.ExpectedStreamHeader: []string{"acct_orders"},
+		},
+	}
+	natsRepo := &fakeDeadLetterRepo{msg: msg}
+	valkeyRepo := &fakeDeliveryErrorRepo{errors: map[string]string{
+		entity.DeliveryErrorKey("acct_orders", "sub-1", 42): "status 500",
+	}}
+	d := NewDeadLetterDispatcher(context.Background(), natsRepo, valkeyRepo, &config.Config{Region: "kr-west1"}).(*deadLetterDispatcher)
+	return d, natsRepo, valkeyRepo
+}
+
+func advisoryMsg(t *testing.T) *fakeMsg {
+	t.Helper()
+	data, err := json.Marshal(entity.MaxDeliveriesAdvisory{Stream: "acct_orders", Consumer: "sub-1", StreamSeq: 42, Deliveries: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := newFakeMsg(string(data))
+	msg.delivered = 1
+	return msg
+}
+
+func TestDeadLetter_CopiesMessage(t *testing.T) {
+	d, natsRepo, valkeyRepo := newTestDeadLetter()
+	msg := advisoryMsg(t)
+
+	d.handle(msg)
+
+	assert.True(t, msg.acked)
+	if assert.Len(t, natsRepo.published, 1) {
+		dlq := natsRepo.published[0]
+		assert.Equal(t, entity.AccountSubject("acct", "orders-dlq"), dlq.Subject)
+		assert.Equal(t, "order created", string(dlq.Data))
+		assert.Equal(t, "msg-1", dlq.Header.Get(entity.HeaderMessageId))
+		assert.Equal(t, "example_corp", dlq.Header.Get("Store"))
+		// the publish options of the original message do not apply to the copy
+		assert.Empty(t, dlq.Header.Get(jetstream.ExpectedStreamHeader))
+		assert.Equal(t, "dlq:acct_orders:sub-1:42", dlq.Header.Get(jetstream.MsgIDHeader))
+
+		assert.Equal(t, "srn:scp:sns:kr-west1:acct:orders", dlq.Header.Get(entity.HeaderDeadLetterTopicSrn))
+		assert.Equal(t, "srn:scp:sns:kr-west1:acct:orders:sub-1", dlq.Header.Get(entity.HeaderDeadLetterSubscriptionSrn))
+		assert.Equal(t, entity.AccountSubject("acct", "orders")+".created", dlq.Header.Get(entity.HeaderDeadLetterSubject))
+		assert.Equal(t, "42", dlq.Header.Get(entity.HeaderDeadLetterSequence))
+		assert.Equal(t, "4", dlq.Header.Get(entity.HeaderDeadLetterAttempts))
+		assert.Equal(t, "status 500", dlq.Header.Get(entity.HeaderDeadLetterLastError))
+	}
+	assert.Empty(t, valkeyRepo.errors)
+}
+
+func TestDeadLetter_RetriesFailedCopy(t *testing.T) {
+	d, natsRepo, valkeyRepo := newTestDeadLetter()
+	natsRepo.publishErr = errors.New("nats: timeout")
+
+	msg := advisoryMsg(t)
+	d.handle(msg)
+	assert.False(t, msg.acked)
+	assert.Equal(t, time.Second, msg.nakDelay)
+	assert.NotEmpty(t, valkeyRepo.errors)
+
+	msg = advisoryMsg(t)
+	msg.delivered = 3
+	d.handle(msg)
+	assert.Equal(t, 4*time.Second, msg.nakDelay)
+
+	msg = advisoryMsg(t)
+	msg.delivered = 30
+	d.handle(msg)
+	assert.Equal(t, deadLetterMaxRetryDelay, msg.nakDelay)
+
+	natsRepo.publishErr = nil
+	msg = advisoryMsg(t)
+	d.handle(msg)
+	assert.True(t, msg.acked)
+	assert.Len(t, natsRepo.published, 1)
+}
+
+func TestDeadLetter_DropsAdvisoryWithNothingToCopy(t *testing.T) {
+	d, natsRepo, _ := newTestDeadLetter()
+	natsRepo.msg = nil
+	msg := advisoryMsg(t)
+	d.handle(msg)
+	assert.True(t, msg.terminated)
+
+	d, natsRepo, _ = newTestDeadLetter()
+	natsRepo.consumerErr = jetstream.ErrConsumerNotFound
+	msg = advisoryMsg(t)
+	d.handle(msg)
+	assert.True(t, msg.terminated)
+	assert.Empty(t, natsRepo.published)
+
+	msg = newFakeMsg("not json")
+	d.handle(msg)
+	assert.True(t, msg.terminated)
+}
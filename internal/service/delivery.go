@@ -0,0 +1,526 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"nats/internal/context/logs"
+	"nats/internal/context/metrics"
+	"nats/internal/context/traces"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"nats/pkg/filterpolicy"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// DeliveryDispatcher pushes the messages of HTTP/HTTPS subscriptions to their endpoints
+type DeliveryDispatcher interface {
+	Start()
+	Stop()
+}
+
+// deliveryTarget is a subscription as seen by the delivery path
+type deliveryTarget struct {
+	Stream          string
+	Consumer        string
+	Account         string
+	SubscriptionSrn string
+	TopicSrn        string
+	Protocol        string
+	Endpoint        string
+	Policy          *entity.DeliveryPolicy // nil when the subscription has no DeliveryPolicy
+	DeadLetter      bool                   // the subscription has a RedrivePolicy
+	Filter          *filterpolicy.Policy   // nil when every message is delivered
+	FilterBody      bool                   // Filter matches the JSON body instead of the attributes
+	Raw             bool                   // RawMessageDelivery, the body is posted without the envelope
+	ConfirmedSeq    uint64                 // messages up to this stream sequence were published before the confirmation
+	Fifo            bool                   // messages are delivered in order within each MessageGroupId
+
+	metadata map[string]string // consumer metadata the target was built from
+	groups   *groupSequencer   // orders the deliveries of a FIFO target, set by its worker
+}
+
+type deliveryWorker struct {
+	target  deliveryTarget
+	consume jetstream.ConsumeContext
+	cancel  context.CancelFunc
+	done    chan struct{} // closed when the FIFO keep-alive loop has returned
+}
+
+// stop cancels the worker context, so that a callback waiting for the throttle or an endpoint
+// slot returns at once, and waits until the last callback has returned
+func (w *deliveryWorker) stop() {
+	w.cancel()
+	w.consume.Drain()
+	<-w.consume.Closed()
+	<-w.done
+}
+
+type deliveryDispatcher struct {
+	ctx              context.Context
+	natsRepo         repo.NatsRepo
+	valkeyRepo       repo.ValkeyRepo
+	cfg              config.DeliveryConfig
+	region           string
+	subscribeBaseURL string // public API URL of the SubscribeURL in confirmations
+	sender           *webhookSender
+	limiter          *endpointLimiter
+
+	mu       sync.Mutex
+	workers  map[string]*deliveryWorker // keyed by subscription SRN
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	inflight sync.WaitGroup
+}
+
+// NewDeliveryDispatcher creates a dispatcher that follows every HTTP/HTTPS subscription.
+// ctx carries the logger used by the background workers.
+func NewDeliveryDispatcher(ctx context.Context, natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo, signer *NotificationSigner, cfg *config.Config) DeliveryDispatcher {
+	dcfg := cfg.Delivery
+	if dcfg.ConcurrencyPerEndpoint <= 0 {
+		dcfg.ConcurrencyPerEndpoint = 10
+	}
+	if dcfg.Timeout <= 0 {
+		dcfg.Timeout = 15 * time.Second
+	}
+	if dcfg.RetryDelay <= 0 {
+		dcfg.RetryDelay = 20 * time.Second
+	}
+	if dcfg.SyncInterval <= 0 {
+		dcfg.SyncInterval = 10 * time.Second
+	}
+
+	return &deliveryDispatcher{
+		ctx:              ctx,
+		natsRepo:         natsRepo,
+		valkeyRepo:       valkeyRepo,
+		cfg:              dcfg,
+		region:           cfg.Region,
+		subscribeBaseURL: cfg.Subscription.SubscribeBaseURL,
+		sender:           newWebhookSender(dcfg.Timeout, signer),
+		limiter:          newEndpointLimiter(dcfg.ConcurrencyPerEndpoint),
+		workers:          make(map[string]*deliveryWorker),
+		stopChan:         make(chan struct{}),
+	}
+}
+
+// Start loads the subscriptions and keeps them in sync until Stop is called
+func (d *deliveryDispatcher) Start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.cfg.SyncInterval)
+		defer ticker.Stop()
+
+		for {
+			d.sync()
+			select {
+			case <-ticker.C:
+			case <-d.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops consuming and waits for the in-flight deliveries to finish. The workers are
+// stopped first, so no callback starts a delivery once inflight is waited on.
+func (d *deliveryDispatcher) Stop() {
+	close(d.stopChan)
+	d.wg.Wait()
+
+	d.mu.Lock()
+	for key, w := range d.workers {
+		w.stop()
+		delete(d.workers, key)
+	}
+	d.mu.Unlock()
+	d.inflight.Wait()
+}
+
+// sync starts workers for new or changed subscriptions and stops the ones that disappeared
+func (d *deliveryDispatcher) sync() {
+	ctx, cancel := context.WithTimeout(d.ctx, d.cfg.SyncInterval)
+	defer cancel()
+	logger := logs.GetLogger(ctx)
+
+	targets, pending, failed, err := d.listTargets(ctx)
+	if err != nil {
+		logger.Warn("Failed to load subscriptions", zap.Error(err))
+		return
+	}
+	d.confirmPending(ctx, pending)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, w := range d.workers {
+		if failed[w.target.Stream] {
+			continue // the subscriptions of the stream are unknown until the next sync
+		}
+		if t, ok := targets[key]; !ok || !maps.Equal(t.metadata, w.target.metadata) {
+			w.stop()
+			delete(d.workers, key)
+		}
+	}
+	for key, t := range targets {
+		if _, ok := d.workers[key]; ok {
+			continue
+		}
+		w, err := d.startWorker(ctx, t)
+		if err != nil {
+			logger.Warn("Failed to start delivery worker", zap.String("subscriptionSrn", key), zap.Error(err))
+			continue
+		}
+		d.workers[key] = w
+	}
+}
+
+// listTargets returns the confirmed HTTP/HTTPS subscriptions of every topic and the pending ones.
+// A stream whose consumers cannot be listed is skipped and returned in failed, so that a
+// single broken topic does not hold up the others.
+func (d *deliveryDispatcher) listTargets(ctx context.Context) (targets map[string]deliveryTarget, pending []deliveryTarget, failed map[string]bool, err error) {
+	streams, err := d.natsRepo.ListStreams(ctx, entity.SubjectRoot+".>")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	targets = make(map[string]deliveryTarget)
+	failed = make(map[string]bool)
+	for _, si := range streams {
+		if si.Config.Metadata[entity.MetaAccount] == "" {
+			continue
+		}
+		consumers, err := d.natsRepo.ListConsumers(ctx, si.Config.Name)
+		if err != nil {
+			logs.GetLogger(ctx).Warn("Failed to load subscriptions of topic", zap.String("stream", si.Config.Name), zap.Error(err))
+			failed[si.Config.Name] = true
+			continue
+		}
+		for _, ci := range consumers {
+			md := ci.Config.Metadata
+			if md[entity.MetaProtocol] != entity.ProtocolHTTP && md[entity.MetaProtocol] != entity.ProtocolHTTPS {
+				continue
+			}
+			t := newDeliveryTarget(d.region, ci)
+			if entity.IsPendingConfirmation(md) {
+				pending = append(pending, t)
+				continue
+			}
+			targets[t.SubscriptionSrn] = t
+		}
+	}
+	return targets, pending, failed, nil
+}
+
+// confirmPending sends the SubscriptionConfirmation of pending subscriptions and deletes the
+// expired ones. The valkey claim makes a single API instance send each token; a failed
+// confirmation is sent again on the next sync.
+func (d *deliveryDispatcher) confirmPending(ctx context.Context, pending []deliveryTarget) {
+	logger := logs.GetLogger(ctx)
+	now := time.Now()
+	for _, t := range pending {
+		if entity.ConfirmationExpired(t.metadata, now) {
+			err := d.natsRepo.DeleteConsumer(ctx, t.Stream, t.Consumer)
+			if err != nil && !errors.Is(err, jetstream.ErrConsumerNotFound) {
+				logger.Warn("Failed to delete expired subscription", zap.String("subscriptionSrn", t.SubscriptionSrn), zap.Error(err))
+				continue
+			}
+			logger.Info("Unconfirmed subscription expired", zap.String("subscriptionSrn", t.SubscriptionSrn))
+			continue
+		}
+
+		token := t.metadata[entity.MetaConfirmToken]
+		expires, _ := time.Parse(time.RFC3339, t.metadata[entity.MetaConfirmExpires])
+		claimed, err := d.valkeyRepo.ClaimConfirmation(ctx, token, expires.Sub(now))
+		if err != nil {
+			logger.Warn("Failed to claim subscription confirmation", zap.String("subscriptionSrn", t.SubscriptionSrn), zap.Error(err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		topic := entity.NewTopicSRN(d.region, t.Account, t.metadata[entity.MetaTopic])
+		subscribeURL := entity.SubscribeURL(d.subscribeBaseURL, topic, token)
+		d.inflight.Add(1)
+		go func() {
+			defer d.inflight.Done()
+			logger := logs.GetLogger(d.ctx).With(zap.String("subscriptionSrn", t.SubscriptionSrn))
+			if err := d.sender.sendConfirmation(d.ctx, t, token, subscribeURL); err != nil {
+				logger.Warn("Failed to send subscription confirmation", zap.Error(err))
+				_ = d.valkeyRepo.ReleaseConfirmation(d.ctx, token)
+				return
+			}
+			logger.Info("Subscription confirmation sent")
+		}()
+	}
+}
+
+func newDeliveryTarget(region string, ci *jetstream.ConsumerInfo) deliveryTarget {
+	md := ci.Config.Metadata
+	topic := entity.NewTopicSRN(region, md[entity.MetaAccount], md[entity.MetaTopic])
+	sub := topic
+	sub.Subscription = ci.Name
+	t := deliveryTarget{
+		Stream:          ci.Stream,
+		Consumer:        ci.Name,
+		Account:         md[entity.MetaAccount],
+		SubscriptionSrn: sub.String(),
+		TopicSrn:        topic.String(),
+		Protocol:        md[entity.MetaProtocol],
+		Endpoint:        md[entity.MetaEndpoint],
+		DeadLetter:      md[entity.MetaRedrivePolicy] != "",
+		Raw:             md[entity.MetaRawMessageDelivery] == "true",
+		ConfirmedSeq:    entity.ConfirmedSequence(md),
+		Fifo:            entity.IsFifoTopic(md[entity.MetaTopic]),
+		metadata:        maps.Clone(md),
+	}
+	if v := md[entity.MetaDeliveryPolicy]; v != "" {
+		if policy, err := entity.ParseDeliveryPolicy(v); err == nil {
+			t.Policy = &policy
+		}
+	}
+	if v := md[entity.MetaFilterPolicy]; v != "" {
+		if policy, err := filterpolicy.Parse([]byte(v), filterScope(md)); err == nil {
+			t.Filter = policy
+			t.FilterBody = md[entity.MetaFilterPolicyScope] == entity.FilterScopeMessageBody
+		}
+	}
+	return t
+}
+
+// matches applies the filter policy to the message body or attributes
+func (t deliveryTarget) matches(msg jetstream.Msg) bool {
+	if t.FilterBody {
+		return t.Filter.MatchBody(msg.Data())
+	}
+	return t.Filter.Match(entity.FilterAttributes(msg.Headers()))
+}
+
+func (d *deliveryDispatcher) startWorker(ctx context.Context, t deliveryTarget) (*deliveryWorker, error) {
+	consumer, err := d.natsRepo.GetConsumer(ctx, t.Stream, t.Consumer)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logs.GetLogger(d.ctx).With(zap.String("subscriptionSrn", t.SubscriptionSrn))
+	workerCtx, cancel := context.WithCancel(d.ctx)
+	throttle := newDeliveryThrottle(t.Policy)
+	if t.Fifo {
+		t.groups = newGroupSequencer()
+	}
+	cc, err := consumer.Consume(func(msg jetstream.Msg) {
+		// a message dropped here is redelivered once its AckWait expires
+		if throttle != nil && throttle.Wait(workerCtx) != nil {
+			return
+		}
+		d.handle(workerCtx, t, msg)
+	},
+		jetstream.PullMaxMessages(d.cfg.ConcurrencyPerEndpoint),
+		jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+			if errors.Is(err, jetstream.ErrConsumerDeleted) || errors.Is(err, jetstream.ErrConsumerNotFound) {
+				logger.Info("Subscription consumer removed", zap.Error(err))
+				return
+			}
+			logger.Warn("Subscription consume error", zap.Error(err))
+		}),
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if t.groups != nil {
+			d.keepGroups(workerCtx, t)
+		}
+	}()
+	return &deliveryWorker{target: t, consume: cc, cancel: cancel, done: done}, nil
+}
+
+// keepGroups keeps the held messages of a FIFO target from being redelivered and resumes the
+// groups whose failed message did not come back, until ctx is done
+func (d *deliveryDispatcher) keepGroups(ctx context.Context, t deliveryTarget) {
+	ticker := time.NewTicker(groupKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		t.groups.keepAlive(ctx)
+		for _, msg := range t.groups.expired(time.Now()) {
+			d.start(ctx, t, msg)
+		}
+	}
+}
+
+// newDeliveryThrottle returns the rate limiter of the policy's throttlePolicy, nil when unthrottled
+func newDeliveryThrottle(policy *entity.DeliveryPolicy) *rate.Limiter {
+	if policy == nil || policy.MaxReceivesPerSecond() == 0 {
+		return nil
+	}
+	n := policy.MaxReceivesPerSecond()
+	return rate.NewLimiter(rate.Limit(n), n)
+}
+
+// handle acks the messages published before the subscription was confirmed and the ones
+// the filter policy rejects, then waits for a free slot of the
+// endpoint and delivers the message in the background.
+// Blocking here keeps the consumer from pulling more than the endpoint can take.
+// When ctx is cancelled first the message is left to be redelivered after its AckWait.
+// On FIFO targets a message whose group is busy is held until the group gets to it.
+func (d *deliveryDispatcher) handle(ctx context.Context, t deliveryTarget, msg jetstream.Msg) {
+	if t.ConfirmedSeq > 0 {
+		if md, err := msg.Metadata(); err == nil && md.Sequence.Stream <= t.ConfirmedSeq {
+			if err := msg.Ack(); err != nil {
+				logs.GetLogger(d.ctx).Warn("Failed to ack message published before confirmation", zap.Error(err))
+			}
+			return
+		}
+	}
+	if t.Filter != nil && !t.matches(msg) {
+		metrics.DeliveryCounter.WithLabelValues(t.Protocol, "filtered").Inc()
+		if err := msg.Ack(); err != nil {
+			logs.GetLogger(d.ctx).Warn("Failed to ack filtered message", zap.Error(err))
+		}
+		return
+	}
+	if t.groups != nil && !t.groups.admit(msg) {
+		return
+	}
+	d.start(ctx, t, msg)
+}
+
+// start waits for a free slot of the endpoint and delivers the message in the background.
+// On FIFO targets the next held message of the group follows in the same slot.
+func (d *deliveryDispatcher) start(ctx context.Context, t deliveryTarget, msg jetstream.Msg) {
+	release, err := d.limiter.acquire(ctx, t.Endpoint)
+	if err != nil {
+		return
+	}
+	d.inflight.Add(1)
+	go func() {
+		defer d.inflight.Done()
+		defer release()
+		for msg != nil {
+			retry := d.deliver(t, msg)
+			if t.groups == nil || ctx.Err() != nil {
+				return
+			}
+			msg = t.groups.settle(msg, retry)
+		}
+	}()
+}
+
+// deliver posts the message and settles it: ack on 2xx, nak with delay otherwise.
+// It returns the delay of a naked message that will be redelivered, 0 otherwise.
+func (d *deliveryDispatcher) deliver(t deliveryTarget, msg jetstream.Msg) time.Duration {
+	ctx, span := traces.StartSpan(d.ctx, "delivery.webhook")
+	defer span.End()
+	logger := logs.GetLogger(ctx)
+
+	err := d.sender.send(ctx, t, msg)
+	if err != nil {
+		metrics.DeliveryCounter.WithLabelValues(t.Protocol, "failed").Inc()
+		span.SetStatus(codes.Error, err.Error())
+		logger.Warn("Delivery failed", logs.WithTraceFields(ctx, zap.String("subscriptionSrn", t.SubscriptionSrn), zap.Error(err))...)
+		d.recordError(ctx, t, msg, err)
+		delay := d.retryDelay(t, msg)
+		if nakErr := msg.NakWithDelay(delay); nakErr != nil {
+			logger.Warn("Failed to nak message", zap.Error(nakErr))
+		}
+		if exhausted(t, msg) {
+			return 0
+		}
+		return delay
+	}
+
+	metrics.DeliveryCounter.WithLabelValues(t.Protocol, "delivered").Inc()
+	span.SetStatus(codes.Ok, "delivered")
+	if ackErr := msg.Ack(); ackErr != nil {
+		logger.Warn("Failed to ack message", zap.Error(ackErr))
+	}
+	return 0
+}
+
+// exhausted reports whether the message had its last delivery attempt under the DeliveryPolicy
+func exhausted(t deliveryTarget, msg jetstream.Msg) bool {
+	if t.Policy == nil {
+		return false
+	}
+	md, err := msg.Metadata()
+	return err == nil && int(md.NumDelivered) >= t.Policy.MaxDeliver()
+}
+
+// recordError keeps the failure reason for the dead-letter copy of the message
+func (d *deliveryDispatcher) recordError(ctx context.Context, t deliveryTarget, msg jetstream.Msg, err error) {
+	if !t.DeadLetter {
+		return
+	}
+	md, mdErr := msg.Metadata()
+	if mdErr != nil {
+		return
+	}
+	_ = d.valkeyRepo.StoreDeliveryError(ctx, entity.DeliveryErrorKey(t.Stream, t.Consumer, md.Sequence.Stream), err.Error())
+}
+
+// retryDelay returns the delay before the next attempt, following the subscription DeliveryPolicy
+func (d *deliveryDispatcher) retryDelay(t deliveryTarget, msg jetstream.Msg) time.Duration {
+	if t.Policy == nil {
+		return d.cfg.RetryDelay
+	}
+	md, err := msg.Metadata()
+	if err != nil {
+		return d.cfg.RetryDelay
+	}
+	return t.Policy.RetryDelay(int(md.NumDelivered))
+}
+
+// endpointLimiter bounds the number of in-flight deliveries per endpoint host
+type endpointLimiter struct {
+	limit int
+
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+func newEndpointLimiter(limit int) *endpointLimiter {
+	return &endpointLimiter{limit: limit, sems: make(map[string]chan struct{})}
+}
+
+// acquire blocks until a slot of the endpoint host is free and returns its release function,
+// or returns the error of ctx when it is done first
+func (l *endpointLimiter) acquire(ctx context.Context, endpoint string) (func(), error) {
+	key := endpoint
+	if u, err := url.Parse(endpoint); err == nil {
+		key = u.Scheme + "://" + u.Host
+	}
+
+	l.mu.Lock()
+	sem, ok := l.sems[key]
+	if !ok {
+		sem = make(chan struct{}, l.limit)
+		l.sems[key] = sem
+	}
+	l.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
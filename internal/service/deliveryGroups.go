@@ -0,0 +1,150 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// groupKeepAliveInterval is how often held messages are marked in progress,
+// well within subscriptionAckWait so the server never redelivers them while they wait
+const groupKeepAliveInterval = subscriptionAckWait / 3
+
+// groupSequencer keeps the deliveries of a FIFO subscription in order within each message group.
+// A group has at most one message being delivered. The messages of a busy group are held,
+// and marked in progress so they are not redelivered, while the other groups keep going.
+// A failed message is naked and the group waits for its redelivery before moving on.
+type groupSequencer struct {
+	mu     sync.Mutex
+	groups map[string]*messageGroup
+}
+
+type messageGroup struct {
+	busy      bool            // a message of the group is being delivered
+	waitSeq   uint64          // stream sequence of the naked message the group waits for, 0 when none
+	waitUntil time.Time       // the group stops waiting for waitSeq after this
+	held      []jetstream.Msg // messages waiting for their turn, in stream order
+}
+
+func newGroupSequencer() *groupSequencer {
+	return &groupSequencer{groups: make(map[string]*messageGroup)}
+}
+
+// admit reports whether the message can be delivered now. Otherwise it is held until the
+// message before it in the group is settled.
+func (s *groupSequencer) admit(msg jetstream.Msg) bool {
+	seq := streamSequence(msg)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := msg.Headers().Get(entity.HeaderMessageGroupId)
+	g, ok := s.groups[key]
+	if !ok {
+		g = &messageGroup{}
+		s.groups[key] = g
+	}
+	if !g.busy && (g.waitSeq == 0 || g.waitSeq == seq) {
+		g.busy, g.waitSeq = true, 0
+		return true
+	}
+	g.hold(msg, seq)
+	return false
+}
+
+// settle records the outcome of the group's delivery and returns the next message of the
+// group to deliver, nil when there is none. retry is the delay of a failed message that will
+// be redelivered, 0 when the message was acked or has no delivery left.
+func (s *groupSequencer) settle(msg jetstream.Msg, retry time.Duration) jetstream.Msg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := msg.Headers().Get(entity.HeaderMessageGroupId)
+	g, ok := s.groups[key]
+	if !ok {
+		return nil
+	}
+	g.busy = false
+	if retry > 0 {
+		g.waitSeq = streamSequence(msg)
+		g.waitUntil = time.Now().Add(retry + subscriptionAckWait)
+		return nil
+	}
+	return s.next(key, g)
+}
+
+// expired stops waiting for redeliveries that never came, e.g. because the message was
+// removed, and returns the messages that can be delivered instead
+func (s *groupSequencer) expired(now time.Time) []jetstream.Msg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var msgs []jetstream.Msg
+	for key, g := range s.groups {
+		if g.busy || g.waitSeq == 0 || now.Before(g.waitUntil) {
+			continue
+		}
+		g.waitSeq = 0
+		if msg := s.next(key, g); msg != nil {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+// keepAlive resets the AckWait of the held messages
+func (s *groupSequencer) keepAlive(ctx context.Context) {
+	s.mu.Lock()
+	var held []jetstream.Msg
+	for _, g := range s.groups {
+		held = append(held, g.held...)
+	}
+	s.mu.Unlock()
+
+	for _, msg := range held {
+		if err := msg.InProgress(); err != nil {
+			logs.GetLogger(ctx).Warn("Failed to extend held FIFO message", zap.Error(err))
+		}
+	}
+}
+
+// next marks the first held message of the group as being delivered, or forgets the idle group
+func (s *groupSequencer) next(key string, g *messageGroup) jetstream.Msg {
+	if len(g.held) == 0 {
+		if g.waitSeq == 0 {
+			delete(s.groups, key)
+		}
+		return nil
+	}
+	msg := g.held[0]
+	g.held = g.held[1:]
+	g.busy = true
+	return msg
+}
+
+// hold keeps the message in stream order. A redelivery of a held message replaces it.
+func (g *messageGroup) hold(msg jetstream.Msg, seq uint64) {
+	i, found := slices.BinarySearchFunc(g.held, seq, func(m jetstream.Msg, seq uint64) int {
+		return cmp.Compare(streamSequence(m), seq)
+	})
+	if found {
+		g.held[i] = msg
+		return
+	}
+	g.held = slices.Insert(g.held, i, msg)
+}
+
+// streamSequence returns the stream sequence of the message, 0 when it has no metadata
+func streamSequence(msg jetstream.Msg) uint64 {
+	md, err := msg.Metadata()
+	if err != nil {
+		return 0
+	}
+	return md.Sequence.Stream
+}
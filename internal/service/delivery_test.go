@@ -0,0 +1,621 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"nats/pkg/filterpolicy"
+	"nats/pkg/signature"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMsg records how the dispatcher settled a message
+type fakeMsg struct {
+	jetstream.Msg
+	data    []byte
+	headers nats.Header
+
+	delivered uint64
+	seq       uint64 // stream sequence, 42 when not set
+
+	mu         sync.Mutex
+	acked      bool
+	terminated bool
+	nakDelay   time.Duration
+	inProgress int
+	settled    chan struct{}
+}
+
+func newFakeMsg(data string) *fakeMsg {
+	h := nats.Header{}
+	h.Set(entity.HeaderMessageId, "msg-1")
+	return &fakeMsg{data: []byte(data), headers: h, settled: make(chan struct{})}
+}
+
+func (m *fakeMsg) Data() []byte         { return m.data }
+func (m *fakeMsg) Headers() nats.Header { return m.headers }
+func (m *fakeMsg) Subject() string      { return "sns.data.acct.orders.created" }
+
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	seq := m.seq
+	if seq == 0 {
+		seq = 42
+	}
+	return &jetstream.MsgMetadata{
+		NumDelivered: m.delivered,
+		Sequence:     jetstream.SequencePair{Stream: seq},
+		Timestamp:    time.Date(2026, 1, 2, 3, 4, 5, 6000000, time.UTC),
+	}, nil
+}
+
+func (m *fakeMsg) Ack() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.acked = true
+	close(m.settled)
+	return nil
+}
+
+func (m *fakeMsg) NakWithDelay(delay time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nakDelay = delay
+	close(m.settled)
+	return nil
+}
+
+func (m *fakeMsg) Term() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.terminated = true
+	close(m.settled)
+	return nil
+}
+
+func (m *fakeMsg) InProgress() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inProgress++
+	return nil
+}
+
+func (m *fakeMsg) isSettled() bool {
+	select {
+	case <-m.settled:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *fakeMsg) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-m.settled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not settled")
+	}
+}
+
+func newTestDispatcher(concurrency int, timeout time.Duration) *deliveryDispatcher {
+	cfg := &config.Config{Region: "kr-west1"}
+	cfg.Delivery.ConcurrencyPerEndpoint = concurrency
+	cfg.Delivery.Timeout = timeout
+	cfg.Delivery.RetryDelay = 7 * time.Second
+	return NewDeliveryDispatcher(context.Background(), nil, nil, nil, cfg).(*deliveryDispatcher)
+}
+
+func testTarget(endpoint string) deliveryTarget {
+	return deliveryTarget{
+		Account:         "acct",
+		SubscriptionSrn: "srn:scp:sns:kr-west1:acct:orders:sub-1",
+		TopicSrn:        "srn:scp:sns:kr-west1:acct:orders",
+		Protocol:        entity.ProtocolHTTP,
+		Endpoint:        endpoint,
+	}
+}
+
+func TestDeliver_AckOn2xx(t *testing.T) {
+	var got *http.Request
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got, body = r, string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(1, time.Second)
+	msg := newFakeMsg("hello")
+	d.handle(context.Background(), testTarget(srv.URL), msg)
+	msg.wait(t)
+
+	assert.True(t, msg.acked)
+	var n entity.Notification
+	assert.NoError(t, json.Unmarshal([]byte(body), &n))
+	assert.Equal(t, entity.Notification{
+		Version:   entity.NotificationVersion,
+		Type:      entity.MessageTypeNotification,
+		MessageId: "msg-1",
+		TopicSrn:  "srn:scp:sns:kr-west1:acct:orders",
+		Subject:   "orders.created",
+		Message:   "hello",
+		Timestamp: "2026-01-02T03:04:05.006Z",
+	}, n)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, entity.MessageTypeNotification, got.Header.Get(entity.HTTPHeaderMessageType))
+	assert.Equal(t, "msg-1", got.Header.Get(entity.HTTPHeaderMessageId))
+	assert.Equal(t, "srn:scp:sns:kr-west1:acct:orders:sub-1", got.Header.Get(entity.HTTPHeaderSubscriptionSrn))
+}
+
+func TestDeliver_SignedNotification(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	d := newTestDispatcher(1, time.Second)
+	d.sender.signer = &NotificationSigner{key: key, certURL: "https://sns.example.com/v1/signing-cert.pem"}
+	msg := newFakeMsg("hello")
+	d.handle(context.Background(), testTarget(srv.URL), msg)
+	msg.wait(t)
+
+	var m signature.Message
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, "https://sns.example.com/v1/signing-cert.pem", m.SigningCertURL)
+	assert.NoError(t, signature.VerifyWithCertificate(&m, &x509.Certificate{PublicKey: &key.PublicKey}))
+}
+
+func TestDeliver_RawMessageDelivery(t *testing.T) {
+	var got *http.Request
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got, body = r, string(b)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(1, time.Second)
+	target := testTarget(srv.URL)
+	target.Raw = true
+	msg := newFakeMsg("hello")
+	entity.SetMessageAttributeHeaders(msg.headers, map[string]entity.MessageAttributeValue{
+		"store": {DataType: entity.AttrTypeString, StringValue: "example_corp"},
+	})
+	d.handle(context.Background(), target, msg)
+	msg.wait(t)
+
+	assert.True(t, msg.acked)
+	assert.Equal(t, "hello", body)
+	assert.Equal(t, `{"store":{"DataType":"String","StringValue":"example_corp"}}`, got.Header.Get(entity.HTTPHeaderMessageAttributes))
+}
+
+func TestDeliver_NakOnErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(1, time.Second)
+	msg := newFakeMsg("hello")
+	d.handle(context.Background(), testTarget(srv.URL), msg)
+	msg.wait(t)
+
+	assert.False(t, msg.acked)
+	assert.Equal(t, 7*time.Second, msg.nakDelay)
+}
+
+func TestDeliver_NakOnTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	d := newTestDispatcher(1, 50*time.Millisecond)
+	msg := newFakeMsg("hello")
+	d.handle(context.Background(), testTarget(srv.URL), msg)
+	msg.wait(t)
+
+	assert.False(t, msg.acked)
+	assert.Equal(t, 7*time.Second, msg.nakDelay)
+}
+
+func TestDeliver_BoundsConcurrencyPerEndpoint(t *testing.T) {
+	const limit = 2
+	var current, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		current.Add(-1)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(limit, time.Second)
+	msgs := make([]*fakeMsg, 8)
+	for i := range msgs {
+		msgs[i] = newFakeMsg("hello")
+		d.handle(context.Background(), testTarget(srv.URL+"/hook"), msgs[i])
+	}
+	for _, msg := range msgs {
+		msg.wait(t)
+		assert.True(t, msg.acked)
+	}
+	assert.LessOrEqual(t, peak.Load(), int32(limit))
+}
+
+func TestDeliver_CancelledWhileWaitingForSlot(t *testing.T) {
+	d := newTestDispatcher(1, time.Second)
+	target := testTarget("http://example.com/hook")
+	release, err := d.limiter.acquire(context.Background(), target.Endpoint)
+	assert.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.handle(ctx, target, newFakeMsg("hello"))
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handle kept waiting for the endpoint after its context was cancelled")
+	}
+	// nothing was started, so a shutdown does not wait on this message
+	d.inflight.Wait()
+}
+
+func TestDeliver_NakFollowsDeliveryPolicy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	policy, err := entity.ParseDeliveryPolicy(`{"healthyRetryPolicy":{"minDelayTarget":1,"maxDelayTarget":16,"numRetries":5,"backoffFunction":"geometric"}}`)
+	assert.NoError(t, err)
+	target := testTarget(srv.URL)
+	target.Policy = &policy
+
+	d := newTestDispatcher(1, time.Second)
+	msg := newFakeMsg("hello")
+	msg.delivered = 3
+	d.handle(context.Background(), target, msg)
+	msg.wait(t)
+
+	assert.Equal(t, 4*time.Second, msg.nakDelay)
+}
+
+func TestDeliver_SkipsMessagesPublishedBeforeConfirmation(t *testing.T) {
+	var posts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(1, time.Second)
+	target := testTarget(srv.URL)
+	target.ConfirmedSeq = 42 // fakeMsg has stream sequence 42
+	skipped := newFakeMsg("hello")
+	d.handle(context.Background(), target, skipped)
+	skipped.wait(t)
+	assert.True(t, skipped.acked)
+	assert.Zero(t, posts.Load())
+
+	target.ConfirmedSeq = 41
+	delivered := newFakeMsg("hello")
+	d.handle(context.Background(), target, delivered)
+	delivered.wait(t)
+	assert.True(t, delivered.acked)
+	assert.EqualValues(t, 1, posts.Load())
+}
+
+func TestDeliver_FilterPolicy(t *testing.T) {
+	var posts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+	}))
+	defer srv.Close()
+
+	policy, err := filterpolicy.Parse([]byte(`{"store": ["example_corp"]}`), filterpolicy.ScopeMessageAttributes)
+	assert.NoError(t, err)
+	target := testTarget(srv.URL)
+	target.Filter = policy
+	d := newTestDispatcher(1, time.Second)
+
+	rejected := newFakeMsg("hello")
+	rejected.headers.Set(entity.HeaderAttributePrefix+"store", "other")
+	d.handle(context.Background(), target, rejected)
+	rejected.wait(t)
+	assert.True(t, rejected.acked)
+	assert.Equal(t, int32(0), posts.Load())
+
+	accepted := newFakeMsg("hello")
+	accepted.headers.Set(entity.HeaderAttributePrefix+"store", "example_corp")
+	d.handle(context.Background(), target, accepted)
+	accepted.wait(t)
+	assert.True(t, accepted.acked)
+	assert.Equal(t, int32(1), posts.Load())
+}
+
+func TestDeliver_FilterPolicyMessageBody(t *testing.T) {
+	var posts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+	}))
+	defer srv.Close()
+
+	policy, err := filterpolicy.Parse([]byte(`{"order": {"store": ["example_corp"]}}`), filterpolicy.ScopeMessageBody)
+	assert.NoError(t, err)
+	target := testTarget(srv.URL)
+	target.Filter = policy
+	target.FilterBody = true
+	d := newTestDispatcher(1, time.Second)
+
+	for _, body := range []string{`{"order": {"store": "other"}}`, `not json`} {
+		rejected := newFakeMsg(body)
+		d.handle(context.Background(), target, rejected)
+		rejected.wait(t)
+		assert.True(t, rejected.acked)
+	}
+	assert.Equal(t, int32(0), posts.Load())
+
+	accepted := newFakeMsg(`{"order": {"store": "example_corp"}}`)
+	d.handle(context.Background(), target, accepted)
+	accepted.wait(t)
+	assert.True(t, accepted.acked)
+	assert.Equal(t, int32(1), posts.Load())
+}
+
+func TestSendConfirmation(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	sender := newWebhookSender(time.Second, &NotificationSigner{key: key, certURL: "https://sns.example.com/v1/signing-cert.pem"})
+	err = sender.sendConfirmation(context.Background(), testTarget(srv.URL), "token-1", "http://localhost:8080/v1/acct/orders?Action=confirmSubscription")
+	assert.NoError(t, err)
+
+	assert.Equal(t, entity.MessageTypeSubscriptionConfirmation, got.Header.Get(entity.HTTPHeaderMessageType))
+	var m signature.Message
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, signature.TypeSubscriptionConfirmation, m.Type)
+	assert.Equal(t, "token-1", m.Token)
+	assert.Equal(t, "http://localhost:8080/v1/acct/orders?Action=confirmSubscription", m.SubscribeURL)
+	assert.NoError(t, signature.VerifyWithCertificate(&m, &x509.Certificate{PublicKey: &key.PublicKey}))
+}
+
+// newGroupMsg returns a FIFO message of the group with the body and stream sequence
+func newGroupMsg(group, body string, seq uint64) *fakeMsg {
+	msg := newFakeMsg(body)
+	msg.headers.Set(entity.HeaderMessageGroupId, group)
+	msg.seq, msg.delivered = seq, 1
+	return msg
+}
+
+func TestDeliver_FifoGroupsDoNotBlockEachOther(t *testing.T) {
+	releaseSlow := make(chan struct{})
+	var mu sync.Mutex
+	var posts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n entity.Notification
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		if n.Message == "slow-1" {
+			<-releaseSlow
+		}
+		mu.Lock()
+		posts = append(posts, n.Message)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(4, 5*time.Second)
+	target := testTarget(srv.URL)
+	target.Fifo, target.groups = true, newGroupSequencer()
+
+	slow1, slow2 := newGroupMsg("slow", "slow-1", 1), newGroupMsg("slow", "slow-2", 2)
+	fast := newGroupMsg("fast", "fast-1", 3)
+	d.handle(context.Background(), target, slow1)
+	d.handle(context.Background(), target, slow2)
+	d.handle(context.Background(), target, fast)
+
+	// the other group is delivered while the first message of slow is still in flight
+	fast.wait(t)
+	assert.True(t, fast.acked)
+	assert.False(t, slow2.isSettled(), "slow-2 must wait for slow-1")
+
+	target.groups.keepAlive(context.Background())
+	assert.Equal(t, 1, slow2.inProgress, "held messages are kept from redelivery")
+
+	close(releaseSlow)
+	slow1.wait(t)
+	slow2.wait(t)
+	assert.True(t, slow1.acked)
+	assert.True(t, slow2.acked)
+	d.inflight.Wait()
+	assert.Equal(t, []string{"fast-1", "slow-1", "slow-2"}, posts)
+}
+
+func TestDeliver_FifoGroupWaitsForRedelivery(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	var mu sync.Mutex
+	var posts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n entity.Notification
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		mu.Lock()
+		posts = append(posts, n.Message)
+		mu.Unlock()
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(4, time.Second)
+	target := testTarget(srv.URL)
+	target.Fifo, target.groups = true, newGroupSequencer()
+
+	first, second := newGroupMsg("g1", "first", 1), newGroupMsg("g1", "second", 2)
+	d.handle(context.Background(), target, first)
+	first.wait(t)
+	assert.Equal(t, 7*time.Second, first.nakDelay)
+
+	// the group waits for the naked message instead of moving on
+	d.handle(context.Background(), target, second)
+	d.inflight.Wait()
+	assert.False(t, second.isSettled())
+
+	failing.Store(false)
+	redelivered := newGroupMsg("g1", "first", 1)
+	redelivered.delivered = 2
+	d.handle(context.Background(), target, redelivered)
+	redelivered.wait(t)
+	second.wait(t)
+	assert.True(t, redelivered.acked)
+	assert.True(t, second.acked)
+	d.inflight.Wait()
+	assert.Equal(t, []string{"first", "first", "second"}, posts)
+}
+
+func TestDeliver_FifoGroupMovesOnWhenExhausted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n entity.Notification
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		if n.Message == "poison" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	policy, err := entity.ParseDeliveryPolicy(`{"healthyRetryPolicy":{"minDelayTarget":1,"maxDelayTarget":1,"numRetries":1}}`)
+	assert.NoError(t, err)
+	d := newTestDispatcher(1, time.Second)
+	target := testTarget(srv.URL)
+	target.Policy = &policy
+	target.Fifo, target.groups = true, newGroupSequencer()
+
+	// the last attempt of poison fails: it goes to the dead-letter path and the group continues
+	poison, next := newGroupMsg("g1", "poison", 1), newGroupMsg("g1", "next", 2)
+	poison.delivered = 2
+	d.handle(context.Background(), target, poison)
+	d.handle(context.Background(), target, next)
+	poison.wait(t)
+	next.wait(t)
+	assert.False(t, poison.acked)
+	assert.True(t, next.acked)
+}
+
+func TestGroupSequencer_ExpiredWait(t *testing.T) {
+	s := newGroupSequencer()
+	first, second := newGroupMsg("g1", "first", 1), newGroupMsg("g1", "second", 2)
+	assert.True(t, s.admit(first))
+	assert.False(t, s.admit(second))
+	assert.Nil(t, s.settle(first, time.Second))
+
+	// a redelivery of a held message replaces it instead of queueing it twice
+	again := newGroupMsg("g1", "second", 2)
+	assert.False(t, s.admit(again))
+
+	assert.Empty(t, s.expired(time.Now()))
+	assert.Equal(t, []jetstream.Msg{again}, s.expired(time.Now().Add(time.Second+subscriptionAckWait+time.Millisecond)))
+	assert.Nil(t, s.settle(again, 0))
+	assert.Empty(t, s.groups, "idle groups are forgotten")
+}
+
+// fakeSyncRepo lists two topic streams, the consumers of one cannot be listed
+type fakeSyncRepo struct {
+	repo.NatsRepo
+}
+
+func (fakeSyncRepo) ListStreams(context.Context, string) ([]*jetstream.StreamInfo, error) {
+	return []*jetstream.StreamInfo{
+		{Config: jetstream.StreamConfig{Name: "acct_orders", Metadata: map[string]string{entity.MetaAccount: "acct"}}},
+		{Config: jetstream.StreamConfig{Name: "acct_invoices", Metadata: map[string]string{entity.MetaAccount: "acct"}}},
+	}, nil
+}
+
+func (fakeSyncRepo) ListConsumers(_ context.Context, stream string) ([]*jetstream.ConsumerInfo, error) {
+	if stream == "acct_orders" {
+		return nil, errors.New("nats: timeout")
+	}
+	return nil, nil
+}
+
+// fakeConsumeContext is a consume context that is closed at once
+type fakeConsumeContext struct {
+	jetstream.ConsumeContext
+	stopped bool
+}
+
+func (c *fakeConsumeContext) Drain() { c.stopped = true }
+
+func (c *fakeConsumeContext) Closed() <-chan struct{} {
+	closed := make(chan struct{})
+	close(closed)
+	return closed
+}
+
+func testWorker(stream, srn string) *deliveryWorker {
+	done := make(chan struct{})
+	close(done)
+	return &deliveryWorker{
+		target:  deliveryTarget{Stream: stream, SubscriptionSrn: srn},
+		consume: &fakeConsumeContext{},
+		cancel:  func() {},
+		done:    done,
+	}
+}
+
+func TestSync_KeepsWorkersOfStreamThatFailedToList(t *testing.T) {
+	d := newTestDispatcher(1, time.Second)
+	d.natsRepo = fakeSyncRepo{}
+	orders := testWorker("acct_orders", "srn:scp:sns:kr-west1:acct:orders:sub-1")
+	invoices := testWorker("acct_invoices", "srn:scp:sns:kr-west1:acct:invoices:sub-2")
+	d.workers[orders.target.SubscriptionSrn] = orders
+	d.workers[invoices.target.SubscriptionSrn] = invoices
+
+	d.sync()
+
+	assert.Contains(t, d.workers, orders.target.SubscriptionSrn)
+	assert.False(t, orders.consume.(*fakeConsumeContext).stopped)
+	// the subscription of the listed stream is gone, so its worker is stopped
+	assert.NotContains(t, d.workers, invoices.target.SubscriptionSrn)
+	assert.True(t, invoices.consume.(*fakeConsumeContext).stopped)
+}
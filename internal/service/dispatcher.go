@@ -91,6 +91,13 @@ func (d *ackDispatcher) process(task *entity.AckTask) {
 			span.SetStatus(codes.Error, "ACK reception failure")
 			_ = d.valkeyRepo.StoreAckResult(ctx, task.ID, entity.AckResult{Status: "FAILED"})
 		}
+	case err := <-task.AckFuture.Err():
+		logger.Error("ACK rejected", logs.WithTraceFields(ctx, zap.String("id", task.ID), zap.Error(err))...)
+		span.SetStatus(codes.Error, "ACK rejected")
+		_ = d.valkeyRepo.StoreAckResult(ctx, task.ID, entity.AckResult{Status: "FAILED"})
+		if task.OnError != nil {
+			task.OnError(err)
+		}
 	case <-time.After(task.TimeOut):
 		logger.Warn("ACK receive timeout", logs.WithTraceFields(ctx, zap.String("id", task.ID))...)
 		span.SetStatus(codes.Error, "ACK receive timeout")
@@ -0,0 +1,482 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"nats/internal/context/logs"
+	"nats/internal/context/traces"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	gonats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+type MessageMoveService interface {
+	StartMessageMoveTask(ctx context.Context, account string, input entity.StartMessageMoveTaskInput) (string, error)
+	ListMessageMoveTasks(ctx context.Context, account string, source entity.SRN, maxResults int) ([]entity.MessageMoveTask, error)
+	CancelMessageMoveTask(ctx context.Context, account, handle string) (int64, error)
+}
+
+// listMessageMoveTasks page size
+const (
+	defaultMoveTaskResults = 1
+	maxMoveTaskResults     = 10
+)
+
+type messageMoveService struct {
+	natsRepo   repo.NatsRepo
+	valkeyRepo repo.ValkeyRepo
+	cfg        *config.Config
+}
+
+func NewMessageMoveService(natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo, cfg *config.Config) MessageMoveService {
+	return &messageMoveService{natsRepo: natsRepo, valkeyRepo: valkeyRepo, cfg: cfg}
+}
+
+// StartMessageMoveTask records a RUNNING task covering the messages the source topic holds now.
+// A MessageMoveDispatcher picks the task up. Only one task may run per source topic.
+func (s *messageMoveService) StartMessageMoveTask(ctx context.Context, account string, input entity.StartMessageMoveTaskInput) (string, error) {
+	if err := entity.ValidateMoveTaskRate(input.MaxNumberOfMessagesPerSecond); err != nil {
+		return "", err
+	}
+	source, err := findOwnedTopicStream(ctx, s.natsRepo, s.cfg.Region, account, input.SourceSrn)
+	if err != nil {
+		return "", err
+	}
+
+	task := entity.MessageMoveTask{
+		TaskHandle:                        uuid.NewString(),
+		Status:                            entity.MoveTaskRunning,
+		SourceSrn:                         entity.NewTopicSRN(s.cfg.Region, account, input.SourceSrn.Topic).String(),
+		MaxNumberOfMessagesPerSecond:      input.MaxNumberOfMessagesPerSecond,
+		ApproximateNumberOfMessagesToMove: int64(source.State.Msgs),
+		StartedTimestamp:                  time.Now().UnixMilli(),
+		Account:                           account,
+		EndSequence:                       source.State.LastSeq,
+	}
+	if task.MaxNumberOfMessagesPerSecond == 0 {
+		task.MaxNumberOfMessagesPerSecond = entity.DefaultMoveTaskRate
+	}
+	if source.State.FirstSeq > 0 {
+		task.LastSequence = source.State.FirstSeq - 1
+	}
+
+	if input.DestinationSrn != nil {
+		dest, err := findOwnedTopicStream(ctx, s.natsRepo, s.cfg.Region, account, *input.DestinationSrn)
+		if err != nil {
+			return "", err
+		}
+		if dest.Config.Name == source.Config.Name {
+			return "", fmt.Errorf("%w: destination must differ from the source topic", entity.ErrInvalidParameter)
+		}
+		task.DestinationSrn = entity.NewTopicSRN(s.cfg.Region, account, input.DestinationSrn.Topic).String()
+	}
+
+	if err := s.claimSource(ctx, task); err != nil {
+		return "", err
+	}
+	if err := s.valkeyRepo.StoreMoveTask(ctx, task); err != nil {
+		_ = s.valkeyRepo.ReleaseMoveTaskSource(context.WithoutCancel(ctx), task.SourceSrn, task.TaskHandle)
+		return "", err
+	}
+	return task.TaskHandle, nil
+}
+
+// claimSource makes the task the only unfinished task of its source. A claim left by a task
+// that finished or was never stored, after a crash, is released and claimed again.
+func (s *messageMoveService) claimSource(ctx context.Context, task entity.MessageMoveTask) error {
+	for range 2 {
+		claimed, err := s.valkeyRepo.ClaimMoveTaskSource(ctx, task.SourceSrn, task.TaskHandle)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			return nil
+		}
+
+		holder, err := s.valkeyRepo.GetMoveTaskSourceClaim(ctx, task.SourceSrn)
+		if err != nil {
+			return err
+		}
+		if holder == "" {
+			continue
+		}
+		t, ok, err := s.valkeyRepo.GetMoveTask(ctx, holder)
+		if err != nil {
+			return err
+		}
+		if ok && !t.Finished() {
+			return fmt.Errorf("%w: task %s is already running for %s", entity.ErrConflict, holder, task.SourceSrn)
+		}
+		if err := s.valkeyRepo.ReleaseMoveTaskSource(ctx, task.SourceSrn, holder); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: another task is starting for %s", entity.ErrConflict, task.SourceSrn)
+}
+
+// ListMessageMoveTasks returns the most recent tasks of the source topic first
+func (s *messageMoveService) ListMessageMoveTasks(ctx context.Context, account string, source entity.SRN, maxResults int) ([]entity.MessageMoveTask, error) {
+	if err := source.CheckOwner(s.cfg.Region, account); err != nil {
+		return nil, err
+	}
+	if maxResults == 0 {
+		maxResults = defaultMoveTaskResults
+	}
+	if maxResults < 1 || maxResults > maxMoveTaskResults {
+		return nil, fmt.Errorf("%w: MaxResults must be between 1 and %d", entity.ErrInvalidParameter, maxMoveTaskResults)
+	}
+
+	tasks, err := s.valkeyRepo.ListMoveTasks(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	tasks = slices.DeleteFunc(tasks, func(t entity.MessageMoveTask) bool {
+		return t.SourceSrn != source.String()
+	})
+	slices.SortFunc(tasks, func(a, b entity.MessageMoveTask) int {
+		return cmp.Compare(b.StartedTimestamp, a.StartedTimestamp)
+	})
+	if len(tasks) > maxResults {
+		tasks = tasks[:maxResults]
+	}
+
+	for i, t := range tasks {
+		if t.Status != entity.MoveTaskRunning {
+			continue
+		}
+		if cancelled, err := s.valkeyRepo.MoveTaskCancelRequested(ctx, t.TaskHandle); err == nil && cancelled {
+			tasks[i].Status = entity.MoveTaskCancelling
+		}
+	}
+	return tasks, nil
+}
+
+// CancelMessageMoveTask asks the runner of the task to stop. Messages already moved stay moved.
+func (s *messageMoveService) CancelMessageMoveTask(ctx context.Context, account, handle string) (int64, error) {
+	task, ok, err := s.valkeyRepo.GetMoveTask(ctx, handle)
+	if err != nil {
+		return 0, err
+	}
+	if !ok || task.Account != account {
+		return 0, fmt.Errorf("%w: message move task %s", entity.ErrNotFound, handle)
+	}
+	if task.Status != entity.MoveTaskRunning {
+		return 0, fmt.Errorf("%w: message move task %s is %s", entity.ErrConflict, handle, task.Status)
+	}
+
+	if err := s.valkeyRepo.RequestMoveTaskCancel(ctx, handle); err != nil {
+		return 0, err
+	}
+	return task.ApproximateNumberOfMessagesMoved, nil
+}
+
+// MessageMoveDispatcher runs the message move tasks in the background. Every API instance
+// runs one; a lease in valkey makes sure a task has a single runner, and a task whose
+// runner died is resumed from its last saved sequence once the lease expires.
+type MessageMoveDispatcher interface {
+	Start()
+	Stop()
+}
+
+const (
+	moveTaskPollInterval = 5 * time.Second
+	moveTaskLeaseTTL     = 30 * time.Second
+	moveTaskSaveInterval = time.Second // progress is saved and the lease renewed at this pace
+)
+
+type messageMoveDispatcher struct {
+	ctx        context.Context // cancelled by Stop
+	cancel     context.CancelFunc
+	natsRepo   repo.NatsRepo
+	valkeyRepo repo.ValkeyRepo
+	region     string
+	owner      string // lease owner id of this instance
+
+	mu       sync.Mutex
+	running  map[string]context.CancelFunc
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewMessageMoveDispatcher creates the dispatcher. ctx carries the logger used by the tasks.
+func NewMessageMoveDispatcher(ctx context.Context, natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo, cfg *config.Config) MessageMoveDispatcher {
+	ctx, cancel := context.WithCancel(ctx)
+	return &messageMoveDispatcher{
+		ctx:        ctx,
+		cancel:     cancel,
+		natsRepo:   natsRepo,
+		valkeyRepo: valkeyRepo,
+		region:     cfg.Region,
+		owner:      uuid.NewString(),
+		running:    make(map[string]context.CancelFunc),
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start polls for tasks without a runner until Stop is called
+func (d *messageMoveDispatcher) Start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(moveTaskPollInterval)
+		defer ticker.Stop()
+
+		for {
+			d.poll()
+			select {
+			case <-ticker.C:
+			case <-d.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop interrupts the running tasks, which save their progress and are resumed on the next start
+func (d *messageMoveDispatcher) Stop() {
+	close(d.stopChan)
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *messageMoveDispatcher) poll() {
+	logger := logs.GetLogger(d.ctx)
+	tasks, err := d.valkeyRepo.ListActiveMoveTasks(d.ctx)
+	if err != nil {
+		logger.Warn("Failed to load message move tasks", zap.Error(err))
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, task := range tasks {
+		if _, ok := d.running[task.TaskHandle]; ok {
+			continue
+		}
+		acquired, err := d.valkeyRepo.AcquireMoveTaskLease(d.ctx, task.TaskHandle, d.owner, moveTaskLeaseTTL)
+		if err != nil || !acquired {
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(d.ctx)
+		d.running[task.TaskHandle] = cancel
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			defer d.release(task.TaskHandle)
+			d.run(ctx, task)
+		}()
+	}
+}
+
+func (d *messageMoveDispatcher) release(handle string) {
+	d.mu.Lock()
+	if cancel, ok := d.running[handle]; ok {
+		cancel()
+		delete(d.running, handle)
+	}
+	d.mu.Unlock()
+	_ = d.valkeyRepo.ReleaseMoveTaskLease(context.WithoutCancel(d.ctx), handle, d.owner)
+}
+
+// run moves the messages up to the task EndSequence, saving progress every moveTaskSaveInterval
+func (d *messageMoveDispatcher) run(ctx context.Context, task entity.MessageMoveTask) {
+	ctx, span := traces.StartSpan(ctx, "messageMoveTask")
+	defer span.End()
+	logger := logs.GetLogger(ctx).With(zap.String("taskHandle", task.TaskHandle))
+	logger.Info("Message move task started", zap.String("source", task.SourceSrn), zap.Uint64("fromSequence", task.LastSequence+1))
+
+	source, err := entity.ParseSRN(task.SourceSrn)
+	if err != nil {
+		d.finish(ctx, &task, entity.MoveTaskFailed, err.Error())
+		return
+	}
+	if task.LastSequence >= task.EndSequence {
+		d.finish(ctx, &task, entity.MoveTaskCompleted, "")
+		return
+	}
+
+	stream := entity.StreamName(source.Account, source.Topic)
+	consumer, err := d.natsRepo.OrderedConsumer(ctx, stream, task.LastSequence+1)
+	if err != nil {
+		d.finish(ctx, &task, entity.MoveTaskFailed, err.Error())
+		return
+	}
+
+	mover := &messageMover{natsRepo: d.natsRepo, region: d.region, task: &task, destinations: make(map[string]*jetstream.StreamInfo)}
+	limiter := rate.NewLimiter(rate.Limit(task.MaxNumberOfMessagesPerSecond), 1)
+	lastSave := time.Now()
+	for {
+		if ctx.Err() != nil {
+			d.save(context.WithoutCancel(ctx), &task) // interrupted by Stop, resumed later
+			return
+		}
+		if time.Since(lastSave) >= moveTaskSaveInterval {
+			if !d.checkpoint(ctx, &task) {
+				return
+			}
+			lastSave = time.Now()
+		}
+
+		msg, err := consumer.Next(jetstream.FetchMaxWait(time.Second))
+		if errors.Is(err, gonats.ErrTimeout) {
+			// a slow fetch or a reconnect also times out, so the stream decides whether the range is done
+			if done, err := d.rangeDone(ctx, stream, &task); err == nil && done {
+				d.finish(ctx, &task, entity.MoveTaskCompleted, "")
+				return
+			}
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			d.finish(ctx, &task, entity.MoveTaskFailed, err.Error())
+			return
+		}
+		md, err := msg.Metadata()
+		if err != nil {
+			d.finish(ctx, &task, entity.MoveTaskFailed, err.Error())
+			return
+		}
+		if md.Sequence.Stream > task.EndSequence {
+			d.finish(ctx, &task, entity.MoveTaskCompleted, "")
+			return
+		}
+
+		if err := limiter.Wait(ctx); err != nil {
+			continue
+		}
+		if err := mover.move(ctx, stream, md.Sequence.Stream, msg); err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			logger.Warn("Message move failed", zap.Uint64("seq", md.Sequence.Stream), zap.Error(err))
+			d.finish(ctx, &task, entity.MoveTaskFailed, fmt.Sprintf("message %d: %v", md.Sequence.Stream, err))
+			return
+		}
+		task.LastSequence = md.Sequence.Stream
+		task.ApproximateNumberOfMessagesMoved++
+	}
+}
+
+// rangeDone reports whether the source holds no message after LastSequence up to EndSequence
+func (d *messageMoveDispatcher) rangeDone(ctx context.Context, stream string, task *entity.MessageMoveTask) (bool, error) {
+	if task.LastSequence >= task.EndSequence {
+		return true, nil
+	}
+	info, err := d.natsRepo.GetStreamInfo(ctx, stream)
+	if err != nil {
+		return false, err
+	}
+	state := info.State
+	return state.Msgs == 0 || state.LastSeq <= task.LastSequence || state.FirstSeq > task.EndSequence, nil
+}
+
+// checkpoint renews the lease and saves the progress. It returns false when the task must stop:
+// the lease was lost to another instance, or the task was cancelled.
+func (d *messageMoveDispatcher) checkpoint(ctx context.Context, task *entity.MessageMoveTask) bool {
+	renewed, err := d.valkeyRepo.RenewMoveTaskLease(ctx, task.TaskHandle, d.owner, moveTaskLeaseTTL)
+	if err == nil && !renewed {
+		logs.GetLogger(ctx).Warn("Message move task lease lost", zap.String("taskHandle", task.TaskHandle))
+		return false
+	}
+	if cancelled, err := d.valkeyRepo.MoveTaskCancelRequested(ctx, task.TaskHandle); err == nil && cancelled {
+		d.finish(ctx, task, entity.MoveTaskCancelled, "")
+		return false
+	}
+	d.save(ctx, task)
+	return true
+}
+
+func (d *messageMoveDispatcher) save(ctx context.Context, task *entity.MessageMoveTask) {
+	if err := d.valkeyRepo.StoreMoveTask(ctx, *task); err != nil {
+		logs.GetLogger(ctx).Warn("Failed to save message move task", zap.String("taskHandle", task.TaskHandle), zap.Error(err))
+	}
+}
+
+func (d *messageMoveDispatcher) finish(ctx context.Context, task *entity.MessageMoveTask, status, reason string) {
+	task.Status, task.FailureReason = status, reason
+	d.save(context.WithoutCancel(ctx), task)
+	logs.GetLogger(ctx).Info("Message move task finished", zap.String("taskHandle", task.TaskHandle),
+		zap.String("status", status), zap.Int64("moved", task.ApproximateNumberOfMessagesMoved))
+}
+
+// messageMover republishes the messages of one task
+type messageMover struct {
+	natsRepo     repo.NatsRepo
+	region       string
+	task         *entity.MessageMoveTask
+	destinations map[string]*jetstream.StreamInfo // by topic SRN
+}
+
+// move publishes the message to its destination, then deletes it from the source stream.
+// The publish is deduplicated on the task and sequence, so a message republished just before
+// a restart is not duplicated when the task resumes.
+func (m *messageMover) move(ctx context.Context, stream string, seq uint64, msg jetstream.Msg) error {
+	destSrn := m.task.DestinationSrn
+	if destSrn == "" {
+		destSrn = msg.Headers().Get(entity.HeaderDeadLetterTopicSrn)
+		if destSrn == "" {
+			return fmt.Errorf("no %s header, a destination is required", entity.HeaderDeadLetterTopicSrn)
+		}
+	}
+	dest, err := entity.ParseSRN(destSrn)
+	if err != nil {
+		return err
+	}
+	if err := dest.CheckOwner(m.region, m.task.Account); err != nil {
+		return err
+	}
+
+	info, ok := m.destinations[destSrn]
+	if !ok {
+		info, err = findTopicStream(ctx, m.natsRepo, dest.Account, dest.Topic)
+		if err != nil {
+			return err
+		}
+		m.destinations[destSrn] = info
+	}
+
+	subject := msg.Headers().Get(entity.HeaderDeadLetterSubject)
+	if subject == "" || !ownsSubject(info.Config.Subjects, subject) {
+		if subject, err = topicDefaultSubject(info, dest); err != nil {
+			return err
+		}
+	}
+
+	out := gonats.NewMsg(subject)
+	out.Data = msg.Data()
+	for key, values := range msg.Headers() {
+		if strings.HasPrefix(key, "Nats-") || strings.HasPrefix(key, "Sns-Dlq-") {
+			continue
+		}
+		out.Header[key] = values
+	}
+	out.Header.Set(jetstream.MsgIDHeader, fmt.Sprintf("move:%s:%d", m.task.TaskHandle, seq))
+
+	future, err := m.natsRepo.PublishAsyncMessage(ctx, out)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-future.Ok():
+	case err := <-future.Err():
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return m.natsRepo.DeleteMessage(ctx, stream, seq)
+}
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	gonats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+const moveRegion = "kr-west1"
+
+// fakeMoveRepo keeps the messages of the topic streams by sequence
+type fakeMoveRepo struct {
+	repo.NatsRepo
+
+	mu        sync.Mutex
+	streams   map[string]map[uint64]*gonats.Msg // stream name -> sequence -> message
+	published []*gonats.Msg
+	startSeq  uint64
+	timeouts  int // Next times out this often before returning messages
+}
+
+func newFakeMoveRepo(topics ...string) *fakeMoveRepo {
+	r := &fakeMoveRepo{streams: make(map[string]map[uint64]*gonats.Msg)}
+	for _, topic := range topics {
+		r.streams[entity.StreamName("acct", topic)] = make(map[uint64]*gonats.Msg)
+	}
+	return r
+}
+
+// deadLetter stores a message of the origin topic in the dead-letter topic
+func (r *fakeMoveRepo) deadLetter(dlq string, seq uint64, origin string) {
+	msg := gonats.NewMsg(entity.AccountSubject("acct", dlq))
+	msg.Data = []byte(fmt.Sprintf("message %d", seq))
+	msg.Header.Set(entity.HeaderDeadLetterTopicSrn, entity.NewTopicSRN(moveRegion, "acct", origin).String())
+	r.streams[entity.StreamName("acct", dlq)][seq] = msg
+}
+
+func (r *fakeMoveRepo) sequences(topic string) []uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var seqs []uint64
+	for seq := range r.streams[entity.StreamName("acct", topic)] {
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	return seqs
+}
+
+func (r *fakeMoveRepo) GetStreamInfo(_ context.Context, name string) (*jetstream.StreamInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	msgs, ok := r.streams[name]
+	if !ok {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	topic := name[len("acct_"):]
+	info := &jetstream.StreamInfo{Config: jetstream.StreamConfig{
+		Name:     name,
+		Subjects: []string{entity.AccountSubject("acct", topic)},
+		Metadata: map[string]string{entity.MetaAccount: "acct", entity.MetaTopic: topic},
+	}}
+	for seq := range msgs {
+		info.State.Msgs++
+		if info.State.FirstSeq == 0 || seq < info.State.FirstSeq {
+			info.State.FirstSeq = seq
+		}
+		info.State.LastSeq = max(info.State.LastSeq, seq)
+	}
+	return info, nil
+}
+
+func (r *fakeMoveRepo) OrderedConsumer(_ context.Context, stream string, startSeq uint64) (jetstream.Consumer, error) {
+	r.startSeq = startSeq
+	return &fakeMoveConsumer{repo: r, stream: stream, next: startSeq}, nil
+}
+
+func (r *fakeMoveRepo) PublishAsyncMessage(_ context.Context, msg *gonats.Msg) (jetstream.PubAckFuture, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.published = append(r.published, msg)
+	ok := make(chan *jetstream.PubAck, 1)
+	ok <- &jetstream.PubAck{}
+	return fakePubAckFuture{ok: ok}, nil
+}
+
+func (r *fakeMoveRepo) DeleteMessage(_ context.Context, stream string, seq uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.streams[stream], seq)
+	return nil
+}
+
+type fakePubAckFuture struct {
+	jetstream.PubAckFuture
+	ok chan *jetstream.PubAck
+}
+
+func (f fakePubAckFuture) Ok() <-chan *jetstream.PubAck { return f.ok }
+func (f fakePubAckFuture) Err() <-chan error            { return nil }
+
+// fakeMoveConsumer returns the messages of the stream from its start sequence, like an ordered consumer
+type fakeMoveConsumer struct {
+	jetstream.Consumer
+	repo   *fakeMoveRepo
+	stream string
+	next   uint64
+}
+
+func (c *fakeMoveConsumer) Next(...jetstream.FetchOpt) (jetstream.Msg, error) {
+	c.repo.mu.Lock()
+	defer c.repo.mu.Unlock()
+	if c.repo.timeouts > 0 {
+		c.repo.timeouts--
+		return nil, gonats.ErrTimeout
+	}
+	msgs := c.repo.streams[c.stream]
+	var seqs []uint64
+	for seq := range msgs {
+		if seq >= c.next {
+			seqs = append(seqs, seq)
+		}
+	}
+	if len(seqs) == 0 {
+		return nil, gonats.ErrTimeout
+	}
+	seq := slices.Min(seqs)
+	c.next = seq + 1
+	return &fakeMoveMsg{msg: msgs[seq], seq: seq}, nil
+}
+
+type fakeMoveMsg struct {
+	jetstream.Msg
+	msg *gonats.Msg
+	seq uint64
+}
+
+func (m *fakeMoveMsg) Data() []byte           { return m.msg.Data }
+func (m *fakeMoveMsg) Headers() gonats.Header { return m.msg.Header }
+
+func (m *fakeMoveMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{Sequence: jetstream.SequencePair{Stream: m.seq}}, nil
+}
+
+// fakeMoveValkeyRepo keeps the tasks, claims, leases and cancel requests in memory
+type fakeMoveValkeyRepo struct {
+	repo.ValkeyRepo
+
+	mu        sync.Mutex
+	tasks     map[string]entity.MessageMoveTask
+	claims    map[string]string // source -> task handle
+	leases    map[string]string // task handle -> owner
+	cancelled map[string]bool
+}
+
+func newFakeMoveValkeyRepo() *fakeMoveValkeyRepo {
+	return &fakeMoveValkeyRepo{
+		tasks:     make(map[string]entity.MessageMoveTask),
+		claims:    make(map[string]string),
+		leases:    make(map[string]string),
+		cancelled: make(map[string]bool),
+	}
+}
+
+func (r *fakeMoveValkeyRepo) StoreMoveTask(_ context.Context, task entity.MessageMoveTask) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks[task.TaskHandle] = task
+	if task.Finished() && r.claims[task.SourceSrn] == task.TaskHandle {
+		delete(r.claims, task.SourceSrn)
+	}
+	return nil
+}
+
+func (r *fakeMoveValkeyRepo) GetMoveTask(_ context.Context, handle string) (entity.MessageMoveTask, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	task, ok := r.tasks[handle]
+	return task, ok, nil
+}
+
+func (r *fakeMoveValkeyRepo) MoveTaskCancelRequested(_ context.Context, handle string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cancelled[handle], nil
+}
+
+func (r *fakeMoveValkeyRepo) RenewMoveTaskLease(_ context.Context, handle, owner string, _ time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leases[handle] == owner, nil
+}
+
+func (r *fakeMoveValkeyRepo) ClaimMoveTaskSource(_ context.Context, source, handle string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.claims[source]; ok {
+		return false, nil
+	}
+	r.claims[source] = handle
+	return true, nil
+}
+
+func (r *fakeMoveValkeyRepo) GetMoveTaskSourceClaim(_ context.Context, source string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.claims[source], nil
+}
+
+func (r *fakeMoveValkeyRepo) ReleaseMoveTaskSource(_ context.Context, source, handle string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.claims[source] == handle {
+		delete(r.claims, source)
+	}
+	return nil
+}
+
+func newTestMoveDispatcher(natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo) *messageMoveDispatcher {
+	return NewMessageMoveDispatcher(context.Background(), natsRepo, valkeyRepo, &config.Config{Region: moveRegion}).(*messageMoveDispatcher)
+}
+
+func testMoveTask(lastSequence, endSequence uint64) entity.MessageMoveTask {
+	return entity.MessageMoveTask{
+		TaskHandle:                   "task-1",
+		Status:                       entity.MoveTaskRunning,
+		SourceSrn:                    entity.NewTopicSRN(moveRegion, "acct", "orders-dlq").String(),
+		MaxNumberOfMessagesPerSecond: entity.MaxMoveTaskRate,
+		Account:                      "acct",
+		LastSequence:                 lastSequence,
+		EndSequence:                  endSequence,
+	}
+}
+
+func TestMessageMoveTask_MovesToOriginalTopic(t *testing.T) {
+	natsRepo := newFakeMoveRepo("orders", "orders-dlq")
+	for seq := uint64(1); seq <= 3; seq++ {
+		natsRepo.deadLetter("orders-dlq", seq, "orders")
+	}
+	valkeyRepo := newFakeMoveValkeyRepo()
+
+	newTestMoveDispatcher(natsRepo, valkeyRepo).run(context.Background(), testMoveTask(0, 3))
+
+	task := valkeyRepo.tasks["task-1"]
+	assert.Equal(t, entity.MoveTaskCompleted, task.Status)
+	assert.EqualValues(t, 3, task.ApproximateNumberOfMessagesMoved)
+	assert.Empty(t, natsRepo.sequences("orders-dlq"))
+	if assert.Len(t, natsRepo.published, 3) {
+		for i, msg := range natsRepo.published {
+			assert.Equal(t, "sns.data.acct.orders", msg.Subject)
+			assert.Equal(t, fmt.Sprintf("move:task-1:%d", i+1), msg.Header.Get(jetstream.MsgIDHeader))
+			assert.Empty(t, msg.Header.Get(entity.HeaderDeadLetterTopicSrn))
+		}
+	}
+}
+
+func TestMessageMoveTask_ResumesAfterLastSequence(t *testing.T) {
+	natsRepo := newFakeMoveRepo("orders", "orders-dlq")
+	for seq := uint64(1); seq <= 3; seq++ {
+		natsRepo.deadLetter("orders-dlq", seq, "orders")
+	}
+	valkeyRepo := newFakeMoveValkeyRepo()
+	task := testMoveTask(1, 3)
+	task.ApproximateNumberOfMessagesMoved = 1
+
+	newTestMoveDispatcher(natsRepo, valkeyRepo).run(context.Background(), task)
+
+	assert.EqualValues(t, 2, natsRepo.startSeq)
+	assert.Equal(t, []uint64{1}, natsRepo.sequences("orders-dlq"))
+	assert.Len(t, natsRepo.published, 2)
+	assert.EqualValues(t, 3, valkeyRepo.tasks["task-1"].ApproximateNumberOfMessagesMoved)
+}
+
+func TestMessageMoveTask_StopsAtEndSequence(t *testing.T) {
+	natsRepo := newFakeMoveRepo("orders", "orders-dlq")
+	for seq := uint64(1); seq <= 3; seq++ {
+		natsRepo.deadLetter("orders-dlq", seq, "orders")
+	}
+	valkeyRepo := newFakeMoveValkeyRepo()
+
+	newTestMoveDispatcher(natsRepo, valkeyRepo).run(context.Background(), testMoveTask(0, 2))
+
+	assert.Equal(t, entity.MoveTaskCompleted, valkeyRepo.tasks["task-1"].Status)
+	assert.Equal(t, []uint64{3}, natsRepo.sequences("orders-dlq"))
+}
+
+func TestMessageMoveTask_FetchTimeoutBeforeEndSequenceRetries(t *testing.T) {
+	natsRepo := newFakeMoveRepo("orders", "orders-dlq")
+	for seq := uint64(1); seq <= 3; seq++ {
+		natsRepo.deadLetter("orders-dlq", seq, "orders")
+	}
+	natsRepo.timeouts = 2
+	valkeyRepo := newFakeMoveValkeyRepo()
+
+	newTestMoveDispatcher(natsRepo, valkeyRepo).run(context.Background(), testMoveTask(0, 3))
+
+	task := valkeyRepo.tasks["task-1"]
+	assert.Equal(t, entity.MoveTaskCompleted, task.Status)
+	assert.EqualValues(t, 3, task.ApproximateNumberOfMessagesMoved)
+	assert.Empty(t, natsRepo.sequences("orders-dlq"))
+}
+
+func TestMessageMoveTask_CompletesWhenRangeWasRemoved(t *testing.T) {
+	// the messages up to EndSequence expired before they were moved, only a newer one is left
+	natsRepo := newFakeMoveRepo("orders", "orders-dlq")
+	natsRepo.deadLetter("orders-dlq", 5, "orders")
+	valkeyRepo := newFakeMoveValkeyRepo()
+	d := newTestMoveDispatcher(natsRepo, valkeyRepo)
+
+	done, err := d.rangeDone(context.Background(), "acct_orders-dlq", &entity.MessageMoveTask{LastSequence: 1, EndSequence: 3})
+	assert.NoError(t, err)
+	assert.True(t, done)
+
+	done, err = d.rangeDone(context.Background(), "acct_orders-dlq", &entity.MessageMoveTask{LastSequence: 1, EndSequence: 5})
+	assert.NoError(t, err)
+	assert.False(t, done)
+}
+
+func TestMessageMoveTask_Cancel(t *testing.T) {
+	valkeyRepo := newFakeMoveValkeyRepo()
+	d := newTestMoveDispatcher(newFakeMoveRepo(), valkeyRepo)
+	task := testMoveTask(1, 3)
+	valkeyRepo.leases[task.TaskHandle] = d.owner
+	valkeyRepo.cancelled[task.TaskHandle] = true
+
+	assert.False(t, d.checkpoint(context.Background(), &task))
+	assert.Equal(t, entity.MoveTaskCancelled, valkeyRepo.tasks["task-1"].Status)
+}
+
+func TestMessageMoveTask_LeaseLost(t *testing.T) {
+	valkeyRepo := newFakeMoveValkeyRepo()
+	d := newTestMoveDispatcher(newFakeMoveRepo(), valkeyRepo)
+	task := testMoveTask(1, 3)
+	valkeyRepo.leases[task.TaskHandle] = "another-instance"
+
+	assert.False(t, d.checkpoint(context.Background(), &task))
+	_, saved := valkeyRepo.tasks["task-1"]
+	assert.False(t, saved, "the new runner owns the progress")
+
+	valkeyRepo.leases[task.TaskHandle] = d.owner
+	assert.True(t, d.checkpoint(context.Background(), &task))
+	assert.Equal(t, entity.MoveTaskRunning, valkeyRepo.tasks["task-1"].Status)
+}
+
+func TestStartMessageMoveTask_OneTaskPerSource(t *testing.T) {
+	natsRepo := newFakeMoveRepo("orders", "orders-dlq")
+	natsRepo.deadLetter("orders-dlq", 1, "orders")
+	valkeyRepo := newFakeMoveValkeyRepo()
+	svc := NewMessageMoveService(natsRepo, valkeyRepo, &config.Config{Region: moveRegion})
+	input := entity.StartMessageMoveTaskInput{SourceSrn: entity.NewTopicSRN(moveRegion, "acct", "orders-dlq")}
+
+	const starts = 8
+	var wg sync.WaitGroup
+	errs := make([]error, starts)
+	for i := range starts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = svc.StartMessageMoveTask(context.Background(), "acct", input)
+		}()
+	}
+	wg.Wait()
+
+	var started int
+	for _, err := range errs {
+		if err == nil {
+			started++
+			continue
+		}
+		assert.ErrorIs(t, err, entity.ErrConflict)
+	}
+	assert.Equal(t, 1, started)
+	assert.Len(t, valkeyRepo.tasks, 1)
+}
+
+func TestStartMessageMoveTask_ReleasesStaleClaim(t *testing.T) {
+	natsRepo := newFakeMoveRepo("orders", "orders-dlq")
+	natsRepo.deadLetter("orders-dlq", 1, "orders")
+	valkeyRepo := newFakeMoveValkeyRepo()
+	svc := NewMessageMoveService(natsRepo, valkeyRepo, &config.Config{Region: moveRegion})
+	input := entity.StartMessageMoveTaskInput{SourceSrn: entity.NewTopicSRN(moveRegion, "acct", "orders-dlq")}
+
+	// the instance that claimed the source died before storing its task
+	valkeyRepo.claims[input.SourceSrn.String()] = "lost-task"
+	handle, err := svc.StartMessageMoveTask(context.Background(), "acct", input)
+	assert.NoError(t, err)
+	assert.Equal(t, handle, valkeyRepo.claims[input.SourceSrn.String()])
+
+	_, err = svc.StartMessageMoveTask(context.Background(), "acct", input)
+	assert.ErrorIs(t, err, entity.ErrConflict)
+
+	task := valkeyRepo.tasks[handle]
+	task.Status = entity.MoveTaskCompleted
+	assert.NoError(t, valkeyRepo.StoreMoveTask(context.Background(), task))
+	_, err = svc.StartMessageMoveTask(context.Background(), "acct", input)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"nats/internal/entity"
+	"strings"
+)
+
+const (
+	defaultMaxResults = 100
+	maxMaxResults     = 100
+)
+
+// pageToken is the decoded form of the opaque NextToken.
+// JetStream lists streams ordered by name, so After anchors the position and
+// Offset is only a hint of where After was found. When streams before the
+// hint are deleted the listing resumes from an earlier offset instead of
+// skipping entries. Scope binds the token to the listing it was issued for.
+type pageToken struct {
+	Offset int    `json:"o"`
+	After  string `json:"a"`
+	Scope  string `json:"s"`
+}
+
+// pageScope identifies a listing by its parts, e.g. the operation, account and filter,
+// so that a token is only accepted by the same listing
+func pageScope(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+func encodePageToken(t pageToken) string {
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageToken decodes the token of the listing identified by scope, an empty token starts at the beginning
+func decodePageToken(token, scope string) (pageToken, error) {
+	if token == "" {
+		return pageToken{Scope: scope}, nil
+	}
+
+	var t pageToken
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(data, &t)
+	}
+	if err != nil || t.Offset < 0 || t.After == "" {
+		return pageToken{}, fmt.Errorf("%w: invalid NextToken", entity.ErrInvalidParameter)
+	}
+	if t.Scope != scope {
+		return pageToken{}, fmt.Errorf("%w: NextToken was issued for another account or filter", entity.ErrInvalidParameter)
+	}
+	return t, nil
+}
+
+// normalizeMaxResults applies the default page size and rejects out of range values
+func normalizeMaxResults(maxResults int) (int, error) {
+	if maxResults == 0 {
+		return defaultMaxResults, nil
+	}
+	if maxResults < 0 || maxResults > maxMaxResults {
+		return 0, fmt.Errorf("%w: MaxResults must be between 1 and %d", entity.ErrInvalidParameter, maxMaxResults)
+	}
+	return maxResults, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"nats/internal/entity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodePageToken(t *testing.T) {
+	scope := pageScope("listTopics", "acct", "", "")
+	token := encodePageToken(pageToken{Offset: 3, After: "acct_orders", Scope: scope})
+
+	got, err := decodePageToken(token, scope)
+	assert.NoError(t, err)
+	assert.Equal(t, pageToken{Offset: 3, After: "acct_orders", Scope: scope}, got)
+
+	got, err = decodePageToken("", scope)
+	assert.NoError(t, err)
+	assert.Equal(t, pageToken{Scope: scope}, got)
+
+	invalid := []string{
+		"not base64!",
+		"bm90IGpzb24",
+		encodePageToken(pageToken{Offset: -1, After: "acct_orders", Scope: scope}),
+		encodePageToken(pageToken{Offset: 3, Scope: scope}),
+		encodePageToken(pageToken{Offset: 3, After: "acct_orders"}),
+	}
+	for _, token := range invalid {
+		_, err := decodePageToken(token, scope)
+		assert.ErrorIs(t, err, entity.ErrInvalidParameter, token)
+	}
+
+	for _, other := range []string{
+		pageScope("listTopics", "other", "", ""),
+		pageScope("listTopics", "acct", "team", ""),
+		pageScope("listTopics", "acct", "", "team"),
+	} {
+		_, err := decodePageToken(token, other)
+		assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+	}
+}
+
+func TestNormalizeMaxResults(t *testing.T) {
+	n, err := normalizeMaxResults(0)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultMaxResults, n)
+
+	n, err = normalizeMaxResults(maxMaxResults)
+	assert.NoError(t, err)
+	assert.Equal(t, maxMaxResults, n)
+
+	for _, n := range []int{-1, maxMaxResults + 1} {
+		_, err := normalizeMaxResults(n)
+		assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+	}
+}
@@ -2,37 +2,54 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"nats/internal/context/logs"
 	"nats/internal/entity"
 	"nats/internal/repo"
+	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	gonats "github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type PublishService interface {
-	PublishAsyncMessage(ctx context.Context, topicName, message, subject string) (string, error)
+	PublishAsyncMessage(ctx context.Context, account string, input entity.PublishInput) (string, error)
+	PublishBatch(ctx context.Context, account string, input entity.PublishBatchInput) ([]entity.PublishBatchEntryResult, error)
 	CheckAckStatus(ctx context.Context, id string) (string, error)
 }
 
+// publishBatchConcurrency bounds the entries of a batch being published at once
+const publishBatchConcurrency = 32
+
 type publishService struct {
-	dispatcher AckDispatcher
-	timeout    time.Duration
-	natsRepo   repo.NatsRepo
-	valkeyRepo repo.ValkeyRepo
+	dispatcher      AckDispatcher
+	timeout         time.Duration
+	natsRepo        repo.NatsRepo
+	valkeyRepo      repo.ValkeyRepo
+	topics          *TopicCache
+	maxBatchEntries int
 }
 
-func NewPublishService(dispatcher AckDispatcher, timeout time.Duration, natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo) PublishService {
+func NewPublishService(dispatcher AckDispatcher, timeout time.Duration, natsRepo repo.NatsRepo, valkeyRepo repo.ValkeyRepo, topics *TopicCache, maxBatchEntries int) PublishService {
+	if maxBatchEntries <= 0 {
+		maxBatchEntries = entity.DefaultMaxBatchEntries
+	}
 	return &publishService{
-		dispatcher: dispatcher,
-		timeout:    timeout,
-		natsRepo:   natsRepo,
-		valkeyRepo: valkeyRepo,
+		dispatcher:      dispatcher,
+		timeout:         timeout,
+		natsRepo:        natsRepo,
+		valkeyRepo:      valkeyRepo,
+		topics:          topics,
+		maxBatchEntries: maxBatchEntries,
 	}
 }
 
@@ -46,21 +63,130 @@ func newAckTask(parentCtx context.Context, id string, future jetstream.PubAckFut
 	}
 }
 
-func (s *publishService) PublishAsyncMessage(ctx context.Context, topicName, message, subject string) (string, error) {
+func (s *publishService) PublishAsyncMessage(ctx context.Context, account string, input entity.PublishInput) (string, error) {
 	logger := logs.GetLogger(ctx)
 	logger.Debug("PublishAsyncMessage", logs.WithTraceFields(ctx)...)
 
-	if topicName == "" || message == "" {
-		return "", errors.New("missing required fields")
+	if input.TopicName == "" || input.Message == "" {
+		return "", fmt.Errorf("%w: missing required fields", entity.ErrInvalidParameter)
 	}
-	if subject == "" {
-		subject = topicName
+	subject, err := publishSubject(input)
+	if err != nil {
+		return "", err
+	}
+	info, err := s.topics.get(ctx, account, input.TopicName)
+	if err != nil {
+		return "", err
 	}
 
-	ackFuture, err := s.natsRepo.PublishAsyncMessage(ctx, message, subject)
+	msg, id, err := newPublishMsg(account, info, subject, input)
 	if err != nil {
 		return "", err
 	}
+	ackFuture, err := s.natsRepo.PublishAsyncMessage(ctx, msg)
+	if err != nil {
+		s.topics.evictIfStreamGone(account, input.TopicName, err)
+		return "", err
+	}
+	s.trackAck(ctx, id, ackFuture, s.evictOnError(account, input.TopicName))
+	return id, nil
+}
+
+// PublishBatch publishes the entries concurrently. A failed entry does not fail the others;
+// only an invalid batch or an unknown topic fails the whole request. On FIFO topics the
+// entries of a message group are published in batch order on one connection.
+func (s *publishService) PublishBatch(ctx context.Context, account string, input entity.PublishBatchInput) ([]entity.PublishBatchEntryResult, error) {
+	logs.GetLogger(ctx).Debug("PublishBatch", logs.WithTraceFields(ctx)...)
+
+	if err := entity.ValidatePublishBatch(input, s.maxBatchEntries); err != nil {
+		return nil, err
+	}
+	info, err := s.topics.get(ctx, account, input.TopicName)
+	if err != nil {
+		return nil, err
+	}
+	fifo := info.Config.Metadata[entity.MetaFifo] == "true"
+
+	// lanes are the entry indexes published in order, one lane per message group on FIFO topics
+	results := make([]entity.PublishBatchEntryResult, len(input.Entries))
+	msgs := make([]*gonats.Msg, len(input.Entries))
+	var lanes [][]int
+	groups := map[string]int{}
+	for i, e := range input.Entries {
+		results[i].Id = e.Id
+		msg, id, err := s.newBatchMsg(account, info, input.TopicName, e)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		msgs[i], results[i].MessageId = msg, id
+
+		lane, ok := groups[e.MessageGroupId]
+		if !fifo || !ok {
+			lane = len(lanes)
+			lanes = append(lanes, nil)
+			if fifo {
+				groups[e.MessageGroupId] = lane
+			}
+		}
+		lanes[lane] = append(lanes[lane], i)
+	}
+
+	onError := s.evictOnError(account, input.TopicName)
+	sem := make(chan struct{}, publishBatchConcurrency)
+	var wg sync.WaitGroup
+	for _, lane := range lanes {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			batch := make([]*gonats.Msg, len(lane))
+			for j, i := range lane {
+				batch[j] = msgs[i]
+			}
+			futures, err := s.natsRepo.PublishAsyncMessages(ctx, batch)
+			if err != nil {
+				onError(err)
+			}
+			for j, i := range lane {
+				if j < len(futures) {
+					s.trackAck(ctx, results[i].MessageId, futures[j], onError)
+					continue
+				}
+				results[i].MessageId, results[i].Err = "", err
+			}
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// newBatchMsg validates a batch entry like a single publish
+func (s *publishService) newBatchMsg(account string, info *jetstream.StreamInfo, topicName string, e entity.PublishBatchEntry) (*gonats.Msg, string, error) {
+	input := e.PublishInput(topicName)
+	if input.Message == "" {
+		return nil, "", fmt.Errorf("%w: missing required fields", entity.ErrInvalidParameter)
+	}
+	subject, err := publishSubject(input)
+	if err != nil {
+		return nil, "", err
+	}
+	return newPublishMsg(account, info, subject, input)
+}
+
+// evictOnError returns the callback dropping the cached topic when a publish finds its stream gone
+func (s *publishService) evictOnError(account, topicName string) func(error) {
+	return func(err error) {
+		s.topics.evictIfStreamGone(account, topicName, err)
+	}
+}
+
+// trackAck records the publish as pending and hands the ack future to the dispatcher
+func (s *publishService) trackAck(ctx context.Context, id string, ackFuture jetstream.PubAckFuture, onError func(error)) {
+	logger := logs.GetLogger(ctx)
 
 	// taskCtx is for goroutine context. So, make new context (without cancel, include span and logger)
 	taskCtx := context.WithoutCancel(ctx)
@@ -69,13 +195,114 @@ func (s *publishService) PublishAsyncMessage(ctx context.Context, topicName, mes
 		taskCtx = trace.ContextWithSpanContext(taskCtx, spanCtx)
 	}
 	taskCtx = logs.WithLogger(taskCtx, logger)
-	id := uuid.NewString()
 	_ = s.valkeyRepo.StoreAckResult(taskCtx, id, entity.AckResult{Status: "PENDING"})
 
 	task := newAckTask(taskCtx, id, ackFuture, s.timeout)
+	task.OnError = onError
 	s.dispatcher.Enqueue(task)
+}
 
-	return id, nil
+// publishSubject returns the validated relative subject of the publish, the topic name by default
+func publishSubject(input entity.PublishInput) (string, error) {
+	subject := input.Subject
+	if subject == "" {
+		subject = input.TopicName
+	}
+	if err := entity.ValidatePublishSubject(subject); err != nil {
+		return "", err
+	}
+	return subject, nil
+}
+
+// newPublishMsg checks the publish against the topic and builds the NATS message with a new MessageId
+func newPublishMsg(account string, info *jetstream.StreamInfo, subject string, input entity.PublishInput) (*gonats.Msg, string, error) {
+	if !ownsSubject(info.Config.Subjects, entity.AccountSubject(account, subject)) {
+		return nil, "", fmt.Errorf("%w: subject %s does not belong to topic %s", entity.ErrInvalidSubject, subject, input.TopicName)
+	}
+
+	if err := entity.ValidateMessageAttributes(input.MessageAttributes); err != nil {
+		return nil, "", err
+	}
+
+	id := uuid.NewString()
+	msg := gonats.NewMsg(entity.AccountSubject(account, subject))
+	msg.Data = []byte(input.Message)
+	msg.Header.Set(entity.HeaderMessageId, id)
+	entity.SetMessageAttributeHeaders(msg.Header, input.MessageAttributes)
+	if err := setFifoHeaders(msg, info.Config.Metadata, input); err != nil {
+		return nil, "", err
+	}
+
+	// JetStream only rejects oversized messages on the ack, check the limit up front
+	if limit := info.Config.MaxMsgSize; limit > 0 {
+		if size := storedSize(msg); size > int(limit) {
+			return nil, "", fmt.Errorf("%w: message with attributes and headers is %d bytes, topic %s accepts %d", entity.ErrInvalidParameter, size, input.TopicName, limit)
+		}
+	}
+	return msg, id, nil
+}
+
+// storedSize is the size JetStream checks against MaxMsgSize: the headers as encoded on the
+// wire, with binary attributes in base64, and the data
+func storedSize(msg *gonats.Msg) int {
+	if len(msg.Header) == 0 {
+		return len(msg.Data)
+	}
+	var n byteCounter
+	_ = http.Header(msg.Header).Write(&n)
+	return len(natsHeaderLine) + int(n) + len("\r\n") + len(msg.Data)
+}
+
+// natsHeaderLine starts the header block of a NATS message
+const natsHeaderLine = "NATS/1.0\r\n"
+
+// byteCounter counts the bytes written to it
+type byteCounter int
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// ownsSubject reports whether one of the topic stream subjects captures the subject
+func ownsSubject(streamSubjects []string, subject string) bool {
+	for _, pattern := range streamSubjects {
+		if entity.SubjectMatches(pattern, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// setFifoHeaders validates the FIFO parameters against the topic type and sets the
+// group and deduplication headers. With content-based deduplication the
+// deduplication id defaults to the SHA-256 of the message body.
+func setFifoHeaders(msg *gonats.Msg, metadata map[string]string, input entity.PublishInput) error {
+	if metadata[entity.MetaFifo] != "true" {
+		if input.MessageGroupId != "" || input.MessageDeduplicationId != "" {
+			return fmt.Errorf("%w: MessageGroupId and MessageDeduplicationId are only supported on FIFO topics", entity.ErrInvalidParameter)
+		}
+		return nil
+	}
+
+	if err := entity.ValidateFifoId("MessageGroupId", input.MessageGroupId); err != nil {
+		return err
+	}
+	dedupId := input.MessageDeduplicationId
+	if dedupId == "" {
+		if metadata[entity.MetaContentBasedDeduplication] != "true" {
+			return fmt.Errorf("%w: MessageDeduplicationId is required unless ContentBasedDeduplication is enabled", entity.ErrInvalidParameter)
+		}
+		sum := sha256.Sum256(msg.Data)
+		dedupId = hex.EncodeToString(sum[:])
+	} else if err := entity.ValidateFifoId("MessageDeduplicationId", dedupId); err != nil {
+		return err
+	}
+
+	msg.Header.Set(entity.HeaderMessageGroupId, input.MessageGroupId)
+	msg.Header.Set(entity.HeaderMessageDeduplicationId, dedupId)
+	msg.Header.Set(jetstream.MsgIDHeader, dedupId)
+	return nil
 }
 
 func (s *publishService) CheckAckStatus(ctx context.Context, id string) (string, error) {
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	gonats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePublishRepo records the messages published by each PublishAsyncMessages call
+type fakePublishRepo struct {
+	repo.NatsRepo
+	info *jetstream.StreamInfo
+
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (r *fakePublishRepo) GetStreamInfo(context.Context, string) (*jetstream.StreamInfo, error) {
+	return r.info, nil
+}
+
+func (r *fakePublishRepo) PublishAsyncMessages(_ context.Context, msgs []*gonats.Msg) ([]jetstream.PubAckFuture, error) {
+	var bodies []string
+	futures := make([]jetstream.PubAckFuture, 0, len(msgs))
+	for _, msg := range msgs {
+		if string(msg.Data) == "fail" {
+			r.record(bodies)
+			return futures, errors.New("nats: connection closed")
+		}
+		bodies = append(bodies, string(msg.Data))
+		futures = append(futures, nil)
+	}
+	r.record(bodies)
+	return futures, nil
+}
+
+func (r *fakePublishRepo) record(bodies []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, bodies)
+}
+
+type fakeAckValkeyRepo struct {
+	repo.ValkeyRepo
+}
+
+func (fakeAckValkeyRepo) StoreAckResult(context.Context, string, entity.AckResult) error {
+	return nil
+}
+
+type fakeAckDispatcher struct {
+	AckDispatcher
+
+	mu  sync.Mutex
+	ids []string
+}
+
+func (d *fakeAckDispatcher) Enqueue(task *entity.AckTask) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ids = append(d.ids, task.ID)
+}
+
+func newTestPublishService(metadata map[string]string) (*publishService, *fakePublishRepo, *fakeAckDispatcher) {
+	metadata[entity.MetaAccount], metadata[entity.MetaTopic] = "acct", "orders"
+	natsRepo := &fakePublishRepo{info: &jetstream.StreamInfo{Config: jetstream.StreamConfig{
+		Name:     "acct_orders",
+		Subjects: []string{"sns.data.acct.orders.>", "sns.data.acct.orders"},
+		Metadata: metadata,
+	}}}
+	dispatcher := &fakeAckDispatcher{}
+	svc := NewPublishService(dispatcher, time.Second, natsRepo, fakeAckValkeyRepo{}, NewTopicCache(natsRepo, time.Minute), 3).(*publishService)
+	return svc, natsRepo, dispatcher
+}
+
+func TestPublishBatch_PerEntryResults(t *testing.T) {
+	svc, _, dispatcher := newTestPublishService(map[string]string{})
+
+	results, err := svc.PublishBatch(context.Background(), "acct", entity.PublishBatchInput{
+		TopicName: "orders",
+		Entries: []entity.PublishBatchEntry{
+			{Id: "ok", Message: "hello", Subject: "orders.created"},
+			{Id: "subject", Message: "hello", Subject: "payments"},
+			{Id: "publish", Message: "fail"},
+		},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, "ok", results[0].Id)
+		assert.NoError(t, results[0].Err)
+		assert.NotEmpty(t, results[0].MessageId)
+
+		assert.Equal(t, "subject", results[1].Id)
+		assert.ErrorIs(t, results[1].Err, entity.ErrInvalidSubject)
+
+		assert.Equal(t, "publish", results[2].Id)
+		assert.Error(t, results[2].Err)
+		assert.Empty(t, results[2].MessageId)
+	}
+	assert.Equal(t, []string{results[0].MessageId}, dispatcher.ids)
+}
+
+func TestPublishBatch_InvalidBatch(t *testing.T) {
+	svc, _, _ := newTestPublishService(map[string]string{})
+
+	entries := []entity.PublishBatchEntry{{Id: "a", Message: "1"}, {Id: "b", Message: "2"}, {Id: "c", Message: "3"}, {Id: "d", Message: "4"}}
+	_, err := svc.PublishBatch(context.Background(), "acct", entity.PublishBatchInput{TopicName: "orders", Entries: entries})
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+}
+
+func TestPublishBatch_FifoGroupsKeepOrder(t *testing.T) {
+	svc, natsRepo, _ := newTestPublishService(map[string]string{entity.MetaFifo: "true", entity.MetaContentBasedDeduplication: "true"})
+
+	results, err := svc.PublishBatch(context.Background(), "acct", entity.PublishBatchInput{
+		TopicName: "orders",
+		Entries: []entity.PublishBatchEntry{
+			{Id: "a", Message: "a", MessageGroupId: "g1"},
+			{Id: "b", Message: "b", MessageGroupId: "g2"},
+			{Id: "c", Message: "c", MessageGroupId: "g1"},
+		},
+	})
+	assert.NoError(t, err)
+	for _, r := range results {
+		assert.NoError(t, r.Err, r.Id)
+	}
+
+	calls := natsRepo.calls
+	sort.Slice(calls, func(i, j int) bool { return calls[i][0] < calls[j][0] })
+	assert.Equal(t, [][]string{{"a", "c"}, {"b"}}, calls)
+}
+
+func TestNewPublishMsg_MaxMsgSize(t *testing.T) {
+	input := entity.PublishInput{
+		TopicName: "orders",
+		Message:   "order created",
+		MessageAttributes: map[string]entity.MessageAttributeValue{
+			"store":  {DataType: "String", StringValue: "example_corp"},
+			"digest": {DataType: "Binary", BinaryValue: make([]byte, 300)},
+		},
+	}
+	info := &jetstream.StreamInfo{Config: jetstream.StreamConfig{Subjects: []string{"sns.data.acct.orders"}}}
+	msg, _, err := newPublishMsg("acct", info, "orders", input)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// the server counts the encoded headers and the data, not the subject
+	size := msg.Size() - len(msg.Subject)
+	assert.Greater(t, size, len(input.Message)+400, "binary attributes are counted base64 encoded")
+
+	info.Config.MaxMsgSize = int32(size)
+	_, _, err = newPublishMsg("acct", info, "orders", input)
+	assert.NoError(t, err)
+
+	info.Config.MaxMsgSize = int32(size - 1)
+	_, _, err = newPublishMsg("acct", info, "orders", input)
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+}
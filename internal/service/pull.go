@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// PullService lets workers without a public endpoint read a topic through a named durable
+// pull consumer. Received messages are settled with their ack tokens.
+type PullService interface {
+	Receive(ctx context.Context, account string, topic entity.SRN, input entity.PullInput) ([]entity.PullMessage, error)
+	Ack(ctx context.Context, account string, topic entity.SRN, tokens []string) error
+	Nak(ctx context.Context, account string, topic entity.SRN, tokens []string) error
+}
+
+// pullInactiveThreshold removes pull consumers nobody has received from for this long
+const pullInactiveThreshold = 7 * 24 * time.Hour
+
+type pullService struct {
+	natsRepo repo.NatsRepo
+	cfg      *config.Config
+}
+
+func NewPullService(natsRepo repo.NatsRepo, cfg *config.Config) PullService {
+	return &pullService{natsRepo: natsRepo, cfg: cfg}
+}
+
+// Receive creates the pull consumer on first use, then fetches up to MaxNumberOfMessages,
+// waiting up to WaitTimeSeconds for the first one. A new consumer starts with new messages.
+func (s *pullService) Receive(ctx context.Context, account string, topic entity.SRN, input entity.PullInput) ([]entity.PullMessage, error) {
+	maxMessages, err := receiveLimits(input.MaxNumberOfMessages, input.WaitTimeSeconds)
+	if err != nil {
+		return nil, err
+	}
+	name, err := entity.PullConsumerName(input.ConsumerName)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := findOwnedTopicStream(ctx, s.natsRepo, s.cfg.Region, account, topic)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := s.pullConsumer(ctx, info, name)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := fetchMessages(ctx, consumer, maxMessages, input.WaitTimeSeconds)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]entity.PullMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		messages = append(messages, newPullMessage(account, msg))
+	}
+	return messages, nil
+}
+
+// Ack settles the messages so that they are never received again
+func (s *pullService) Ack(ctx context.Context, account string, topic entity.SRN, tokens []string) error {
+	return s.settle(ctx, account, topic, tokens, entity.AckAck)
+}
+
+// Nak makes the messages available to the next receive at once
+func (s *pullService) Nak(ctx context.Context, account string, topic entity.SRN, tokens []string) error {
+	return s.settle(ctx, account, topic, tokens, entity.AckNak)
+}
+
+// settle checks every token before settling any, so that a request is applied completely or not at all
+func (s *pullService) settle(ctx context.Context, account string, topic entity.SRN, tokens []string, ack string) error {
+	if len(tokens) == 0 || len(tokens) > entity.MaxAckTokens {
+		return fmt.Errorf("%w: AckTokens must have 1 to %d tokens", entity.ErrInvalidParameter, entity.MaxAckTokens)
+	}
+	info, err := findOwnedTopicStream(ctx, s.natsRepo, s.cfg.Region, account, topic)
+	if err != nil {
+		return err
+	}
+
+	handles := make([]entity.ReceiptHandle, 0, len(tokens))
+	for _, token := range tokens {
+		handle, err := entity.ParseReceiptHandle(token)
+		if err != nil {
+			return fmt.Errorf("%w: invalid AckToken", entity.ErrInvalidParameter)
+		}
+		if handle.Stream != info.Config.Name || !strings.HasPrefix(handle.Consumer, entity.PullConsumerPrefix) {
+			return fmt.Errorf("%w: AckToken does not belong to topic %s", entity.ErrInvalidParameter, topic)
+		}
+		handles = append(handles, handle)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, settleTimeout)
+	defer cancel()
+	for _, handle := range handles {
+		if err := s.natsRepo.SettleMessage(ctx, handle.Subject, ack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pullConsumer returns the named pull consumer of the topic, creating it when it does not exist
+func (s *pullService) pullConsumer(ctx context.Context, info *jetstream.StreamInfo, name string) (jetstream.Consumer, error) {
+	consumer, err := s.natsRepo.GetConsumer(ctx, info.Config.Name, name)
+	if err == nil {
+		return consumer, nil
+	}
+	if !errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return nil, err
+	}
+
+	cfg := jetstream.ConsumerConfig{
+		Durable:           name,
+		DeliverPolicy:     jetstream.DeliverNewPolicy,
+		AckPolicy:         jetstream.AckExplicitPolicy,
+		AckWait:           subscriptionAckWait,
+		MaxDeliver:        -1,
+		MaxAckPending:     1000,
+		InactiveThreshold: pullInactiveThreshold,
+		Metadata: map[string]string{
+			entity.MetaAccount: info.Config.Metadata[entity.MetaAccount],
+			entity.MetaTopic:   info.Config.Metadata[entity.MetaTopic],
+			entity.MetaOwner:   info.Config.Metadata[entity.MetaAccount],
+		},
+	}
+	if info.Config.Metadata[entity.MetaFifo] == "true" {
+		cfg.MaxAckPending = fifoReceiveMaxAckPending
+	}
+	// a receive racing this one may create the consumer first; the same config is accepted
+	if _, err := s.natsRepo.CreateConsumer(ctx, info.Config.Name, cfg); err != nil {
+		return nil, err
+	}
+	return s.natsRepo.GetConsumer(ctx, info.Config.Name, name)
+}
+
+// newPullMessage returns the message as received by a pull consumer
+func newPullMessage(account string, msg jetstream.Msg) entity.PullMessage {
+	m := entity.PullMessage{
+		AckToken:          entity.EncodeReceiptHandle(msg.Reply()),
+		MessageId:         deliveryMessageId(msg),
+		Subject:           strings.TrimPrefix(msg.Subject(), entity.AccountSubjectPrefix(account)),
+		Message:           string(msg.Data()),
+		MessageGroupId:    msg.Headers().Get(entity.HeaderMessageGroupId),
+		MessageAttributes: entity.MessageAttributesFromHeader(msg.Headers()),
+	}
+	if md, err := msg.Metadata(); err == nil {
+		m.SequenceNumber = md.Sequence.Stream
+		m.Timestamp = entity.NotificationTimestamp(md.Timestamp)
+		m.ReceiveCount = md.NumDelivered
+	}
+	return m
+}
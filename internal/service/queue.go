@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nats/internal/context/logs"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"strconv"
+	"time"
+
+	gonats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// QueueService reads queue subscriptions. A queue subscription is a durable pull consumer
+// shared by every receiver: a received message stays invisible until it is deleted or its
+// visibility timeout (the consumer AckWait) expires.
+type QueueService interface {
+	ReceiveMessages(ctx context.Context, account string, subscription entity.SRN, input entity.ReceiveMessageInput) ([]entity.QueueMessage, error)
+	DeleteMessage(ctx context.Context, account string, subscription entity.SRN, receiptHandle string) error
+	ChangeMessageVisibility(ctx context.Context, account string, subscription entity.SRN, receiptHandle string, visibilityTimeout int) error
+}
+
+// settleTimeout bounds the flush of an acknowledgement
+const settleTimeout = 5 * time.Second
+
+type queueService struct {
+	natsRepo repo.NatsRepo
+	cfg      *config.Config
+}
+
+func NewQueueService(natsRepo repo.NatsRepo, cfg *config.Config) QueueService {
+	return &queueService{natsRepo: natsRepo, cfg: cfg}
+}
+
+// ReceiveMessages fetches up to MaxNumberOfMessages, waiting up to WaitTimeSeconds for the first one.
+// Messages the FilterPolicy rejects are acked and not returned.
+func (s *queueService) ReceiveMessages(ctx context.Context, account string, subscription entity.SRN, input entity.ReceiveMessageInput) ([]entity.QueueMessage, error) {
+	maxMessages, err := receiveLimits(input.MaxNumberOfMessages, input.WaitTimeSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	ci, err := s.queueConsumer(ctx, account, subscription)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := s.natsRepo.GetConsumer(ctx, ci.Stream, ci.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := fetchMessages(ctx, consumer, maxMessages, input.WaitTimeSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newDeliveryTarget(s.cfg.Region, ci)
+	messages := make([]entity.QueueMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if t.Filter != nil && !t.matches(msg) {
+			_ = msg.Ack()
+			continue
+		}
+		m, err := newQueueMessage(t, msg)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// receiveLimits checks MaxNumberOfMessages and WaitTimeSeconds of a receive and returns
+// MaxNumberOfMessages, 1 when it is not set
+func receiveLimits(maxMessages, waitSeconds int) (int, error) {
+	if maxMessages == 0 {
+		maxMessages = 1
+	}
+	if maxMessages < 1 || maxMessages > entity.MaxReceiveMessages {
+		return 0, fmt.Errorf("%w: MaxNumberOfMessages must be between 1 and %d", entity.ErrInvalidParameter, entity.MaxReceiveMessages)
+	}
+	if waitSeconds < 0 || waitSeconds > entity.MaxReceiveWaitSeconds {
+		return 0, fmt.Errorf("%w: WaitTimeSeconds must be between 0 and %d", entity.ErrInvalidParameter, entity.MaxReceiveWaitSeconds)
+	}
+	return maxMessages, nil
+}
+
+// fetchMessages fetches up to maxMessages from the pull consumer, waiting up to waitSeconds for
+// the first one. The messages fetched before a batch error are delivered already, so they are
+// returned and the error is only logged; they would otherwise wait for their AckWait.
+func fetchMessages(ctx context.Context, consumer jetstream.Consumer, maxMessages, waitSeconds int) ([]jetstream.Msg, error) {
+	var batch jetstream.MessageBatch
+	var err error
+	if waitSeconds == 0 {
+		batch, err = consumer.FetchNoWait(maxMessages)
+	} else {
+		batch, err = consumer.Fetch(maxMessages, jetstream.FetchMaxWait(time.Duration(waitSeconds)*time.Second))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]jetstream.Msg, 0, maxMessages)
+	for msg := range batch.Messages() {
+		msgs = append(msgs, msg)
+	}
+	if err := batch.Error(); err != nil && !errors.Is(err, gonats.ErrTimeout) {
+		if len(msgs) == 0 {
+			return nil, err
+		}
+		logs.GetLogger(ctx).Warn("Fetch ended early, returning the messages received so far", zap.Int("messages", len(msgs)), zap.Error(err))
+	}
+	return msgs, nil
+}
+
+// DeleteMessage acks the received message so that it is never delivered again
+func (s *queueService) DeleteMessage(ctx context.Context, account string, subscription entity.SRN, receiptHandle string) error {
+	handle, err := s.receipt(ctx, account, subscription, receiptHandle)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, settleTimeout)
+	defer cancel()
+	return s.natsRepo.SettleMessage(ctx, handle.Subject, entity.AckAck)
+}
+
+// ChangeMessageVisibility makes the message visible again once the timeout has passed, at
+// once when it is 0. The message is naked with the timeout as delay, so it counts as a receive.
+func (s *queueService) ChangeMessageVisibility(ctx context.Context, account string, subscription entity.SRN, receiptHandle string, visibilityTimeout int) error {
+	if visibilityTimeout < 0 || visibilityTimeout > entity.MaxVisibilityTimeout {
+		return fmt.Errorf("%w: VisibilityTimeout must be between 0 and %d", entity.ErrInvalidParameter, entity.MaxVisibilityTimeout)
+	}
+	handle, err := s.receipt(ctx, account, subscription, receiptHandle)
+	if err != nil {
+		return err
+	}
+
+	ack := entity.AckNak
+	if visibilityTimeout > 0 {
+		ack = entity.AckNakWithDelay(time.Duration(visibilityTimeout) * time.Second)
+	}
+	ctx, cancel := context.WithTimeout(ctx, settleTimeout)
+	defer cancel()
+	return s.natsRepo.SettleMessage(ctx, handle.Subject, ack)
+}
+
+// queueConsumer returns the consumer of an owned queue subscription
+func (s *queueService) queueConsumer(ctx context.Context, account string, subscription entity.SRN) (*jetstream.ConsumerInfo, error) {
+	ci, err := findOwnedConsumer(ctx, s.natsRepo, s.cfg.Region, account, subscription)
+	if err != nil {
+		return nil, err
+	}
+	if ci.Config.Metadata[entity.MetaProtocol] != entity.ProtocolQueue {
+		return nil, fmt.Errorf("%w: %s is not a queue subscription", entity.ErrInvalidParameter, subscription)
+	}
+	return ci, nil
+}
+
+// receipt decodes the receipt handle and checks that it was issued by the subscription,
+// so that a handle can never settle messages of another account
+func (s *queueService) receipt(ctx context.Context, account string, subscription entity.SRN, receiptHandle string) (entity.ReceiptHandle, error) {
+	handle, err := entity.ParseReceiptHandle(receiptHandle)
+	if err != nil {
+		return entity.ReceiptHandle{}, err
+	}
+	ci, err := s.queueConsumer(ctx, account, subscription)
+	if err != nil {
+		return entity.ReceiptHandle{}, err
+	}
+	if handle.Stream != ci.Stream || handle.Consumer != ci.Name {
+		return entity.ReceiptHandle{}, fmt.Errorf("%w: ReceiptHandle does not belong to subscription %s", entity.ErrInvalidParameter, subscription)
+	}
+	return handle, nil
+}
+
+// newQueueMessage returns the message as received from the queue
+func newQueueMessage(t deliveryTarget, msg jetstream.Msg) (entity.QueueMessage, error) {
+	attrs := entity.MessageAttributesFromHeader(msg.Headers())
+	m := entity.QueueMessage{
+		MessageId:         deliveryMessageId(msg),
+		ReceiptHandle:     entity.EncodeReceiptHandle(msg.Reply()),
+		Body:              string(msg.Data()),
+		Attributes:        map[string]string{},
+		MessageAttributes: attrs,
+	}
+	if !t.Raw {
+		b, err := json.Marshal(newNotification(t, msg, attrs))
+		if err != nil {
+			return entity.QueueMessage{}, err
+		}
+		m.Body = string(b)
+	}
+	if md, err := msg.Metadata(); err == nil {
+		m.Attributes[entity.QueueAttrReceiveCount] = strconv.FormatUint(md.NumDelivered, 10)
+		m.Attributes[entity.QueueAttrSentTimestamp] = strconv.FormatInt(md.Timestamp.UnixMilli(), 10)
+		m.Attributes[entity.QueueAttrSequenceNumber] = strconv.FormatUint(md.Sequence.Stream, 10)
+	}
+	return m, nil
+}
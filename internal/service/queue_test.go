@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"nats/internal/entity"
+	"nats/pkg/config"
+	"testing"
+
+	gonats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeBatch struct {
+	msgs chan jetstream.Msg
+	err  error
+}
+
+func newFakeBatch(err error, msgs ...jetstream.Msg) *fakeBatch {
+	b := &fakeBatch{msgs: make(chan jetstream.Msg, len(msgs)), err: err}
+	for _, msg := range msgs {
+		b.msgs <- msg
+	}
+	close(b.msgs)
+	return b
+}
+
+func (b *fakeBatch) Messages() <-chan jetstream.Msg { return b.msgs }
+func (b *fakeBatch) Error() error                   { return b.err }
+
+// fakeFetchConsumer returns its batch from Fetch and FetchNoWait
+type fakeFetchConsumer struct {
+	jetstream.Consumer
+	batch  *fakeBatch
+	noWait bool
+}
+
+func (c *fakeFetchConsumer) Fetch(int, ...jetstream.FetchOpt) (jetstream.MessageBatch, error) {
+	return c.batch, nil
+}
+
+func (c *fakeFetchConsumer) FetchNoWait(int) (jetstream.MessageBatch, error) {
+	c.noWait = true
+	return c.batch, nil
+}
+
+func TestReceiveLimits(t *testing.T) {
+	n, err := receiveLimits(0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	for _, tt := range [][2]int{{11, 0}, {-1, 0}, {1, -1}, {1, 21}} {
+		_, err := receiveLimits(tt[0], tt[1])
+		assert.ErrorIs(t, err, entity.ErrInvalidParameter, "%v", tt)
+	}
+}
+
+func TestFetchMessages(t *testing.T) {
+	first, second := newFakeMsg("first"), newFakeMsg("second")
+
+	consumer := &fakeFetchConsumer{batch: newFakeBatch(gonats.ErrTimeout, first)}
+	msgs, err := fetchMessages(context.Background(), consumer, 10, 0)
+	assert.NoError(t, err)
+	assert.True(t, consumer.noWait)
+	assert.Len(t, msgs, 1)
+
+	// the messages fetched before the connection dropped are delivered already
+	consumer = &fakeFetchConsumer{batch: newFakeBatch(errors.New("nats: connection closed"), first, second)}
+	msgs, err = fetchMessages(context.Background(), consumer, 10, 5)
+	assert.NoError(t, err)
+	assert.False(t, consumer.noWait)
+	assert.Equal(t, []jetstream.Msg{first, second}, msgs)
+
+	consumer = &fakeFetchConsumer{batch: newFakeBatch(errors.New("nats: connection closed"))}
+	_, err = fetchMessages(context.Background(), consumer, 10, 5)
+	assert.Error(t, err)
+}
+
+// fakeSettleRepo records the acknowledgements published for a queue subscription
+type fakeSettleRepo struct {
+	*fakeSubscriptionRepo
+	acks []string
+}
+
+func (r *fakeSettleRepo) SettleMessage(_ context.Context, _ string, ack string) error {
+	r.acks = append(r.acks, ack)
+	return nil
+}
+
+func TestChangeMessageVisibility(t *testing.T) {
+	natsRepo := &fakeSettleRepo{fakeSubscriptionRepo: newFakeSubscriptionRepo()}
+	cfg := &config.Config{Region: "kr-west1"}
+	sub, err := NewSubscriptionService(natsRepo, cfg).Subscribe(context.Background(), "acct", entity.NewTopicSRN("kr-west1", "acct", "orders"),
+		entity.SubscribeInput{Protocol: entity.ProtocolQueue, Endpoint: "orders-worker"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	srn, _ := entity.ParseSRN(sub.SubscriptionSrn)
+	handle := entity.EncodeReceiptHandle("$JS.ACK.acct_orders." + srn.Subscription + ".1.42.1.1700000000000000000.0")
+	svc := NewQueueService(natsRepo, cfg)
+
+	assert.NoError(t, svc.ChangeMessageVisibility(context.Background(), "acct", srn, handle, 0))
+	assert.NoError(t, svc.ChangeMessageVisibility(context.Background(), "acct", srn, handle, 90))
+	assert.Equal(t, []string{"-NAK", `-NAK {"delay": 90000000000}`}, natsRepo.acks)
+
+	err = svc.ChangeMessageVisibility(context.Background(), "acct", srn, handle, entity.MaxVisibilityTimeout+1)
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+
+	foreign := entity.EncodeReceiptHandle("$JS.ACK.acct_orders.other.1.42.1.1700000000000000000.0")
+	err = svc.ChangeMessageVisibility(context.Background(), "acct", srn, foreign, 0)
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+	assert.Len(t, natsRepo.acks, 2)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"nats/internal/entity"
+	"nats/pkg/config"
+	"nats/pkg/signature"
+	"os"
+)
+
+// NotificationSigner signs the delivery envelopes with the configured key
+type NotificationSigner struct {
+	key     *rsa.PrivateKey
+	certPEM []byte
+	certURL string
+}
+
+// NewNotificationSigner loads the signing key and certificate.
+// It returns nil when no key is configured, deliveries are then unsigned.
+func NewNotificationSigner(cfg *config.Config) (*NotificationSigner, error) {
+	c := cfg.Signing
+	if c.PrivateKeyFile == "" {
+		return nil, nil
+	}
+	if c.CertificateFile == "" || c.CertificateURL == "" {
+		return nil, errors.New("signing needs certificateFile and certificateURL")
+	}
+
+	keyPEM, err := os.ReadFile(c.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := signature.ParsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+	certPEM, err := os.ReadFile(c.CertificateFile)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := signature.ParseCertificate(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("signing certificate: %w", err)
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		return nil, errors.New("signing certificate does not match the signing key")
+	}
+	return &NotificationSigner{key: key, certPEM: certPEM, certURL: c.CertificateURL}, nil
+}
+
+// CertificatePEM returns the certificate receivers verify signatures with
+func (s *NotificationSigner) CertificatePEM() []byte {
+	return s.certPEM
+}
+
+// Sign fills the signature fields of the envelope
+func (s *NotificationSigner) Sign(n *entity.Notification) error {
+	m := signature.Message{
+		Type:           n.Type,
+		MessageId:      n.MessageId,
+		TopicSrn:       n.TopicSrn,
+		Subject:        n.Subject,
+		Message:        n.Message,
+		Timestamp:      n.Timestamp,
+		Token:          n.Token,
+		SubscribeURL:   n.SubscribeURL,
+		SigningCertURL: s.certURL,
+	}
+	if err := signature.Sign(&m, s.key); err != nil {
+		return err
+	}
+	n.SignatureVersion, n.Signature, n.SigningCertURL = m.SignatureVersion, m.Signature, m.SigningCertURL
+	return nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// StreamService streams a topic live to SSE and WebSocket clients. Every client gets its
+// own ephemeral consumer, so clients never share or settle messages.
+type StreamService interface {
+	Open(ctx context.Context, account string, topic entity.SRN, lastEventId uint64) (TopicStream, error)
+}
+
+// TopicStream is an open live stream. Close must be called when the client disconnects.
+type TopicStream interface {
+	// Events delivers the messages in stream order
+	Events() <-chan entity.StreamEvent
+	Close()
+}
+
+// streamInactiveThreshold removes the consumer of a stream whose server died before closing it
+const streamInactiveThreshold = time.Minute
+
+// streamBuffer is how many events wait for a slow client before the consumer stops pulling
+const streamBuffer = 64
+
+type streamService struct {
+	natsRepo repo.NatsRepo
+	cfg      *config.Config
+}
+
+func NewStreamService(natsRepo repo.NatsRepo, cfg *config.Config) StreamService {
+	return &streamService{natsRepo: natsRepo, cfg: cfg}
+}
+
+type topicStream struct {
+	natsRepo repo.NatsRepo
+	stream   string
+	consumer string
+	cc       jetstream.ConsumeContext
+	events   chan entity.StreamEvent
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Open attaches an ephemeral consumer to the topic. It starts after lastEventId,
+// or with new messages when lastEventId is 0.
+func (s *streamService) Open(ctx context.Context, account string, topic entity.SRN, lastEventId uint64) (TopicStream, error) {
+	info, err := findOwnedTopicStream(ctx, s.natsRepo, s.cfg.Region, account, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := jetstream.ConsumerConfig{
+		Name:              entity.StreamConsumerPrefix + uuid.NewString(),
+		DeliverPolicy:     jetstream.DeliverNewPolicy,
+		AckPolicy:         jetstream.AckNonePolicy,
+		InactiveThreshold: streamInactiveThreshold,
+		MemoryStorage:     true,
+	}
+	if lastEventId > 0 {
+		cfg.DeliverPolicy = jetstream.DeliverByStartSequencePolicy
+		cfg.OptStartSeq = lastEventId + 1
+	}
+	ci, err := s.natsRepo.CreateConsumer(ctx, info.Config.Name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &topicStream{
+		natsRepo: s.natsRepo,
+		stream:   ci.Stream,
+		consumer: ci.Name,
+		events:   make(chan entity.StreamEvent, streamBuffer),
+		done:     make(chan struct{}),
+	}
+	consumer, err := s.natsRepo.GetConsumer(ctx, ci.Stream, ci.Name)
+	if err == nil {
+		ts.cc, err = consumer.Consume(func(msg jetstream.Msg) {
+			select {
+			case ts.events <- newStreamEvent(account, msg):
+			case <-ts.done:
+			}
+		})
+	}
+	if err != nil {
+		ts.Close()
+		return nil, err
+	}
+	return ts, nil
+}
+
+func (t *topicStream) Events() <-chan entity.StreamEvent {
+	return t.events
+}
+
+// Close stops pulling and deletes the consumer. The request context is usually
+// cancelled by then, so the deletion runs on its own timeout.
+func (t *topicStream) Close() {
+	t.once.Do(func() {
+		close(t.done)
+		if t.cc != nil {
+			t.cc.Stop()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), settleTimeout)
+		defer cancel()
+		_ = t.natsRepo.DeleteConsumer(ctx, t.stream, t.consumer)
+	})
+}
+
+// newStreamEvent returns the message as pushed to a live stream client
+func newStreamEvent(account string, msg jetstream.Msg) entity.StreamEvent {
+	e := entity.StreamEvent{
+		MessageId:         deliveryMessageId(msg),
+		Subject:           strings.TrimPrefix(msg.Subject(), entity.AccountSubjectPrefix(account)),
+		Message:           string(msg.Data()),
+		MessageGroupId:    msg.Headers().Get(entity.HeaderMessageGroupId),
+		MessageAttributes: entity.MessageAttributesFromHeader(msg.Headers()),
+	}
+	if md, err := msg.Metadata(); err == nil {
+		e.SequenceNumber = md.Sequence.Stream
+		e.Timestamp = entity.NotificationTimestamp(md.Timestamp)
+	}
+	return e
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStreamEvent(t *testing.T) {
+	msg := newFakeMsg("hello")
+	msg.headers.Set("Sns-Attr-store", "seoul")
+
+	e := newStreamEvent("acct", msg)
+	assert.Equal(t, uint64(42), e.SequenceNumber)
+	assert.Equal(t, "msg-1", e.MessageId)
+	assert.Equal(t, "orders.created", e.Subject)
+	assert.Equal(t, "hello", e.Message)
+	assert.Equal(t, "2026-01-02T03:04:05.006Z", e.Timestamp)
+	assert.Equal(t, "seoul", e.MessageAttributes["store"].StringValue)
+}
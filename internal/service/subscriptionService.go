@@ -0,0 +1,507 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"nats/internal/context/traces"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"nats/pkg/filterpolicy"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type SubscriptionService interface {
+	Subscribe(ctx context.Context, account string, topic entity.SRN, input entity.SubscribeInput) (entity.Subscription, error)
+	Unsubscribe(ctx context.Context, account string, subscription entity.SRN) error
+	ListSubscriptionsByTopic(ctx context.Context, account string, topic entity.SRN) ([]entity.Subscription, error)
+	GetSubscriptionAttributes(ctx context.Context, account string, subscription entity.SRN) (map[string]string, error)
+	SetSubscriptionAttributes(ctx context.Context, account string, subscription entity.SRN, attrName, attrValue string) error
+	ConfirmSubscription(ctx context.Context, topic entity.SRN, token string) (entity.Subscription, error)
+}
+
+type subscriptionService struct {
+	natsRepo repo.NatsRepo
+	cfg      *config.Config
+}
+
+func NewSubscriptionService(natsRepo repo.NatsRepo, cfg *config.Config) SubscriptionService {
+	return &subscriptionService{natsRepo: natsRepo, cfg: cfg}
+}
+
+// Subscribe creates a durable consumer on the topic stream. Subscribing the same
+// protocol and endpoint twice returns the existing subscription, unless the
+// requested attributes differ from the existing ones.
+// The consumer name is derived from the protocol and endpoint, so concurrent subscribes
+// of an endpoint meet at the same consumer instead of creating one each.
+// HTTP/HTTPS subscriptions stay pending until the endpoint confirms them; subscribing a
+// pending endpoint again issues a new token so that the confirmation is sent again.
+func (s *subscriptionService) Subscribe(ctx context.Context, account string, topic entity.SRN, input entity.SubscribeInput) (entity.Subscription, error) {
+	if err := entity.ValidateEndpoint(input.Protocol, input.Endpoint); err != nil {
+		return entity.Subscription{}, err
+	}
+
+	info, err := findOwnedTopicStream(ctx, s.natsRepo, s.cfg.Region, account, topic)
+	if err != nil {
+		return entity.Subscription{}, err
+	}
+
+	consumerCfg := newConsumerConfig(info, subscriptionID(input.Protocol, input.Endpoint), input)
+	if err := applySubscriptionAttributes(&consumerCfg, input.Attributes); err != nil {
+		return entity.Subscription{}, err
+	}
+	if err := s.checkDeadLetterTarget(ctx, account, info, consumerCfg.Metadata); err != nil {
+		return entity.Subscription{}, err
+	}
+	if entity.NeedsConfirmation(input.Protocol) {
+		if err := s.setPendingConfirmation(consumerCfg.Metadata); err != nil {
+			return entity.Subscription{}, err
+		}
+	}
+
+	existing, err := s.findSubscription(ctx, info.Config.Name, consumerCfg)
+	if err != nil {
+		return entity.Subscription{}, err
+	}
+	if existing != nil {
+		return s.resubscribe(ctx, existing, consumerCfg)
+	}
+
+	ci, err := s.natsRepo.CreateConsumer(ctx, info.Config.Name, consumerCfg)
+	if errors.Is(err, jetstream.ErrConsumerExists) {
+		// a concurrent subscribe of the endpoint created the consumer first
+		existing, err := s.natsRepo.GetConsumerInfo(ctx, info.Config.Name, consumerCfg.Durable)
+		if err != nil {
+			return entity.Subscription{}, err
+		}
+		return s.resubscribe(ctx, existing, consumerCfg)
+	}
+	if err != nil {
+		return entity.Subscription{}, err
+	}
+	return s.subscription(ci), nil
+}
+
+// subscriptionID derives the consumer name of a subscription from its protocol and endpoint
+func subscriptionID(protocol, endpoint string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(protocol+"\x00"+endpoint)).String()
+}
+
+// findSubscription returns the consumer of the protocol and endpoint, nil when there is none.
+// Subscriptions created before consumer names were derived from the endpoint have random
+// names and are found by their metadata.
+func (s *subscriptionService) findSubscription(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (*jetstream.ConsumerInfo, error) {
+	ci, err := s.natsRepo.GetConsumerInfo(ctx, stream, cfg.Durable)
+	if err == nil {
+		return ci, nil
+	}
+	if !errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return nil, err
+	}
+
+	consumers, err := s.natsRepo.ListConsumers(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	for _, ci := range consumers {
+		md := ci.Config.Metadata
+		if md[entity.MetaProtocol] == cfg.Metadata[entity.MetaProtocol] && md[entity.MetaEndpoint] == cfg.Metadata[entity.MetaEndpoint] {
+			return ci, nil
+		}
+	}
+	return nil, nil
+}
+
+// resubscribe returns the existing subscription of the endpoint when its attributes match the
+// requested ones, issuing a new confirmation token when it is still pending
+func (s *subscriptionService) resubscribe(ctx context.Context, ci *jetstream.ConsumerInfo, want jetstream.ConsumerConfig) (entity.Subscription, error) {
+	md := ci.Config.Metadata
+	for _, key := range entity.SubscriptionAttributeMeta {
+		if md[key] != want.Metadata[key] {
+			return entity.Subscription{}, fmt.Errorf("%w: endpoint is already subscribed with different attributes", entity.ErrConflict)
+		}
+	}
+	if entity.IsPendingConfirmation(md) {
+		pending := ci.Config
+		pending.Metadata = maps.Clone(md)
+		if err := s.setPendingConfirmation(pending.Metadata); err != nil {
+			return entity.Subscription{}, err
+		}
+		updated, err := s.natsRepo.UpdateConsumer(ctx, ci.Stream, pending)
+		if err != nil {
+			return entity.Subscription{}, err
+		}
+		ci = updated
+	}
+	return s.subscription(ci), nil
+}
+
+func (s *subscriptionService) Unsubscribe(ctx context.Context, account string, subscription entity.SRN) error {
+	ci, err := s.ownedConsumer(ctx, account, subscription)
+	if err != nil {
+		return err
+	}
+	return s.natsRepo.DeleteConsumer(ctx, ci.Stream, ci.Name)
+}
+
+func (s *subscriptionService) ListSubscriptionsByTopic(ctx context.Context, account string, topic entity.SRN) ([]entity.Subscription, error) {
+	ctx, span := traces.StartSpan(ctx, "listSubscriptionsByTopic")
+	defer span.End()
+
+	info, err := findOwnedTopicStream(ctx, s.natsRepo, s.cfg.Region, account, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	consumers, err := s.natsRepo.ListConsumers(ctx, info.Config.Name)
+	if err != nil {
+		traces.RecordSpanError(ctx, span, "natsRepo.ListConsumers error", err)
+		return nil, err
+	}
+
+	subscriptions := make([]entity.Subscription, 0, len(consumers))
+	for _, ci := range consumers {
+		if ci.Config.Metadata[entity.MetaProtocol] == "" {
+			continue // not created by subscribe
+		}
+		subscriptions = append(subscriptions, s.subscription(ci))
+	}
+	return subscriptions, nil
+}
+
+func (s *subscriptionService) GetSubscriptionAttributes(ctx context.Context, account string, subscription entity.SRN) (map[string]string, error) {
+	ci, err := s.ownedConsumer(ctx, account, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := s.subscription(ci)
+	attrs := map[string]string{
+		entity.AttrSubscriptionSrn:     sub.SubscriptionSrn,
+		entity.AttrTopicSrn:            sub.TopicSrn,
+		entity.AttrOwner:               sub.Owner,
+		entity.AttrProtocol:            sub.Protocol,
+		entity.AttrEndpoint:            sub.Endpoint,
+		entity.AttrPendingConfirmation: strconv.FormatBool(sub.PendingConfirmation),
+	}
+	for name, key := range entity.SubscriptionAttributeMeta {
+		if v := ci.Config.Metadata[key]; v != "" {
+			attrs[name] = v
+		}
+	}
+	return attrs, nil
+}
+
+func (s *subscriptionService) SetSubscriptionAttributes(ctx context.Context, account string, subscription entity.SRN, attrName, attrValue string) error {
+	ci, err := s.ownedConsumer(ctx, account, subscription)
+	if err != nil {
+		return err
+	}
+
+	consumerCfg := ci.Config
+	consumerCfg.Metadata = maps.Clone(consumerCfg.Metadata)
+	if err := applySubscriptionAttributes(&consumerCfg, map[string]string{attrName: attrValue}); err != nil {
+		return err
+	}
+	if attrName == entity.AttrRedrivePolicy && attrValue != "" {
+		info, err := s.natsRepo.GetStreamInfo(ctx, ci.Stream)
+		if err != nil {
+			return err
+		}
+		if err := s.checkDeadLetterTarget(ctx, account, info, consumerCfg.Metadata); err != nil {
+			return err
+		}
+	}
+	_, err = s.natsRepo.UpdateConsumer(ctx, ci.Stream, consumerCfg)
+	return err
+}
+
+// ConfirmSubscription confirms the pending subscription of the topic that was sent the token.
+// The token is the credential, so the caller does not need to own the topic.
+// The last sequence of the topic is recorded so that messages published while the
+// subscription was pending are not delivered.
+func (s *subscriptionService) ConfirmSubscription(ctx context.Context, topic entity.SRN, token string) (entity.Subscription, error) {
+	if token == "" {
+		return entity.Subscription{}, fmt.Errorf("%w: missing confirmation token", entity.ErrInvalidParameter)
+	}
+	info, err := findOwnedTopicStream(ctx, s.natsRepo, s.cfg.Region, topic.Account, topic)
+	if err != nil {
+		return entity.Subscription{}, err
+	}
+	consumers, err := s.natsRepo.ListConsumers(ctx, info.Config.Name)
+	if err != nil {
+		return entity.Subscription{}, err
+	}
+
+	for _, ci := range consumers {
+		md := ci.Config.Metadata
+		if !entity.IsPendingConfirmation(md) || subtle.ConstantTimeCompare([]byte(md[entity.MetaConfirmToken]), []byte(token)) != 1 {
+			continue
+		}
+		if entity.ConfirmationExpired(md, time.Now()) {
+			return entity.Subscription{}, fmt.Errorf("%w: confirmation token expired", entity.ErrInvalidParameter)
+		}
+
+		confirmed := ci.Config
+		confirmed.Metadata = maps.Clone(md)
+		delete(confirmed.Metadata, entity.MetaStatus)
+		delete(confirmed.Metadata, entity.MetaConfirmToken)
+		delete(confirmed.Metadata, entity.MetaConfirmExpires)
+		confirmed.Metadata[entity.MetaConfirmedSeq] = strconv.FormatUint(info.State.LastSeq, 10)
+		updated, err := s.natsRepo.UpdateConsumer(ctx, ci.Stream, confirmed)
+		if err != nil {
+			return entity.Subscription{}, err
+		}
+		return s.subscription(updated), nil
+	}
+	return entity.Subscription{}, fmt.Errorf("%w: invalid confirmation token", entity.ErrInvalidParameter)
+}
+
+// setPendingConfirmation marks the subscription pending with a new token and expiry
+func (s *subscriptionService) setPendingConfirmation(md map[string]string) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	expiry := s.cfg.Subscription.ConfirmationExpiry
+	if expiry <= 0 {
+		expiry = entity.DefaultConfirmationExpiry
+	}
+	md[entity.MetaStatus] = entity.SubscriptionPendingConfirmation
+	md[entity.MetaConfirmToken] = hex.EncodeToString(b)
+	md[entity.MetaConfirmExpires] = time.Now().Add(expiry).UTC().Format(time.RFC3339)
+	return nil
+}
+
+// checkDeadLetterTarget checks that the dead-letter topic of the redrive policy exists, is owned
+// by the account, is not the subscribed topic itself and has the same type (FIFO or standard)
+func (s *subscriptionService) checkDeadLetterTarget(ctx context.Context, account string, source *jetstream.StreamInfo, metadata map[string]string) error {
+	value := metadata[entity.MetaRedrivePolicy]
+	if value == "" {
+		return nil
+	}
+	_, target, err := entity.ParseRedrivePolicy(value)
+	if err != nil {
+		return err
+	}
+
+	info, err := findOwnedTopicStream(ctx, s.natsRepo, s.cfg.Region, account, target)
+	if err != nil {
+		return err
+	}
+	if info.Config.Name == source.Config.Name {
+		return fmt.Errorf("%w: a topic cannot be its own dead-letter topic", entity.ErrInvalidParameter)
+	}
+	if info.Config.Metadata[entity.MetaFifo] != source.Config.Metadata[entity.MetaFifo] {
+		return fmt.Errorf("%w: the dead-letter topic of a FIFO topic must be a FIFO topic and vice versa", entity.ErrInvalidParameter)
+	}
+	return nil
+}
+
+func (s *subscriptionService) ownedConsumer(ctx context.Context, account string, subscription entity.SRN) (*jetstream.ConsumerInfo, error) {
+	return findOwnedConsumer(ctx, s.natsRepo, s.cfg.Region, account, subscription)
+}
+
+// findOwnedConsumer checks that the subscription SRN belongs to the region and the account,
+// then returns the consumer info backing the subscription
+func findOwnedConsumer(ctx context.Context, natsRepo repo.NatsRepo, region, account string, subscription entity.SRN) (*jetstream.ConsumerInfo, error) {
+	if !subscription.IsSubscription() {
+		return nil, fmt.Errorf("%w: %s is not a subscription srn", entity.ErrInvalidParameter, subscription)
+	}
+	info, err := findOwnedTopicStream(ctx, natsRepo, region, account, subscription.TopicSRN())
+	if err != nil {
+		return nil, err
+	}
+
+	ci, err := natsRepo.GetConsumerInfo(ctx, info.Config.Name, subscription.Subscription)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return nil, fmt.Errorf("%w: subscription %s", entity.ErrNotFound, subscription)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ci.Config.Metadata[entity.MetaOwner] != account {
+		return nil, fmt.Errorf("%w: subscription %s is not owned by account %s", entity.ErrAuthorization, subscription, account)
+	}
+	return ci, nil
+}
+
+func (s *subscriptionService) subscription(ci *jetstream.ConsumerInfo) entity.Subscription {
+	md := ci.Config.Metadata
+	topic := entity.NewTopicSRN(s.cfg.Region, md[entity.MetaAccount], md[entity.MetaTopic])
+	sub := topic
+	sub.Subscription = ci.Name
+	return entity.Subscription{
+		SubscriptionSrn: sub.String(),
+		TopicSrn:        topic.String(),
+		Owner:           md[entity.MetaOwner],
+		Protocol:        md[entity.MetaProtocol],
+		Endpoint:        md[entity.MetaEndpoint],
+
+		PendingConfirmation: entity.IsPendingConfirmation(md),
+	}
+}
+
+// subscriptionAckWait is how long a delivered message may stay unsettled before it is redelivered.
+// It must outlive the webhook timeout so an in-flight POST is never delivered twice.
+const subscriptionAckWait = 30 * time.Second
+
+// fifoReceiveMaxAckPending orders the queue and pull receives of a FIFO topic across the
+// whole topic: receivers settle messages themselves, so one unacknowledged message at a time
+// is the only way to keep every message group in order. A slow group blocks the others.
+// HTTP/HTTPS deliveries order each group separately, see groupSequencer.
+const fifoReceiveMaxAckPending = 1
+
+// newConsumerConfig builds the durable pull consumer backing a subscription.
+// The subscription record is kept in the consumer metadata. Queue subscriptions of
+// FIFO topics receive the whole topic in order.
+func newConsumerConfig(topic *jetstream.StreamInfo, id string, input entity.SubscribeInput) jetstream.ConsumerConfig {
+	account := topic.Config.Metadata[entity.MetaAccount]
+	cfg := jetstream.ConsumerConfig{
+		Durable:       id,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       subscriptionAckWait,
+		MaxDeliver:    -1,
+		MaxAckPending: 1000,
+		Metadata: map[string]string{
+			entity.MetaAccount:  account,
+			entity.MetaTopic:    topic.Config.Metadata[entity.MetaTopic],
+			entity.MetaOwner:    account,
+			entity.MetaProtocol: input.Protocol,
+			entity.MetaEndpoint: input.Endpoint,
+		},
+	}
+	if topic.Config.Metadata[entity.MetaFifo] == "true" && input.Protocol == entity.ProtocolQueue {
+		cfg.MaxAckPending = fifoReceiveMaxAckPending
+	}
+	return cfg
+}
+
+// applySubscriptionAttributes validates the attributes and writes them into the consumer config.
+//
+// DeliveryPolicy maps onto the consumer: numRetries+1 becomes MaxDeliver and the retry delays
+// become BackOff. Failed deliveries are naked with the policy delay, BackOff only schedules
+// messages that were never settled, so every step is kept at least subscriptionAckWait.
+// RedrivePolicy requires a DeliveryPolicy, without one MaxDeliver is unlimited and no message
+// is ever dead-lettered. An empty value removes the policy.
+func applySubscriptionAttributes(cfg *jetstream.ConsumerConfig, attrs map[string]string) error {
+	for name, value := range attrs {
+		if entity.ReadOnlySubscriptionAttributes[name] {
+			return fmt.Errorf("%w: attribute %s is read-only", entity.ErrInvalidParameter, name)
+		}
+		switch name {
+		case entity.AttrDeliveryPolicy:
+			if cfg.Metadata[entity.MetaProtocol] == entity.ProtocolQueue {
+				return fmt.Errorf("%w: DeliveryPolicy does not apply to queue subscriptions", entity.ErrInvalidParameter)
+			}
+			if value == "" {
+				delete(cfg.Metadata, entity.MetaDeliveryPolicy)
+				cfg.MaxDeliver, cfg.BackOff, cfg.AckWait = -1, nil, subscriptionAckWait
+				continue
+			}
+			policy, err := entity.ParseDeliveryPolicy(value)
+			if err != nil {
+				return err
+			}
+			cfg.Metadata[entity.MetaDeliveryPolicy] = policy.String()
+			cfg.MaxDeliver = policy.MaxDeliver()
+			cfg.BackOff = nil
+			for _, d := range policy.Delays() {
+				cfg.BackOff = append(cfg.BackOff, max(d, subscriptionAckWait))
+			}
+			cfg.AckWait = subscriptionAckWait
+			if len(cfg.BackOff) > 0 {
+				cfg.AckWait = cfg.BackOff[0]
+			}
+		case entity.AttrRedrivePolicy:
+			if cfg.Metadata[entity.MetaProtocol] == entity.ProtocolQueue {
+				return fmt.Errorf("%w: RedrivePolicy does not apply to queue subscriptions", entity.ErrInvalidParameter)
+			}
+			if value == "" {
+				delete(cfg.Metadata, entity.MetaRedrivePolicy)
+				continue
+			}
+			policy, _, err := entity.ParseRedrivePolicy(value)
+			if err != nil {
+				return err
+			}
+			cfg.Metadata[entity.MetaRedrivePolicy] = policy.String()
+		case entity.AttrFilterPolicyScope:
+			switch value {
+			case "", entity.FilterScopeMessageAttributes:
+				delete(cfg.Metadata, entity.MetaFilterPolicyScope)
+			case entity.FilterScopeMessageBody:
+				cfg.Metadata[entity.MetaFilterPolicyScope] = value
+			default:
+				return fmt.Errorf("%w: FilterPolicyScope must be %s or %s", entity.ErrInvalidParameter, entity.FilterScopeMessageAttributes, entity.FilterScopeMessageBody)
+			}
+		case entity.AttrVisibilityTimeout:
+			if cfg.Metadata[entity.MetaProtocol] != entity.ProtocolQueue {
+				return fmt.Errorf("%w: VisibilityTimeout only applies to queue subscriptions", entity.ErrInvalidParameter)
+			}
+			if value == "" {
+				delete(cfg.Metadata, entity.MetaVisibilityTimeout)
+				cfg.AckWait = subscriptionAckWait
+				continue
+			}
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds < 1 || seconds > entity.MaxVisibilityTimeout {
+				return fmt.Errorf("%w: VisibilityTimeout must be between 1 and %d seconds", entity.ErrInvalidParameter, entity.MaxVisibilityTimeout)
+			}
+			cfg.Metadata[entity.MetaVisibilityTimeout] = value
+			cfg.AckWait = time.Duration(seconds) * time.Second
+		case entity.AttrRawMessageDelivery:
+			switch value {
+			case "", "false":
+				delete(cfg.Metadata, entity.MetaRawMessageDelivery)
+			case "true":
+				cfg.Metadata[entity.MetaRawMessageDelivery] = value
+			default:
+				return fmt.Errorf("%w: RawMessageDelivery must be true or false", entity.ErrInvalidParameter)
+			}
+		case entity.AttrFilterPolicy:
+			if value == "" {
+				delete(cfg.Metadata, entity.MetaFilterPolicy)
+				continue
+			}
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, []byte(value)); err != nil {
+				return fmt.Errorf("%w: FilterPolicy is not valid JSON", entity.ErrInvalidParameter)
+			}
+			cfg.Metadata[entity.MetaFilterPolicy] = compact.String()
+		default:
+			return fmt.Errorf("%w: unknown attribute %s", entity.ErrInvalidParameter, name)
+		}
+	}
+
+	// the policies may be set together, validate them once all are applied
+	if cfg.Metadata[entity.MetaRedrivePolicy] != "" && cfg.Metadata[entity.MetaDeliveryPolicy] == "" {
+		return fmt.Errorf("%w: RedrivePolicy requires a DeliveryPolicy, messages are dead-lettered once its retries are exhausted", entity.ErrInvalidParameter)
+	}
+	if policy := cfg.Metadata[entity.MetaFilterPolicy]; policy != "" {
+		if _, err := filterpolicy.Parse([]byte(policy), filterScope(cfg.Metadata)); err != nil {
+			return fmt.Errorf("%w: %v", entity.ErrInvalidParameter, err)
+		}
+	}
+	return nil
+}
+
+// filterScope returns the scope of the subscription FilterPolicy
+func filterScope(md map[string]string) filterpolicy.Scope {
+	if md[entity.MetaFilterPolicyScope] == entity.FilterScopeMessageBody {
+		return filterpolicy.ScopeMessageBody
+	}
+	return filterpolicy.ScopeMessageAttributes
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSubscriptionRepo holds the consumers of a single topic stream
+type fakeSubscriptionRepo struct {
+	repo.NatsRepo
+	mu        sync.Mutex
+	creates   int
+	info      *jetstream.StreamInfo
+	consumers map[string]jetstream.ConsumerConfig
+	updateErr error
+	deleted   bool
+}
+
+func newFakeSubscriptionRepo() *fakeSubscriptionRepo {
+	return &fakeSubscriptionRepo{
+		info: &jetstream.StreamInfo{
+			Config: jetstream.StreamConfig{
+				Name:     "acct_orders",
+				Metadata: map[string]string{entity.MetaAccount: "acct", entity.MetaTopic: "orders"},
+			},
+			State: jetstream.StreamState{LastSeq: 17},
+		},
+		consumers: make(map[string]jetstream.ConsumerConfig),
+	}
+}
+
+func (r *fakeSubscriptionRepo) GetStreamInfo(context.Context, string) (*jetstream.StreamInfo, error) {
+	return r.info, nil
+}
+
+func (r *fakeSubscriptionRepo) ListConsumers(_ context.Context, stream string) ([]*jetstream.ConsumerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []*jetstream.ConsumerInfo
+	for name, cfg := range r.consumers {
+		list = append(list, &jetstream.ConsumerInfo{Stream: stream, Name: name, Config: cfg})
+	}
+	return list, nil
+}
+
+func (r *fakeSubscriptionRepo) GetConsumerInfo(_ context.Context, stream, name string) (*jetstream.ConsumerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cfg, ok := r.consumers[name]
+	if !ok {
+		return nil, jetstream.ErrConsumerNotFound
+	}
+	return &jetstream.ConsumerInfo{Stream: stream, Name: name, Config: cfg}, nil
+}
+
+// CreateConsumer accepts the config of an existing consumer only when it is the same, as the server does
+func (r *fakeSubscriptionRepo) CreateConsumer(_ context.Context, stream string, cfg jetstream.ConsumerConfig) (*jetstream.ConsumerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.consumers[cfg.Durable]; ok && !reflect.DeepEqual(existing, cfg) {
+		return nil, jetstream.ErrConsumerExists
+	}
+	if _, ok := r.consumers[cfg.Durable]; !ok {
+		r.creates++
+	}
+	r.consumers[cfg.Durable] = cfg
+	return &jetstream.ConsumerInfo{Stream: stream, Name: cfg.Durable, Config: cfg}, nil
+}
+
+func (r *fakeSubscriptionRepo) UpdateConsumer(_ context.Context, stream string, cfg jetstream.ConsumerConfig) (*jetstream.ConsumerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.updateErr != nil {
+		return nil, r.updateErr
+	}
+	r.consumers[cfg.Durable] = cfg
+	return &jetstream.ConsumerInfo{Stream: stream, Name: cfg.Durable, Config: cfg}, nil
+}
+
+func (r *fakeSubscriptionRepo) DeleteConsumer(_ context.Context, _ string, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.consumers, name)
+	r.deleted = true
+	return nil
+}
+
+func pendingSubscription(r *fakeSubscriptionRepo, token string) {
+	r.consumers["sub-1"] = jetstream.ConsumerConfig{
+		Durable: "sub-1",
+		Metadata: map[string]string{
+			entity.MetaAccount:        "acct",
+			entity.MetaTopic:          "orders",
+			entity.MetaOwner:          "acct",
+			entity.MetaProtocol:       entity.ProtocolHTTPS,
+			entity.MetaEndpoint:       "https://example.com/hook",
+			entity.MetaStatus:         entity.SubscriptionPendingConfirmation,
+			entity.MetaConfirmToken:   token,
+			entity.MetaConfirmExpires: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+func TestConfirmSubscription_UpdatesConsumerInPlace(t *testing.T) {
+	natsRepo := newFakeSubscriptionRepo()
+	pendingSubscription(natsRepo, "token-1")
+	svc := NewSubscriptionService(natsRepo, &config.Config{Region: "kr-west1"})
+
+	sub, err := svc.ConfirmSubscription(context.Background(), entity.NewTopicSRN("kr-west1", "acct", "orders"), "token-1")
+	assert.NoError(t, err)
+	assert.False(t, sub.PendingConfirmation)
+	assert.False(t, natsRepo.deleted)
+
+	md := natsRepo.consumers["sub-1"].Metadata
+	assert.False(t, entity.IsPendingConfirmation(md))
+	assert.Empty(t, md[entity.MetaConfirmToken])
+	assert.EqualValues(t, 17, entity.ConfirmedSequence(md))
+}
+
+func TestConfirmSubscription_FailedUpdateKeepsToken(t *testing.T) {
+	natsRepo := newFakeSubscriptionRepo()
+	pendingSubscription(natsRepo, "token-1")
+	natsRepo.updateErr = errors.New("nats: timeout")
+	svc := NewSubscriptionService(natsRepo, &config.Config{Region: "kr-west1"})
+	topic := entity.NewTopicSRN("kr-west1", "acct", "orders")
+
+	_, err := svc.ConfirmSubscription(context.Background(), topic, "token-1")
+	assert.Error(t, err)
+
+	natsRepo.updateErr = nil
+	_, err = svc.ConfirmSubscription(context.Background(), topic, "token-1")
+	assert.NoError(t, err)
+}
+
+func TestApplySubscriptionAttributes_RedrivePolicyRequiresDeliveryPolicy(t *testing.T) {
+	const (
+		redrive  = `{"deadLetterTargetSrn":"srn:scp:sns:kr-west1:acct:orders-dlq"}`
+		delivery = `{"healthyRetryPolicy":{"minDelayTarget":20,"maxDelayTarget":20,"numRetries":3}}`
+	)
+	newCfg := func(protocol string) *jetstream.ConsumerConfig {
+		return &jetstream.ConsumerConfig{MaxDeliver: -1, Metadata: map[string]string{entity.MetaProtocol: protocol}}
+	}
+
+	err := applySubscriptionAttributes(newCfg(entity.ProtocolHTTPS), map[string]string{entity.AttrRedrivePolicy: redrive})
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+
+	cfg := newCfg(entity.ProtocolHTTPS)
+	assert.NoError(t, applySubscriptionAttributes(cfg, map[string]string{entity.AttrRedrivePolicy: redrive, entity.AttrDeliveryPolicy: delivery}))
+	assert.Equal(t, 4, cfg.MaxDeliver)
+
+	// removing the DeliveryPolicy would leave the RedrivePolicy without effect
+	err = applySubscriptionAttributes(cfg, map[string]string{entity.AttrDeliveryPolicy: ""})
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+
+	err = applySubscriptionAttributes(newCfg(entity.ProtocolQueue), map[string]string{entity.AttrRedrivePolicy: redrive})
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+}
+
+func TestSubscribe_Idempotent(t *testing.T) {
+	natsRepo := newFakeSubscriptionRepo()
+	svc := NewSubscriptionService(natsRepo, &config.Config{Region: "kr-west1"})
+	topic := entity.NewTopicSRN("kr-west1", "acct", "orders")
+	input := entity.SubscribeInput{Protocol: entity.ProtocolQueue, Endpoint: "orders-worker"}
+
+	first, err := svc.Subscribe(context.Background(), "acct", topic, input)
+	assert.NoError(t, err)
+	assert.Equal(t, "srn:scp:sns:kr-west1:acct:orders:"+subscriptionID(entity.ProtocolQueue, "orders-worker"), first.SubscriptionSrn)
+	assert.False(t, first.PendingConfirmation)
+
+	second, err := svc.Subscribe(context.Background(), "acct", topic, input)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Len(t, natsRepo.consumers, 1)
+
+	input.Attributes = map[string]string{entity.AttrRawMessageDelivery: "true"}
+	_, err = svc.Subscribe(context.Background(), "acct", topic, input)
+	assert.ErrorIs(t, err, entity.ErrConflict)
+}
+
+func TestSubscribe_ConcurrentSubscribesShareTheConsumer(t *testing.T) {
+	natsRepo := newFakeSubscriptionRepo()
+	svc := NewSubscriptionService(natsRepo, &config.Config{Region: "kr-west1"})
+	topic := entity.NewTopicSRN("kr-west1", "acct", "orders")
+	input := entity.SubscribeInput{Protocol: entity.ProtocolHTTPS, Endpoint: "https://example.com/hook"}
+
+	var wg sync.WaitGroup
+	srns := make([]string, 8)
+	for i := range srns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sub, err := svc.Subscribe(context.Background(), "acct", topic, input)
+			assert.NoError(t, err)
+			srns[i] = sub.SubscriptionSrn
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, natsRepo.creates)
+	assert.Len(t, natsRepo.consumers, 1)
+	for _, srn := range srns {
+		assert.Equal(t, srns[0], srn)
+	}
+}
+
+func TestSubscribe_PendingEndpointGetsNewToken(t *testing.T) {
+	natsRepo := newFakeSubscriptionRepo()
+	svc := NewSubscriptionService(natsRepo, &config.Config{Region: "kr-west1"})
+	topic := entity.NewTopicSRN("kr-west1", "acct", "orders")
+	input := entity.SubscribeInput{Protocol: entity.ProtocolHTTPS, Endpoint: "https://example.com/hook"}
+
+	sub, err := svc.Subscribe(context.Background(), "acct", topic, input)
+	assert.NoError(t, err)
+	assert.True(t, sub.PendingConfirmation)
+	name := subscriptionID(input.Protocol, input.Endpoint)
+	token := natsRepo.consumers[name].Metadata[entity.MetaConfirmToken]
+
+	_, err = svc.Subscribe(context.Background(), "acct", topic, input)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, natsRepo.consumers[name].Metadata[entity.MetaConfirmToken])
+	assert.Len(t, natsRepo.consumers, 1)
+}
+
+func TestSubscribe_FindsSubscriptionWithRandomName(t *testing.T) {
+	natsRepo := newFakeSubscriptionRepo()
+	pendingSubscription(natsRepo, "token-1")
+	svc := NewSubscriptionService(natsRepo, &config.Config{Region: "kr-west1"})
+
+	sub, err := svc.Subscribe(context.Background(), "acct", entity.NewTopicSRN("kr-west1", "acct", "orders"),
+		entity.SubscribeInput{Protocol: entity.ProtocolHTTPS, Endpoint: "https://example.com/hook"})
+	assert.NoError(t, err)
+	assert.Equal(t, "srn:scp:sns:kr-west1:acct:orders:sub-1", sub.SubscriptionSrn)
+	assert.Len(t, natsRepo.consumers, 1)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	natsRepo := newFakeSubscriptionRepo()
+	svc := NewSubscriptionService(natsRepo, &config.Config{Region: "kr-west1"})
+	sub, err := svc.Subscribe(context.Background(), "acct", entity.NewTopicSRN("kr-west1", "acct", "orders"),
+		entity.SubscribeInput{Protocol: entity.ProtocolQueue, Endpoint: "orders-worker"})
+	assert.NoError(t, err)
+	srn, err := entity.ParseSRN(sub.SubscriptionSrn)
+	assert.NoError(t, err)
+
+	err = svc.Unsubscribe(context.Background(), "other", srn)
+	assert.Error(t, err)
+	assert.Len(t, natsRepo.consumers, 1)
+
+	err = svc.Unsubscribe(context.Background(), "acct", entity.NewTopicSRN("kr-west1", "acct", "orders"))
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+
+	assert.NoError(t, svc.Unsubscribe(context.Background(), "acct", srn))
+	assert.Empty(t, natsRepo.consumers)
+
+	err = svc.Unsubscribe(context.Background(), "acct", srn)
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+}
+
+func TestListSubscriptionsByTopic(t *testing.T) {
+	natsRepo := newFakeSubscriptionRepo()
+	svc := NewSubscriptionService(natsRepo, &config.Config{Region: "kr-west1"})
+	topic := entity.NewTopicSRN("kr-west1", "acct", "orders")
+	for _, endpoint := range []string{"orders-worker", "orders-audit"} {
+		_, err := svc.Subscribe(context.Background(), "acct", topic, entity.SubscribeInput{Protocol: entity.ProtocolQueue, Endpoint: endpoint})
+		assert.NoError(t, err)
+	}
+	// consumers not created by subscribe, e.g. pull consumers, are not subscriptions
+	natsRepo.consumers[entity.PullConsumerPrefix+"reader"] = jetstream.ConsumerConfig{Durable: entity.PullConsumerPrefix + "reader"}
+
+	subs, err := svc.ListSubscriptionsByTopic(context.Background(), "acct", topic)
+	assert.NoError(t, err)
+	var endpoints []string
+	for _, sub := range subs {
+		assert.Equal(t, topic.String(), sub.TopicSrn)
+		assert.Equal(t, "acct", sub.Owner)
+		endpoints = append(endpoints, sub.Endpoint)
+	}
+	assert.ElementsMatch(t, []string{"orders-worker", "orders-audit"}, endpoints)
+
+	_, err = svc.ListSubscriptionsByTopic(context.Background(), "other", topic)
+	assert.Error(t, err)
+}
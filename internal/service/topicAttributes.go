@@ -0,0 +1,166 @@
+package service
+
+import (
+	"fmt"
+	"nats/internal/entity"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	maxRetentionPeriod = 365 * 24 * time.Hour
+	maxMessageSize     = 1024 * 1024 // NATS server default max_payload
+	maxReplicas        = 5
+)
+
+// applyTopicAttributes validates the attributes and writes them into the stream config.
+// When creating is false the immutable attributes are rejected.
+func applyTopicAttributes(cfg *jetstream.StreamConfig, attrs map[string]string, creating bool) error {
+	for name, value := range attrs {
+		if entity.ReadOnlyTopicAttributes[name] {
+			return fmt.Errorf("%w: attribute %s is read-only", entity.ErrInvalidParameter, name)
+		}
+		if !creating && entity.ImmutableTopicAttributes[name] {
+			return fmt.Errorf("%w: attribute %s cannot be changed after the topic is created", entity.ErrInvalidParameter, name)
+		}
+		if err := applyTopicAttribute(cfg, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyTopicAttribute(cfg *jetstream.StreamConfig, name, value string) error {
+	switch name {
+	case entity.AttrRetentionPeriod:
+		sec, err := parseRange(name, value, 1, int64(maxRetentionPeriod/time.Second))
+		if err != nil {
+			return err
+		}
+		cfg.MaxAge = time.Duration(sec) * time.Second
+	case entity.AttrMaximumMessageSize:
+		size, err := parseRange(name, value, 1, maxMessageSize)
+		if err != nil {
+			return err
+		}
+		cfg.MaxMsgSize = int32(size)
+	case entity.AttrMaxMessages:
+		n, err := parseLimit(name, value)
+		if err != nil {
+			return err
+		}
+		cfg.MaxMsgs = n
+	case entity.AttrMaxBytes:
+		n, err := parseLimit(name, value)
+		if err != nil {
+			return err
+		}
+		cfg.MaxBytes = n
+	case entity.AttrStorageType:
+		switch strings.ToLower(value) {
+		case "file":
+			cfg.Storage = jetstream.FileStorage
+		case "memory":
+			cfg.Storage = jetstream.MemoryStorage
+		default:
+			return fmt.Errorf("%w: %s must be File or Memory", entity.ErrInvalidParameter, name)
+		}
+	case entity.AttrReplicas:
+		n, err := parseRange(name, value, 1, maxReplicas)
+		if err != nil {
+			return err
+		}
+		cfg.Replicas = int(n)
+	case entity.AttrDiscardPolicy:
+		switch strings.ToLower(value) {
+		case "old":
+			cfg.Discard = jetstream.DiscardOld
+		case "new":
+			cfg.Discard = jetstream.DiscardNew
+		default:
+			return fmt.Errorf("%w: %s must be Old or New", entity.ErrInvalidParameter, name)
+		}
+	case entity.AttrFifoTopic:
+		fifo, err := strconv.ParseBool(value)
+		if err != nil || fifo != (cfg.Metadata[entity.MetaFifo] == "true") {
+			return fmt.Errorf("%w: %s must be true exactly when the topic name ends with %s", entity.ErrInvalidParameter, name, entity.FifoSuffix)
+		}
+	case entity.AttrContentBasedDeduplication:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be true or false", entity.ErrInvalidParameter, name)
+		}
+		if cfg.Metadata[entity.MetaFifo] != "true" {
+			return fmt.Errorf("%w: %s is only supported on FIFO topics", entity.ErrInvalidParameter, name)
+		}
+		cfg.Metadata[entity.MetaContentBasedDeduplication] = strconv.FormatBool(enabled)
+	default:
+		return fmt.Errorf("%w: unknown attribute %s", entity.ErrInvalidParameter, name)
+	}
+	return nil
+}
+
+// topicAttributes reports the attributes of a topic from its stream info
+func topicAttributes(region string, info *jetstream.StreamInfo) map[string]string {
+	cfg := info.Config
+	account := cfg.Metadata[entity.MetaAccount]
+
+	storage := "File"
+	if cfg.Storage == jetstream.MemoryStorage {
+		storage = "Memory"
+	}
+	discard := "Old"
+	if cfg.Discard == jetstream.DiscardNew {
+		discard = "New"
+	}
+
+	attrs := map[string]string{
+		entity.AttrTopicSrn:           entity.NewTopicSRN(region, account, cfg.Metadata[entity.MetaTopic]).String(),
+		entity.AttrOwner:              account,
+		entity.AttrMessages:           strconv.FormatUint(info.State.Msgs, 10),
+		entity.AttrBytes:              strconv.FormatUint(info.State.Bytes, 10),
+		entity.AttrRetentionPeriod:    strconv.FormatInt(int64(cfg.MaxAge/time.Second), 10),
+		entity.AttrMaximumMessageSize: strconv.FormatInt(int64(cfg.MaxMsgSize), 10),
+		entity.AttrMaxMessages:        strconv.FormatInt(cfg.MaxMsgs, 10),
+		entity.AttrMaxBytes:           strconv.FormatInt(cfg.MaxBytes, 10),
+		entity.AttrStorageType:        storage,
+		entity.AttrReplicas:           strconv.Itoa(cfg.Replicas),
+		entity.AttrDiscardPolicy:      discard,
+		entity.AttrFifoTopic:          strconv.FormatBool(cfg.Metadata[entity.MetaFifo] == "true"),
+		entity.AttrSubjects:           strings.Join(topicSubjects(account, cfg.Subjects), ","),
+	}
+	if cfg.Metadata[entity.MetaFifo] == "true" {
+		attrs[entity.AttrContentBasedDeduplication] = strconv.FormatBool(cfg.Metadata[entity.MetaContentBasedDeduplication] == "true")
+	}
+	return attrs
+}
+
+func parseRange(name, value string, min, max int64) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%w: %s must be an integer between %d and %d", entity.ErrInvalidParameter, name, min, max)
+	}
+	return n, nil
+}
+
+// parseLimit parses a stream limit where -1 means unlimited
+func parseLimit(name, value string) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n == 0 || n < -1 {
+		return 0, fmt.Errorf("%w: %s must be -1 (unlimited) or a positive integer", entity.ErrInvalidParameter, name)
+	}
+	return n, nil
+}
+
+// topicSubjects strips the account namespace from the stream subjects
+func topicSubjects(account string, streamSubjects []string) []string {
+	prefix := entity.AccountSubjectPrefix(account)
+	subjects := make([]string, 0, len(streamSubjects))
+	for _, subject := range streamSubjects {
+		subjects = append(subjects, strings.TrimPrefix(subject, prefix))
+	}
+	return subjects
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"nats/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyTopicAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		topic    string
+		attrs    map[string]string
+		creating bool
+		wantErr  bool
+		check    func(t *testing.T, cfg jetstream.StreamConfig)
+	}{
+		{name: "retention period", attrs: map[string]string{entity.AttrRetentionPeriod: "3600"}, check: func(t *testing.T, cfg jetstream.StreamConfig) {
+			assert.Equal(t, time.Hour, cfg.MaxAge)
+		}},
+		{name: "retention period max", attrs: map[string]string{entity.AttrRetentionPeriod: "31536000"}, check: func(t *testing.T, cfg jetstream.StreamConfig) {
+			assert.Equal(t, maxRetentionPeriod, cfg.MaxAge)
+		}},
+		{name: "retention period zero", attrs: map[string]string{entity.AttrRetentionPeriod: "0"}, wantErr: true},
+		{name: "retention period over max", attrs: map[string]string{entity.AttrRetentionPeriod: "31536001"}, wantErr: true},
+		{name: "retention period not a number", attrs: map[string]string{entity.AttrRetentionPeriod: "1h"}, wantErr: true},
+		{name: "message size", attrs: map[string]string{entity.AttrMaximumMessageSize: "1048576"}, check: func(t *testing.T, cfg jetstream.StreamConfig) {
+			assert.EqualValues(t, maxMessageSize, cfg.MaxMsgSize)
+		}},
+		{name: "message size over max_payload", attrs: map[string]string{entity.AttrMaximumMessageSize: "1048577"}, wantErr: true},
+		{name: "message size negative", attrs: map[string]string{entity.AttrMaximumMessageSize: "-1"}, wantErr: true},
+		{name: "max messages unlimited", attrs: map[string]string{entity.AttrMaxMessages: "-1"}, check: func(t *testing.T, cfg jetstream.StreamConfig) {
+			assert.EqualValues(t, -1, cfg.MaxMsgs)
+		}},
+		{name: "max messages", attrs: map[string]string{entity.AttrMaxMessages: "1000"}, check: func(t *testing.T, cfg jetstream.StreamConfig) {
+			assert.EqualValues(t, 1000, cfg.MaxMsgs)
+		}},
+		{name: "max messages zero", attrs: map[string]string{entity.AttrMaxMessages: "0"}, wantErr: true},
+		{name: "max bytes below unlimited", attrs: map[string]string{entity.AttrMaxBytes: "-2"}, wantErr: true},
+		{name: "max bytes not a number", attrs: map[string]string{entity.AttrMaxBytes: "lots"}, wantErr: true},
+		{name: "replicas", attrs: map[string]string{entity.AttrReplicas: "3"}, check: func(t *testing.T, cfg jetstream.StreamConfig) {
+			assert.Equal(t, 3, cfg.Replicas)
+		}},
+		{name: "replicas over max", attrs: map[string]string{entity.AttrReplicas: "6"}, wantErr: true},
+		{name: "discard policy", attrs: map[string]string{entity.AttrDiscardPolicy: "new"}, check: func(t *testing.T, cfg jetstream.StreamConfig) {
+			assert.Equal(t, jetstream.DiscardNew, cfg.Discard)
+		}},
+		{name: "discard policy unknown", attrs: map[string]string{entity.AttrDiscardPolicy: "Oldest"}, wantErr: true},
+		{name: "storage type on create", attrs: map[string]string{entity.AttrStorageType: "Memory"}, creating: true, check: func(t *testing.T, cfg jetstream.StreamConfig) {
+			assert.Equal(t, jetstream.MemoryStorage, cfg.Storage)
+		}},
+		{name: "storage type unknown", attrs: map[string]string{entity.AttrStorageType: "Disk"}, creating: true, wantErr: true},
+		{name: "storage type is immutable", attrs: map[string]string{entity.AttrStorageType: "Memory"}, wantErr: true},
+		{name: "fifo topic is immutable", topic: "orders.fifo", attrs: map[string]string{entity.AttrFifoTopic: "true"}, wantErr: true},
+		{name: "fifo topic on create", topic: "orders.fifo", attrs: map[string]string{entity.AttrFifoTopic: "true"}, creating: true},
+		{name: "fifo topic without suffix", attrs: map[string]string{entity.AttrFifoTopic: "true"}, creating: true, wantErr: true},
+		{name: "content based deduplication", topic: "orders.fifo", attrs: map[string]string{entity.AttrContentBasedDeduplication: "true"}, check: func(t *testing.T, cfg jetstream.StreamConfig) {
+			assert.Equal(t, "true", cfg.Metadata[entity.MetaContentBasedDeduplication])
+		}},
+		{name: "content based deduplication on standard topic", attrs: map[string]string{entity.AttrContentBasedDeduplication: "true"}, wantErr: true},
+		{name: "read-only attribute", attrs: map[string]string{entity.AttrOwner: "other"}, creating: true, wantErr: true},
+		{name: "unknown attribute", attrs: map[string]string{"DisplayName": "orders"}, creating: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topic := tt.topic
+			if topic == "" {
+				topic = "orders"
+			}
+			cfg := newStreamConfig("acct", topic)
+			err := applyTopicAttributes(&cfg, tt.attrs, tt.creating)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+				return
+			}
+			if assert.NoError(t, err) && tt.check != nil {
+				tt.check(t, cfg)
+			}
+		})
+	}
+}
+
+func TestTopicAttributes_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newFakeStreamRepo()
+	svc, _ := newTestTopicService(natsRepo)
+	srn := entity.NewTopicSRN("local", "acct", "orders.fifo")
+
+	_, err := svc.CreateTopic(ctx, "acct", entity.CreateTopicInput{
+		Name:     "orders.fifo",
+		Subjects: []string{"orders.fifo.>"},
+		Attributes: map[string]string{
+			entity.AttrRetentionPeriod: "7200",
+			entity.AttrStorageType:     "Memory",
+			entity.AttrReplicas:        "3",
+		},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, svc.SetTopicAttributes(ctx, "acct", srn, entity.AttrMaxMessages, "500"))
+	assert.NoError(t, svc.SetTopicAttributes(ctx, "acct", srn, entity.AttrContentBasedDeduplication, "true"))
+
+	cfg := natsRepo.streams[entity.StreamName("acct", "orders.fifo")]
+	assert.Equal(t, 2*time.Hour, cfg.MaxAge)
+	assert.Equal(t, jetstream.MemoryStorage, cfg.Storage)
+	assert.EqualValues(t, 500, cfg.MaxMsgs)
+	assert.Equal(t, "true", cfg.Metadata[entity.MetaContentBasedDeduplication])
+
+	attrs, err := svc.GetTopicAttributes(ctx, "acct", srn)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		entity.AttrTopicSrn:                  srn.String(),
+		entity.AttrOwner:                     "acct",
+		entity.AttrMessages:                  "0",
+		entity.AttrBytes:                     "0",
+		entity.AttrRetentionPeriod:           "7200",
+		entity.AttrMaximumMessageSize:        "262144",
+		entity.AttrMaxMessages:               "500",
+		entity.AttrMaxBytes:                  "-1",
+		entity.AttrStorageType:               "Memory",
+		entity.AttrReplicas:                  "3",
+		entity.AttrDiscardPolicy:             "Old",
+		entity.AttrFifoTopic:                 "true",
+		entity.AttrContentBasedDeduplication: "true",
+		entity.AttrSubjects:                  "orders.fifo,orders.fifo.>",
+	}, attrs)
+
+	// immutable and invalid values leave the stored config untouched
+	assert.ErrorIs(t, svc.SetTopicAttributes(ctx, "acct", srn, entity.AttrStorageType, "File"), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, svc.SetTopicAttributes(ctx, "acct", srn, entity.AttrMaxMessages, "0"), entity.ErrInvalidParameter)
+	assert.Equal(t, cfg, natsRepo.streams[entity.StreamName("acct", "orders.fifo")])
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// TopicCache keeps recently resolved topic streams so that the publish path
+// does not pay a JetStream API round trip for every message.
+// Lookup failures are not cached, so a newly created topic is usable immediately.
+// The topic service evicts a topic when it deletes or changes it; other
+// instances keep their entry until it expires.
+type TopicCache struct {
+	natsRepo repo.NatsRepo
+	ttl      time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]topicCacheEntry
+}
+
+type topicCacheEntry struct {
+	info    *jetstream.StreamInfo
+	expires time.Time
+}
+
+func NewTopicCache(natsRepo repo.NatsRepo, ttl time.Duration) *TopicCache {
+	return &TopicCache{
+		natsRepo: natsRepo,
+		ttl:      ttl,
+		entries:  make(map[string]topicCacheEntry),
+	}
+}
+
+// get returns the stream info of the account's topic
+func (c *TopicCache) get(ctx context.Context, account, name string) (*jetstream.StreamInfo, error) {
+	key := entity.StreamName(account, name)
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.info, nil
+	}
+
+	info, err := findTopicStream(ctx, c.natsRepo, account, name)
+	if err != nil {
+		c.evict(account, name)
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = topicCacheEntry{info: info, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return info, nil
+}
+
+// evict drops the cached stream info of the account's topic
+func (c *TopicCache) evict(account, name string) {
+	c.mu.Lock()
+	delete(c.entries, entity.StreamName(account, name))
+	c.mu.Unlock()
+}
+
+// evictIfStreamGone drops the topic when a publish error shows that its stream no longer exists
+func (c *TopicCache) evictIfStreamGone(account, name string, err error) {
+	if errors.Is(err, jetstream.ErrStreamNotFound) || errors.Is(err, jetstream.ErrNoStreamResponse) {
+		c.evict(account, name)
+	}
+}
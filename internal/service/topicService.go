@@ -2,63 +2,378 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"maps"
 	"nats/internal/context/traces"
 	"nats/internal/entity"
 	"nats/internal/repo"
 	"nats/pkg/config"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
 )
 
 type TopicService interface {
-	CreateTopic(ctx context.Context, name, account string) (entity.Topic, error)
-	DeleteTopic(ctx context.Context, name string) error
-	ListTopics(ctx context.Context, account string) ([]entity.Topic, error)
+	CreateTopic(ctx context.Context, account string, input entity.CreateTopicInput) (entity.Topic, error)
+	DeleteTopic(ctx context.Context, account string, topic entity.SRN) error
+	ListTopics(ctx context.Context, account string, filter entity.TopicFilter, nextToken string, maxResults int) ([]entity.Topic, string, error)
+	GetTopicAttributes(ctx context.Context, account string, topic entity.SRN) (map[string]string, error)
+	SetTopicAttributes(ctx context.Context, account string, topic entity.SRN, attrName, attrValue string) error
+
+	TagResource(ctx context.Context, account string, resource entity.SRN, tags []entity.Tag) error
+	UntagResource(ctx context.Context, account string, resource entity.SRN, tagKeys []string) error
+	ListTagsForResource(ctx context.Context, account string, resource entity.SRN) ([]entity.Tag, error)
 }
 
+// errStreamSubjectOverlap matches the JetStream error returned when the subjects
+// of a new stream overlap with an existing stream
+var errStreamSubjectOverlap = &jetstream.APIError{ErrorCode: 10065}
+
 type topicService struct {
 	natsRepo repo.NatsRepo
+	topics   *TopicCache
 	cfg      *config.Config
 }
 
-func NewTopicService(natsRepo repo.NatsRepo, cfg *config.Config) TopicService {
-	return &topicService{natsRepo: natsRepo, cfg: cfg}
+// NewTopicService creates the topic service. Topics are evicted from the cache
+// shared with the publish service whenever they are deleted or changed.
+func NewTopicService(natsRepo repo.NatsRepo, topics *TopicCache, cfg *config.Config) TopicService {
+	return &topicService{natsRepo: natsRepo, topics: topics, cfg: cfg}
 }
 
-func (s *topicService) CreateTopic(ctx context.Context, name, account string) (entity.Topic, error) {
-	_, err := s.natsRepo.CreateStream(ctx, name)
-	topic := makeTopicSrn(s.cfg.Region, account, name)
-	return topic, err
+func (s *topicService) CreateTopic(ctx context.Context, account string, input entity.CreateTopicInput) (entity.Topic, error) {
+	if err := entity.ValidateAccountID(account); err != nil {
+		return entity.Topic{}, err
+	}
+	if err := entity.ValidateTopicName(input.Name); err != nil {
+		return entity.Topic{}, err
+	}
+
+	streamCfg := newStreamConfig(account, input.Name)
+	if err := applyTopicSubjects(&streamCfg, account, input.Name, input.Subjects); err != nil {
+		return entity.Topic{}, err
+	}
+	if err := applyTopicAttributes(&streamCfg, input.Attributes, true); err != nil {
+		return entity.Topic{}, err
+	}
+	if err := applyTags(streamCfg.Metadata, input.Tags); err != nil {
+		return entity.Topic{}, err
+	}
+
+	topic := newTopic(s.cfg.Region, account, input.Name)
+
+	// Re-creating a topic with identical attributes returns the existing topic.
+	info, err := s.natsRepo.GetStreamInfo(ctx, streamCfg.Name)
+	if err == nil {
+		return topic, checkSameTopic(streamCfg, info.Config, len(input.Tags) > 0)
+	}
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return entity.Topic{}, err
+	}
+
+	_, err = s.natsRepo.CreateStream(ctx, streamCfg)
+	if errors.Is(err, errStreamSubjectOverlap) {
+		return entity.Topic{}, fmt.Errorf("%w: subjects of topic %s overlap with another topic", entity.ErrConflict, input.Name)
+	}
+	if errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		// lost a race with a concurrent createTopic of the same name
+		info, err := s.natsRepo.GetStreamInfo(ctx, streamCfg.Name)
+		if err != nil {
+			return entity.Topic{}, err
+		}
+		return topic, checkSameTopic(streamCfg, info.Config, len(input.Tags) > 0)
+	}
+	if err != nil {
+		return entity.Topic{}, err
+	}
+	return topic, nil
 }
 
-func (s *topicService) DeleteTopic(ctx context.Context, name string) error {
-	return s.natsRepo.DeleteStream(ctx, name)
+func (s *topicService) DeleteTopic(ctx context.Context, account string, topic entity.SRN) error {
+	if _, err := s.ownedStream(ctx, account, topic); err != nil {
+		return err
+	}
+	defer s.topics.evict(account, topic.Topic)
+	return s.natsRepo.DeleteStream(ctx, entity.StreamName(account, topic.Topic))
 }
 
-func (s *topicService) ListTopics(ctx context.Context, account string) ([]entity.Topic, error) {
+func (s *topicService) ListTopics(ctx context.Context, account string, filter entity.TopicFilter, nextToken string, maxResults int) ([]entity.Topic, string, error) {
 	ctx, span := traces.StartSpan(ctx, "listTopics")
 	defer span.End()
 
-	namesCh, err := s.natsRepo.ListStreamNames(ctx)
+	if err := entity.ValidateAccountID(account); err != nil {
+		return nil, "", err
+	}
+	maxResults, err := normalizeMaxResults(maxResults)
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := decodePageToken(nextToken, pageScope("listTopics", account, filter.TagKey, filter.TagValue))
+	if err != nil {
+		return nil, "", err
+	}
+
+	subject := entity.AccountSubjectPrefix(account) + ">"
+	offset := token.Offset
+	anchored := token.After == "" // the listing has stepped back to the token position
+	topics := make([]entity.Topic, 0, maxResults)
+	for {
+		page, err := s.natsRepo.ListStreamsPage(ctx, subject, offset)
+		if err != nil {
+			traces.RecordSpanError(ctx, span, "natsRepo.ListStreamsPage error", err)
+			return nil, "", err
+		}
+
+		// streams before the token position were deleted: step back until the anchor is in range
+		if !anchored && offset > 0 && len(page.Streams) > 0 && page.Streams[0].Config.Name > token.After {
+			offset = max(0, offset-max(page.Limit, 1))
+			continue
+		}
+		anchored = true
+
+		for i, info := range page.Streams {
+			if info.Config.Name <= token.After {
+				continue
+			}
+			if info.Config.Metadata[entity.MetaAccount] != account || !filter.Matches(info.Config.Metadata) {
+				continue
+			}
+			topics = append(topics, newTopic(s.cfg.Region, account, info.Config.Metadata[entity.MetaTopic]))
+
+			if len(topics) == maxResults {
+				next := page.Offset + i + 1
+				if next >= page.Total {
+					return topics, "", nil
+				}
+				return topics, encodePageToken(pageToken{Offset: next, After: info.Config.Name, Scope: token.Scope}), nil
+			}
+		}
+
+		offset = page.Offset + len(page.Streams)
+		if len(page.Streams) == 0 || offset >= page.Total {
+			return topics, "", nil
+		}
+	}
+}
+
+func (s *topicService) GetTopicAttributes(ctx context.Context, account string, topic entity.SRN) (map[string]string, error) {
+	info, err := s.ownedStream(ctx, account, topic)
+	if err != nil {
+		return nil, err
+	}
+	return topicAttributes(s.cfg.Region, info), nil
+}
+
+func (s *topicService) SetTopicAttributes(ctx context.Context, account string, topic entity.SRN, attrName, attrValue string) error {
+	info, err := s.ownedStream(ctx, account, topic)
+	if err != nil {
+		return err
+	}
+
+	streamCfg := info.Config
+	streamCfg.Metadata = maps.Clone(streamCfg.Metadata)
+	if err := applyTopicAttributes(&streamCfg, map[string]string{attrName: attrValue}, false); err != nil {
+		return err
+	}
+	return s.updateStream(ctx, account, topic.Topic, streamCfg)
+}
+
+func (s *topicService) TagResource(ctx context.Context, account string, resource entity.SRN, tags []entity.Tag) error {
+	info, err := s.ownedStream(ctx, account, resource)
+	if err != nil {
+		return err
+	}
+
+	streamCfg := info.Config
+	streamCfg.Metadata = maps.Clone(streamCfg.Metadata)
+	if err := applyTags(streamCfg.Metadata, tags); err != nil {
+		return err
+	}
+	return s.updateStream(ctx, account, resource.Topic, streamCfg)
+}
+
+func (s *topicService) UntagResource(ctx context.Context, account string, resource entity.SRN, tagKeys []string) error {
+	for _, key := range tagKeys {
+		if err := entity.ValidateTagKey(key); err != nil {
+			return err
+		}
+	}
+
+	info, err := s.ownedStream(ctx, account, resource)
+	if err != nil {
+		return err
+	}
+
+	streamCfg := info.Config
+	streamCfg.Metadata = maps.Clone(streamCfg.Metadata)
+	for _, key := range tagKeys {
+		delete(streamCfg.Metadata, entity.MetaTagPrefix+key)
+	}
+	return s.updateStream(ctx, account, resource.Topic, streamCfg)
+}
+
+func (s *topicService) ListTagsForResource(ctx context.Context, account string, resource entity.SRN) ([]entity.Tag, error) {
+	info, err := s.ownedStream(ctx, account, resource)
 	if err != nil {
-		traces.RecordSpanError(ctx, span, "natsRepo.ListStreamNames error", err)
 		return nil, err
 	}
+	return entity.TagsFromMetadata(info.Config.Metadata), nil
+}
+
+// updateStream stores the changed stream config of the topic and evicts the cached one
+func (s *topicService) updateStream(ctx context.Context, account, name string, cfg jetstream.StreamConfig) error {
+	defer s.topics.evict(account, name)
+	_, err := s.natsRepo.UpdateStream(ctx, cfg)
+	return err
+}
 
-	var topics []entity.Topic
-	for name := range namesCh {
-		topics = append(topics, makeTopicSrn(s.cfg.Region, account, name))
+// applyTopicSubjects validates the subject patterns requested for the topic and
+// maps them into the account namespace. The topic name itself, the default publish
+// subject, is always owned.
+func applyTopicSubjects(cfg *jetstream.StreamConfig, account, name string, subjects []string) error {
+	if len(subjects) == 0 {
+		return nil
+	}
+	if len(subjects) > entity.MaxTopicSubjects {
+		return fmt.Errorf("%w: a topic can own at most %d subjects", entity.ErrInvalidParameter, entity.MaxTopicSubjects)
+	}
+
+	cfg.Subjects = make([]string, 0, len(subjects)+1)
+	if !slices.Contains(subjects, name) {
+		cfg.Subjects = append(cfg.Subjects, entity.AccountSubject(account, name))
+	}
+	for _, subject := range subjects {
+		if err := entity.ValidateTopicSubject(name, subject); err != nil {
+			return err
+		}
+		cfg.Subjects = append(cfg.Subjects, entity.AccountSubject(account, subject))
+	}
+	return nil
+}
+
+// checkSameTopic compares the requested stream config with the one of an existing topic.
+// Tags are only compared when the request carries tags, as SNS does.
+func checkSameTopic(want, have jetstream.StreamConfig, compareTags bool) error {
+	same := slices.Equal(want.Subjects, have.Subjects) &&
+		want.Storage == have.Storage &&
+		want.Replicas == have.Replicas &&
+		want.Retention == have.Retention &&
+		want.Discard == have.Discard &&
+		want.MaxMsgs == have.MaxMsgs &&
+		want.MaxBytes == have.MaxBytes &&
+		want.MaxAge == have.MaxAge &&
+		want.MaxMsgSize == have.MaxMsgSize &&
+		(want.Duplicates == 0 || want.Duplicates == have.Duplicates) &&
+		maps.Equal(topicMetadata(want.Metadata, compareTags), topicMetadata(have.Metadata, compareTags))
+	if !same {
+		return fmt.Errorf("%w: topic %s already exists with different attributes", entity.ErrConflict, want.Metadata[entity.MetaTopic])
+	}
+	return nil
+}
+
+// topicMetadata returns the metadata entries owned by this service, leaving out
+// the ones added by the NATS server and optionally the tags
+func topicMetadata(metadata map[string]string, withTags bool) map[string]string {
+	out := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if strings.HasPrefix(k, "_nats.") || (!withTags && strings.HasPrefix(k, entity.MetaTagPrefix)) {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// applyTags validates the tags and stores them in the stream metadata,
+// keeping the total number of tags within the per-resource limit
+func applyTags(metadata map[string]string, tags []entity.Tag) error {
+	if err := entity.ValidateTags(tags); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		metadata[entity.MetaTagPrefix+tag.Key] = tag.Value
+	}
+	if n := len(entity.TagsFromMetadata(metadata)); n > entity.MaxTagsPerResource {
+		return fmt.Errorf("%w: a topic can have at most %d tags", entity.ErrInvalidParameter, entity.MaxTagsPerResource)
+	}
+	return nil
+}
+
+// ownedStream checks that the topic SRN belongs to this region and the account,
+// then looks up the stream backing the topic
+func (s *topicService) ownedStream(ctx context.Context, account string, topic entity.SRN) (*jetstream.StreamInfo, error) {
+	return findOwnedTopicStream(ctx, s.natsRepo, s.cfg.Region, account, topic)
+}
+
+// findOwnedTopicStream checks that the topic SRN belongs to the region and the account,
+// then returns the stream info of the topic
+func findOwnedTopicStream(ctx context.Context, natsRepo repo.NatsRepo, region, account string, topic entity.SRN) (*jetstream.StreamInfo, error) {
+	if topic.IsSubscription() {
+		return nil, fmt.Errorf("%w: %s is not a topic srn", entity.ErrInvalidParameter, topic)
+	}
+	if err := topic.CheckOwner(region, account); err != nil {
+		return nil, err
+	}
+	return findTopicStream(ctx, natsRepo, account, topic.Topic)
+}
+
+// findTopicStream returns the stream info of the account's topic, or ErrNotFound / ErrAuthorization
+func findTopicStream(ctx context.Context, natsRepo repo.NatsRepo, account, name string) (*jetstream.StreamInfo, error) {
+	if err := entity.ValidateAccountID(account); err != nil {
+		return nil, err
+	}
+	if err := entity.ValidateTopicName(name); err != nil {
+		return nil, err
+	}
+
+	info, err := natsRepo.GetStreamInfo(ctx, entity.StreamName(account, name))
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		return nil, fmt.Errorf("%w: topic %s", entity.ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Config.Metadata[entity.MetaAccount] != account || info.Config.Metadata[entity.MetaTopic] != name {
+		return nil, fmt.Errorf("%w: topic %s is not owned by account %s", entity.ErrAuthorization, name, account)
+	}
+	return info, nil
+}
+
+// newStreamConfig builds the stream configuration for a new topic owned by the account.
+// FIFO topics keep a deduplication window so that Nats-Msg-Id drops repeated publishes.
+func newStreamConfig(account, name string) jetstream.StreamConfig {
+	cfg := jetstream.StreamConfig{
+		Name:              entity.StreamName(account, name),
+		Subjects:          []string{entity.AccountSubject(account, name)},
+		Storage:           jetstream.FileStorage,
+		Replicas:          1,
+		Retention:         jetstream.LimitsPolicy,
+		Discard:           jetstream.DiscardOld,
+		MaxMsgs:           -1,
+		MaxMsgsPerSubject: -1,
+		MaxBytes:          -1,
+		MaxAge:            96 * time.Hour,
+		MaxMsgSize:        262144,
+		Duplicates:        0,
+		AllowRollup:       false,
+		DenyDelete:        false,
+		DenyPurge:         false,
+		Metadata: map[string]string{
+			entity.MetaAccount: account,
+			entity.MetaTopic:   name,
+		},
+	}
+	if entity.IsFifoTopic(name) {
+		cfg.Duplicates = entity.FifoDeduplicationWindow
+		cfg.Metadata[entity.MetaFifo] = "true"
+		cfg.Metadata[entity.MetaContentBasedDeduplication] = "false"
 	}
-	return topics, nil
+	return cfg
 }
 
-func makeTopicSrn(region, account, name string) entity.Topic {
-	var sb strings.Builder
-	sb.Grow(len("srn:scp:sns:::") + len(region) + len(account) + len(name))
-	sb.WriteString("srn:scp:sns:")
-	sb.WriteString(region)
-	sb.WriteByte(':')
-	sb.WriteString(account)
-	sb.WriteByte(':')
-	sb.WriteString(name)
-	return entity.Topic{TopicSrn: sb.String()}
+func newTopic(region, account, name string) entity.Topic {
+	return entity.Topic{TopicSrn: entity.NewTopicSRN(region, account, name).String()}
 }
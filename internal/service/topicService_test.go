@@ -0,0 +1,478 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"nats/internal/entity"
+	"nats/internal/repo"
+	"nats/pkg/config"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStreamRepo keeps the topic streams by name and lists them in pages of pageLimit
+type fakeStreamRepo struct {
+	repo.NatsRepo
+	streams   map[string]jetstream.StreamConfig
+	pageLimit int
+	lookups   int
+}
+
+func newFakeStreamRepo() *fakeStreamRepo {
+	return &fakeStreamRepo{streams: map[string]jetstream.StreamConfig{}, pageLimit: 256}
+}
+
+func (r *fakeStreamRepo) GetStreamInfo(_ context.Context, name string) (*jetstream.StreamInfo, error) {
+	r.lookups++
+	cfg, ok := r.streams[name]
+	if !ok {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	cfg.Metadata = maps.Clone(cfg.Metadata)
+	return &jetstream.StreamInfo{Config: cfg}, nil
+}
+
+func (r *fakeStreamRepo) CreateStream(_ context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	if _, ok := r.streams[cfg.Name]; ok {
+		return nil, jetstream.ErrStreamNameAlreadyInUse
+	}
+	r.streams[cfg.Name] = cfg
+	return nil, nil
+}
+
+func (r *fakeStreamRepo) UpdateStream(_ context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	if _, ok := r.streams[cfg.Name]; !ok {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	r.streams[cfg.Name] = cfg
+	return nil, nil
+}
+
+func (r *fakeStreamRepo) DeleteStream(_ context.Context, name string) error {
+	if _, ok := r.streams[name]; !ok {
+		return jetstream.ErrStreamNotFound
+	}
+	delete(r.streams, name)
+	return nil
+}
+
+// ListStreamsPage lists the streams with a subject under the filter prefix ordered by name, like STREAM.LIST
+func (r *fakeStreamRepo) ListStreamsPage(_ context.Context, subject string, offset int) (repo.StreamPage, error) {
+	prefix := strings.TrimSuffix(subject, ">")
+	var names []string
+	for name, cfg := range r.streams {
+		if slices.ContainsFunc(cfg.Subjects, func(s string) bool { return strings.HasPrefix(s, prefix) }) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	page := repo.StreamPage{Total: len(names), Offset: offset, Limit: r.pageLimit}
+	for _, name := range names[min(offset, len(names)):min(offset+r.pageLimit, len(names))] {
+		page.Streams = append(page.Streams, &jetstream.StreamInfo{Config: r.streams[name]})
+	}
+	return page, nil
+}
+
+// createTopics creates the topics of the account with the default attributes
+func createTopics(t *testing.T, svc TopicService, account string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if _, err := svc.CreateTopic(context.Background(), account, entity.CreateTopicInput{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newTestTopicService(natsRepo repo.NatsRepo) (TopicService, *TopicCache) {
+	topics := NewTopicCache(natsRepo, time.Minute)
+	return NewTopicService(natsRepo, topics, &config.Config{Region: "local"}), topics
+}
+
+func TestCreateTopic_FifoAndStandardNamesDoNotShareStream(t *testing.T) {
+	orders := [][]string{
+		{"orders.fifo", "orders_fifo"},
+		{"orders_fifo", "orders.fifo"},
+	}
+	for _, names := range orders {
+		natsRepo := newFakeStreamRepo()
+		svc, _ := newTestTopicService(natsRepo)
+
+		for _, name := range names {
+			_, err := svc.CreateTopic(context.Background(), "acct", entity.CreateTopicInput{Name: name})
+			if name == "orders_fifo" {
+				assert.ErrorIs(t, err, entity.ErrInvalidParameter, "%v: %s", names, name)
+			} else {
+				assert.NoError(t, err, "%v: %s", names, name)
+			}
+		}
+
+		info, err := findTopicStream(context.Background(), natsRepo, "acct", "orders.fifo")
+		if assert.NoError(t, err, names) {
+			assert.Equal(t, "true", info.Config.Metadata[entity.MetaFifo])
+		}
+		assert.Len(t, natsRepo.streams, 1)
+	}
+}
+
+func TestTopicCache_EvictedWhenTopicChanges(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newFakeStreamRepo()
+	svc, topics := newTestTopicService(natsRepo)
+	createTopics(t, svc, "acct", "orders")
+	srn := entity.NewTopicSRN("local", "acct", "orders")
+
+	info, err := topics.get(ctx, "acct", "orders")
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 262144, info.Config.MaxMsgSize)
+	}
+
+	assert.NoError(t, svc.SetTopicAttributes(ctx, "acct", srn, entity.AttrMaximumMessageSize, "1024"))
+	info, err = topics.get(ctx, "acct", "orders")
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 1024, info.Config.MaxMsgSize)
+	}
+
+	assert.NoError(t, svc.TagResource(ctx, "acct", srn, []entity.Tag{{Key: "team", Value: "payments"}}))
+	info, err = topics.get(ctx, "acct", "orders")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "payments", info.Config.Metadata[entity.MetaTagPrefix+"team"])
+	}
+
+	assert.NoError(t, svc.UntagResource(ctx, "acct", srn, []string{"team"}))
+	info, err = topics.get(ctx, "acct", "orders")
+	if assert.NoError(t, err) {
+		assert.NotContains(t, info.Config.Metadata, entity.MetaTagPrefix+"team")
+	}
+
+	assert.NoError(t, svc.DeleteTopic(ctx, "acct", srn))
+	_, err = topics.get(ctx, "acct", "orders")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+}
+
+func TestTopicCache_EvictedWhenStreamIsGone(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newFakeStreamRepo()
+	svc, topics := newTestTopicService(natsRepo)
+	createTopics(t, svc, "acct", "orders")
+
+	_, err := topics.get(ctx, "acct", "orders")
+	assert.NoError(t, err)
+	_, err = topics.get(ctx, "acct", "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, natsRepo.lookups, "the second get is served from the cache")
+
+	// deleted by another instance: the publish error evicts the topic
+	delete(natsRepo.streams, entity.StreamName("acct", "orders"))
+	topics.evictIfStreamGone("acct", "orders", assert.AnError)
+	_, err = topics.get(ctx, "acct", "orders")
+	assert.NoError(t, err, "other publish errors keep the entry")
+
+	topics.evictIfStreamGone("acct", "orders", jetstream.ErrNoStreamResponse)
+	_, err = topics.get(ctx, "acct", "orders")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+}
+
+func TestFindTopicStream_AccountIsolation(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newFakeStreamRepo()
+	svc, _ := newTestTopicService(natsRepo)
+	createTopics(t, svc, "acct", "orders")
+
+	_, err := findTopicStream(ctx, natsRepo, "acct", "orders")
+	assert.NoError(t, err)
+
+	// the stream name of another account's topic never resolves to this one
+	_, err = findTopicStream(ctx, natsRepo, "other", "orders")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+
+	// a stream whose metadata names another owner is not handed out
+	cfg := natsRepo.streams["acct_orders"]
+	cfg.Metadata = map[string]string{entity.MetaAccount: "other", entity.MetaTopic: "orders"}
+	natsRepo.streams["acct_orders"] = cfg
+	_, err = findTopicStream(ctx, natsRepo, "acct", "orders")
+	assert.ErrorIs(t, err, entity.ErrAuthorization)
+
+	// an SRN of another account is rejected before the lookup
+	_, err = findOwnedTopicStream(ctx, natsRepo, "local", "acct", entity.NewTopicSRN("local", "other", "orders"))
+	assert.ErrorIs(t, err, entity.ErrAuthorization)
+	_, err = findOwnedTopicStream(ctx, natsRepo, "local", "acct", entity.NewTopicSRN("remote", "acct", "orders"))
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+}
+
+func TestListTopics_OnlyAccountTopics(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newFakeStreamRepo()
+	svc, _ := newTestTopicService(natsRepo)
+	createTopics(t, svc, "acct", "orders", "payments")
+	createTopics(t, svc, "other", "orders", "users")
+
+	// a stream capturing the account's subjects but owned by another account is left out
+	natsRepo.streams["rogue"] = jetstream.StreamConfig{
+		Name:     "rogue",
+		Subjects: []string{entity.AccountSubject("acct", "rogue")},
+		Metadata: map[string]string{entity.MetaAccount: "other", entity.MetaTopic: "rogue"},
+	}
+
+	topics, next, err := svc.ListTopics(ctx, "acct", entity.TopicFilter{}, "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+	assert.Equal(t, []entity.Topic{
+		{TopicSrn: "srn:scp:sns:local:acct:orders"},
+		{TopicSrn: "srn:scp:sns:local:acct:payments"},
+	}, topics)
+}
+
+func TestTagResource(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newFakeStreamRepo()
+	svc, _ := newTestTopicService(natsRepo)
+	createTopics(t, svc, "acct", "orders")
+	srn := entity.NewTopicSRN("local", "acct", "orders")
+
+	assert.NoError(t, svc.TagResource(ctx, "acct", srn, []entity.Tag{{Key: "team", Value: "payments"}, {Key: "sns.account", Value: "other"}}))
+	tags, err := svc.ListTagsForResource(ctx, "acct", srn)
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Tag{{Key: "sns.account", Value: "other"}, {Key: "team", Value: "payments"}}, tags)
+
+	// tags live under the sns.tag. prefix and never change the ownership metadata
+	cfg := natsRepo.streams["acct_orders"]
+	assert.Equal(t, "acct", cfg.Metadata[entity.MetaAccount])
+	assert.Equal(t, "other", cfg.Metadata[entity.MetaTagPrefix+entity.MetaAccount])
+
+	// the tag count limit counts the tags already on the topic
+	var tooMany []entity.Tag
+	for i := range entity.MaxTagsPerResource - 1 {
+		tooMany = append(tooMany, entity.Tag{Key: "key" + strconv.Itoa(i)})
+	}
+	assert.ErrorIs(t, svc.TagResource(ctx, "acct", srn, tooMany), entity.ErrInvalidParameter)
+	assert.NoError(t, svc.TagResource(ctx, "acct", srn, tooMany[:entity.MaxTagsPerResource-2]))
+
+	assert.ErrorIs(t, svc.TagResource(ctx, "acct", srn, []entity.Tag{{Key: strings.Repeat("k", entity.MaxTagKeyLength+1)}}), entity.ErrInvalidParameter)
+	assert.ErrorIs(t, svc.UntagResource(ctx, "acct", srn, []string{"scp:owner"}), entity.ErrInvalidParameter)
+
+	assert.NoError(t, svc.UntagResource(ctx, "acct", srn, []string{"sns.account", "missing"}))
+	tags, err = svc.ListTagsForResource(ctx, "acct", srn)
+	assert.NoError(t, err)
+	assert.Len(t, tags, entity.MaxTagsPerResource-1)
+	assert.Equal(t, "acct", natsRepo.streams["acct_orders"].Metadata[entity.MetaAccount])
+}
+
+func TestTagResource_NotOwned(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newFakeStreamRepo()
+	svc, _ := newTestTopicService(natsRepo)
+	createTopics(t, svc, "other", "orders")
+	assert.NoError(t, svc.TagResource(ctx, "other", entity.NewTopicSRN("local", "other", "orders"), []entity.Tag{{Key: "team", Value: "payments"}}))
+	before := natsRepo.streams["other_orders"]
+
+	srn := entity.NewTopicSRN("local", "other", "orders")
+	assert.ErrorIs(t, svc.TagResource(ctx, "acct", srn, []entity.Tag{{Key: "team", Value: "orders"}}), entity.ErrAuthorization)
+	assert.ErrorIs(t, svc.UntagResource(ctx, "acct", srn, []string{"team"}), entity.ErrAuthorization)
+	_, err := svc.ListTagsForResource(ctx, "acct", srn)
+	assert.ErrorIs(t, err, entity.ErrAuthorization)
+
+	// the account's own SRN for a topic it does not have
+	assert.ErrorIs(t, svc.TagResource(ctx, "acct", entity.NewTopicSRN("local", "acct", "orders"), []entity.Tag{{Key: "team"}}), entity.ErrNotFound)
+	assert.Equal(t, before, natsRepo.streams["other_orders"])
+}
+
+func TestListTopics_TagFilter(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newFakeStreamRepo()
+	svc, _ := newTestTopicService(natsRepo)
+	for name, team := range map[string]string{"orders": "payments", "refunds": "payments", "users": "identity"} {
+		_, err := svc.CreateTopic(ctx, "acct", entity.CreateTopicInput{Name: name, Tags: []entity.Tag{{Key: "team", Value: team}}})
+		assert.NoError(t, err)
+	}
+	createTopics(t, svc, "acct", "untagged")
+	_, err := svc.CreateTopic(ctx, "other", entity.CreateTopicInput{Name: "ledger", Tags: []entity.Tag{{Key: "team", Value: "payments"}}})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		filter entity.TopicFilter
+		want   []string
+	}{
+		{filter: entity.TopicFilter{TagKey: "team", TagValue: "payments"}, want: []string{"orders", "refunds"}},
+		{filter: entity.TopicFilter{TagKey: "team"}, want: []string{"orders", "refunds", "users"}},
+		{filter: entity.TopicFilter{TagKey: "env"}, want: nil},
+		{filter: entity.TopicFilter{}, want: []string{"orders", "refunds", "untagged", "users"}},
+	}
+	for _, tt := range tests {
+		topics, _, err := svc.ListTopics(ctx, "acct", tt.filter, "", 0)
+		assert.NoError(t, err, tt.filter)
+		var names []string
+		for _, topic := range topics {
+			srn, _ := entity.ParseSRN(topic.TopicSrn)
+			names = append(names, srn.Topic)
+		}
+		assert.Equal(t, tt.want, names, tt.filter)
+	}
+}
+
+func TestCreateTopic_Idempotent(t *testing.T) {
+	base := entity.CreateTopicInput{
+		Name:       "orders",
+		Subjects:   []string{"orders", "orders.>"},
+		Attributes: map[string]string{entity.AttrRetentionPeriod: "3600", entity.AttrMaximumMessageSize: "1024"},
+		Tags:       []entity.Tag{{Key: "team", Value: "payments"}},
+	}
+	tests := []struct {
+		name    string
+		change  func(in *entity.CreateTopicInput)
+		wantErr bool
+	}{
+		{name: "identical", change: func(*entity.CreateTopicInput) {}},
+		{name: "without tags", change: func(in *entity.CreateTopicInput) { in.Tags = nil }},
+		{name: "changed MaxAge", change: func(in *entity.CreateTopicInput) {
+			in.Attributes = map[string]string{entity.AttrRetentionPeriod: "7200", entity.AttrMaximumMessageSize: "1024"}
+		}, wantErr: true},
+		{name: "changed MaxMsgSize", change: func(in *entity.CreateTopicInput) {
+			in.Attributes = map[string]string{entity.AttrRetentionPeriod: "3600", entity.AttrMaximumMessageSize: "2048"}
+		}, wantErr: true},
+		{name: "default attributes", change: func(in *entity.CreateTopicInput) { in.Attributes = nil }, wantErr: true},
+		{name: "default subject implied", change: func(in *entity.CreateTopicInput) { in.Subjects = []string{"orders.>"} }},
+		{name: "changed subjects", change: func(in *entity.CreateTopicInput) { in.Subjects = []string{"orders", "orders.eu.>"} }, wantErr: true},
+		{name: "changed tags", change: func(in *entity.CreateTopicInput) {
+			in.Tags = []entity.Tag{{Key: "team", Value: "orders"}}
+		}, wantErr: true},
+		{name: "extra tag", change: func(in *entity.CreateTopicInput) {
+			in.Tags = append(in.Tags, entity.Tag{Key: "env", Value: "prod"})
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			natsRepo := newFakeStreamRepo()
+			svc, _ := newTestTopicService(natsRepo)
+			_, err := svc.CreateTopic(ctx, "acct", base)
+			assert.NoError(t, err)
+			created := natsRepo.streams["acct_orders"]
+
+			again := base
+			tt.change(&again)
+			topic, err := svc.CreateTopic(ctx, "acct", again)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, entity.ErrConflict)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "srn:scp:sns:local:acct:orders", topic.TopicSrn)
+			}
+			assert.Equal(t, created, natsRepo.streams["acct_orders"], "the existing topic is never changed")
+		})
+	}
+}
+
+func TestCheckSameTopic_IgnoresServerMetadata(t *testing.T) {
+	want := newStreamConfig("acct", "orders")
+	have := newStreamConfig("acct", "orders")
+	have.Metadata["_nats.req.level"] = "1"
+	have.Metadata[entity.MetaTagPrefix+"team"] = "payments"
+
+	assert.NoError(t, checkSameTopic(want, have, false))
+	assert.ErrorIs(t, checkSameTopic(want, have, true), entity.ErrConflict)
+}
+
+// listTopicNames pages through ListTopics and returns the topic names of every page
+func listTopicNames(t *testing.T, svc TopicService, account string, filter entity.TopicFilter, maxResults int, between func()) [][]string {
+	t.Helper()
+	var pages [][]string
+	token := ""
+	for {
+		topics, next, err := svc.ListTopics(context.Background(), account, filter, token, maxResults)
+		if !assert.NoError(t, err) {
+			return pages
+		}
+		var names []string
+		for _, topic := range topics {
+			srn, _ := entity.ParseSRN(topic.TopicSrn)
+			names = append(names, srn.Topic)
+		}
+		pages = append(pages, names)
+		if next == "" {
+			return pages
+		}
+		token = next
+		if between != nil {
+			between()
+		}
+	}
+}
+
+func TestListTopics_Pagination(t *testing.T) {
+	natsRepo := newFakeStreamRepo()
+	natsRepo.pageLimit = 2
+	svc, _ := newTestTopicService(natsRepo)
+	createTopics(t, svc, "acct", "a", "b", "c", "d", "e")
+	createTopics(t, svc, "other", "x")
+
+	// pages end exactly at the last topic without an extra empty page
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, listTopicNames(t, svc, "acct", entity.TopicFilter{}, 2, nil))
+	assert.Equal(t, [][]string{{"a", "b", "c", "d", "e"}}, listTopicNames(t, svc, "acct", entity.TopicFilter{}, 5, nil))
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"d", "e"}}, listTopicNames(t, svc, "acct", entity.TopicFilter{}, 3, nil))
+
+	// topics deleted before the token position do not make the listing skip topics
+	deleted := false
+	pages := listTopicNames(t, svc, "acct", entity.TopicFilter{}, 2, func() {
+		if !deleted {
+			deleted = true
+			assert.NoError(t, svc.DeleteTopic(context.Background(), "acct", entity.NewTopicSRN("local", "acct", "a")))
+		}
+	})
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, pages)
+}
+
+func TestListTopics_RejectsForeignToken(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newFakeStreamRepo()
+	svc, _ := newTestTopicService(natsRepo)
+	createTopics(t, svc, "acct", "a", "b", "c")
+	createTopics(t, svc, "other", "a", "b", "c")
+	for _, name := range []string{"a", "b", "c"} {
+		assert.NoError(t, svc.TagResource(ctx, "acct", entity.NewTopicSRN("local", "acct", name), []entity.Tag{{Key: "team", Value: "payments"}}))
+	}
+
+	_, token, err := svc.ListTopics(ctx, "acct", entity.TopicFilter{}, "", 1)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	_, _, err = svc.ListTopics(ctx, "other", entity.TopicFilter{}, token, 1)
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter, "token of another account")
+	_, _, err = svc.ListTopics(ctx, "acct", entity.TopicFilter{TagKey: "team"}, token, 1)
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter, "token of another filter")
+	_, _, err = svc.ListTopics(ctx, "acct", entity.TopicFilter{}, "garbage", 1)
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+	_, _, err = svc.ListTopics(ctx, "acct", entity.TopicFilter{}, "", 101)
+	assert.ErrorIs(t, err, entity.ErrInvalidParameter)
+
+	topics, _, err := svc.ListTopics(ctx, "acct", entity.TopicFilter{}, token, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Topic{{TopicSrn: "srn:scp:sns:local:acct:b"}}, topics)
+}
+
+func TestCreateTopic_SubjectsRootedAtName(t *testing.T) {
+	ctx := context.Background()
+	natsRepo := newFakeStreamRepo()
+	svc, _ := newTestTopicService(natsRepo)
+
+	for _, subjects := range [][]string{{">"}, {"*"}, {"payments"}, {"orders.>", "payments.>"}, {"ordersx"}} {
+		_, err := svc.CreateTopic(ctx, "acct", entity.CreateTopicInput{Name: "orders", Subjects: subjects})
+		assert.ErrorIs(t, err, entity.ErrInvalidParameter, subjects)
+	}
+	assert.Empty(t, natsRepo.streams)
+
+	_, err := svc.CreateTopic(ctx, "acct", entity.CreateTopicInput{Name: "orders", Subjects: []string{"orders.>"}})
+	assert.NoError(t, err)
+	// the topic name stays the default publish subject of the topic
+	assert.Equal(t, []string{"sns.data.acct.orders", "sns.data.acct.orders.>"}, natsRepo.streams["acct_orders"].Subjects)
+
+	_, err = svc.CreateTopic(ctx, "acct", entity.CreateTopicInput{Name: "payments", Subjects: []string{"payments", "payments.*.created"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sns.data.acct.payments", "sns.data.acct.payments.*.created"}, natsRepo.streams["acct_payments"].Subjects)
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"nats/internal/entity"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// webhookSender posts subscription messages to HTTP/HTTPS endpoints
+type webhookSender struct {
+	client *http.Client
+	signer *NotificationSigner // nil when notifications are unsigned
+}
+
+func newWebhookSender(timeout time.Duration, signer *NotificationSigner) *webhookSender {
+	return &webhookSender{client: &http.Client{Timeout: timeout}, signer: signer}
+}
+
+// send posts the message to the endpoint.
+// The message is wrapped in the Notification envelope unless the subscription asks for raw delivery.
+func (s *webhookSender) send(ctx context.Context, t deliveryTarget, msg jetstream.Msg) error {
+	attrs := entity.MessageAttributesFromHeader(msg.Headers())
+	body, contentType := msg.Data(), "text/plain; charset=UTF-8"
+	if !t.Raw {
+		n := newNotification(t, msg, attrs)
+		if s.signer != nil {
+			if err := s.signer.Sign(&n); err != nil {
+				return err
+			}
+		}
+		b, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		body, contentType = b, "application/json"
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	header.Set(entity.HTTPHeaderMessageType, entity.MessageTypeNotification)
+	header.Set(entity.HTTPHeaderMessageId, deliveryMessageId(msg))
+	if attrs != nil {
+		b, err := json.Marshal(attrs)
+		if err != nil {
+			return err
+		}
+		header.Set(entity.HTTPHeaderMessageAttributes, string(b))
+	}
+	return s.post(ctx, t, header, body)
+}
+
+// sendConfirmation posts the SubscriptionConfirmation of a pending subscription
+func (s *webhookSender) sendConfirmation(ctx context.Context, t deliveryTarget, token, subscribeURL string) error {
+	n := entity.Notification{
+		Version:      entity.NotificationVersion,
+		Type:         entity.MessageTypeSubscriptionConfirmation,
+		MessageId:    uuid.NewString(),
+		TopicSrn:     t.TopicSrn,
+		Message:      fmt.Sprintf("You have chosen to subscribe to the topic %s.\nTo confirm the subscription, visit the SubscribeURL included in this message.", t.TopicSrn),
+		Timestamp:    entity.NotificationTimestamp(time.Now()),
+		Token:        token,
+		SubscribeURL: subscribeURL,
+	}
+	if s.signer != nil {
+		if err := s.signer.Sign(&n); err != nil {
+			return err
+		}
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(entity.HTTPHeaderMessageType, entity.MessageTypeSubscriptionConfirmation)
+	header.Set(entity.HTTPHeaderMessageId, n.MessageId)
+	return s.post(ctx, t, header, body)
+}
+
+// post sends the body with the subscription headers and fails on anything but a 2xx answer
+func (s *webhookSender) post(ctx context.Context, t deliveryTarget, header http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set(entity.HTTPHeaderTopicSrn, t.TopicSrn)
+	req.Header.Set(entity.HTTPHeaderSubscriptionSrn, t.SubscriptionSrn)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+// deliveryMessageId returns the id given on publish, or the stream sequence for older messages
+func deliveryMessageId(msg jetstream.Msg) string {
+	if id := msg.Headers().Get(entity.HeaderMessageId); id != "" {
+		return id
+	}
+	if md, err := msg.Metadata(); err == nil {
+		return strconv.FormatUint(md.Sequence.Stream, 10)
+	}
+	return ""
+}
+
+// newNotification wraps the message in the delivery envelope
+func newNotification(t deliveryTarget, msg jetstream.Msg, attrs map[string]entity.MessageAttributeValue) entity.Notification {
+	n := entity.Notification{
+		Version:           entity.NotificationVersion,
+		Type:              entity.MessageTypeNotification,
+		MessageId:         deliveryMessageId(msg),
+		TopicSrn:          t.TopicSrn,
+		Subject:           strings.TrimPrefix(msg.Subject(), entity.AccountSubjectPrefix(t.Account)),
+		Message:           string(msg.Data()),
+		MessageGroupId:    msg.Headers().Get(entity.HeaderMessageGroupId),
+		MessageAttributes: entity.NotificationAttributes(attrs),
+	}
+	if md, err := msg.Metadata(); err == nil {
+		n.Timestamp = entity.NotificationTimestamp(md.Timestamp)
+		if n.MessageGroupId != "" {
+			n.SequenceNumber = strconv.FormatUint(md.Sequence.Stream, 10)
+		}
+	}
+	return n
+}
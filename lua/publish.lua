@@ -4,6 +4,6 @@ wrk.body = [[
 {
   "topicName": "sns-wrk-test",
   "message": "hello from wrk",
-  "subject": "sns.wrk.test"
+  "subject": "sns-wrk-test"
 }
 ]]
\ No newline at end of file
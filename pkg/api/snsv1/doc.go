@@ -0,0 +1,4 @@
+// Package snsv1 holds the gRPC stubs of the sns.v1 API generated from proto/sns/v1/sns.proto.
+package snsv1
+
+//go:generate protoc -I ../../../proto --go_out=../../.. --go_opt=module=nats --go-grpc_out=../../.. --go-grpc_opt=module=nats sns/v1/sns.proto
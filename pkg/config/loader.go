@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -10,12 +11,16 @@ const configPath = "configs/config.yaml"
 
 // 전체 설정 구조체 정의
 type Config struct {
-	Region  string        `yaml:"region"`
-	Env     string        `yaml:"env"`
-	Log     LoggerConfig  `yaml:"log"`
-	Nats    NatsConfig    `yaml:"nats"`
-	Valkey  ValkeyConfig  `yaml:"valkey"`
-	Publish PublishConfig `yaml:"publish"`
+	Region       string             `yaml:"region"`
+	Env          string             `yaml:"env"`
+	Log          LoggerConfig       `yaml:"log"`
+	Nats         NatsConfig         `yaml:"nats"`
+	Valkey       ValkeyConfig       `yaml:"valkey"`
+	Publish      PublishConfig      `yaml:"publish"`
+	Delivery     DeliveryConfig     `yaml:"delivery"`
+	Signing      SigningConfig      `yaml:"signing"`
+	Subscription SubscriptionConfig `yaml:"subscription"`
+	Grpc         GrpcConfig         `yaml:"grpc"`
 }
 
 type LoggerConfig struct {
@@ -33,7 +38,30 @@ type ValkeyConfig struct {
 }
 
 type PublishConfig struct {
-	Worker int `yaml:"worker"`
+	Worker          int `yaml:"worker"`
+	MaxBatchEntries int `yaml:"maxBatchEntries"` // entries accepted by publishBatch, 10 when 0
+}
+
+type DeliveryConfig struct {
+	ConcurrencyPerEndpoint int           `yaml:"concurrencyPerEndpoint"` // in-flight HTTP requests per endpoint host
+	Timeout                time.Duration `yaml:"timeout"`                // HTTP request timeout
+	RetryDelay             time.Duration `yaml:"retryDelay"`             // nak delay after a failed delivery
+	SyncInterval           time.Duration `yaml:"syncInterval"`           // how often subscriptions are reloaded
+}
+
+type SigningConfig struct {
+	PrivateKeyFile  string `yaml:"privateKeyFile"`  // PEM RSA key, notifications are unsigned when empty
+	CertificateFile string `yaml:"certificateFile"` // PEM certificate of the key, served by the API
+	CertificateURL  string `yaml:"certificateURL"`  // public URL of the certificate endpoint, sent as SigningCertURL
+}
+
+type SubscriptionConfig struct {
+	ConfirmationExpiry time.Duration `yaml:"confirmationExpiry"` // unconfirmed HTTP/HTTPS subscriptions are deleted after this
+	SubscribeBaseURL   string        `yaml:"subscribeBaseURL"`   // public API URL used in the SubscribeURL of confirmations
+}
+
+type GrpcConfig struct {
+	Addr string `yaml:"addr"` // listen address of the gRPC API, disabled when empty
 }
 
 func LoadConfig(path string) (*Config, error) {
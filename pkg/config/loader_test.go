@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -14,5 +15,8 @@ func TestSampleConfigFile(t *testing.T) {
 		assert.Equal(t, "dev2", config.Env)
 		assert.Equal(t, 5, config.Nats.ConnPoolCnt)
 		assert.Equal(t, "localhost:6379", config.Valkey.Addr)
+		assert.Equal(t, 15*time.Second, config.Delivery.Timeout)
+		assert.Equal(t, ":9090", config.Grpc.Addr)
+		assert.Equal(t, 10, config.Publish.MaxBatchEntries)
 	}
 }
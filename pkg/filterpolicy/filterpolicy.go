@@ -0,0 +1,530 @@
+// Package filterpolicy evaluates SNS-style subscription filter policies.
+//
+// A policy is a JSON object whose keys name message attributes and whose values are
+// arrays of conditions. Every key must match (AND), and a key matches when any of its
+// conditions matches (OR). The "$or" key holds alternative policies, one of which must match.
+// Policies scoped to the JSON message body may nest objects to reach nested keys.
+//
+//	{"store": ["example_corp"], "price_usd": [{"numeric": [">=", 100]}], "event": [{"anything-but": "cancelled"}]}
+//
+// Supported conditions:
+//
+//	"value", 5                  exact string or number (true and null too for the message body)
+//	{"prefix": "abc"}           string prefix
+//	{"suffix": "abc"}           string suffix
+//	{"equals-ignore-case": "a"} case-insensitive string
+//	{"anything-but": ...}       a value, an array of values or {"prefix": "abc"}; the attribute must exist
+//	{"numeric": [">", 0, "<=", 5]}
+//	{"exists": true}
+package filterpolicy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPolicy is returned for policies that do not follow the grammar or exceed the limits.
+var ErrInvalidPolicy = errors.New("invalid filter policy")
+
+// OrKey holds alternative policies.
+const OrKey = "$or"
+
+// Scope tells what a policy is evaluated against.
+type Scope int
+
+const (
+	// ScopeMessageAttributes matches the message attributes. Keys cannot be nested.
+	ScopeMessageAttributes Scope = iota
+	// ScopeMessageBody matches the JSON message body. A key whose value is an object
+	// matches the object found under that key in the body.
+	ScopeMessageBody
+)
+
+// Policy limits.
+const (
+	MaxPolicySize   = 256 * 1024 // bytes of JSON
+	MaxKeys         = 5          // leaf keys, counted along each $or alternative
+	MaxCombinations = 150        // distinct ways the policy can match
+	MaxDepth        = 5          // nesting levels of a message body policy
+)
+
+// Policy is a parsed filter policy. The zero value is not usable, use Parse.
+type Policy struct {
+	root *expr
+}
+
+// expr matches when every key matches and one alternative of every $or matches
+type expr struct {
+	keys []keyExpr
+	ors  [][]*expr
+}
+
+// keyExpr holds either the conditions of a value or the policy of a nested object
+type keyExpr struct {
+	name   string
+	conds  []condition
+	nested *expr
+}
+
+// condition tests a single attribute value. present is false for missing attributes.
+type condition interface {
+	match(v any, present bool) bool
+}
+
+// Parse decodes and validates a filter policy of the given scope.
+func Parse(data []byte, scope Scope) (*Policy, error) {
+	if len(data) > MaxPolicySize {
+		return nil, fmt.Errorf("%w: policy exceeds %d bytes", ErrInvalidPolicy, MaxPolicySize)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: policy must be a JSON object", ErrInvalidPolicy)
+	}
+
+	p := parser{scope: scope}
+	root, err := p.parseExpr(raw, 0)
+	if err != nil {
+		return nil, err
+	}
+	if keys := root.maxKeys(); keys > MaxKeys {
+		return nil, fmt.Errorf("%w: policy has %d keys, at most %d are allowed", ErrInvalidPolicy, keys, MaxKeys)
+	}
+	if n := root.combinations(); n > MaxCombinations {
+		return nil, fmt.Errorf("%w: policy has %d combinations, at most %d are allowed", ErrInvalidPolicy, n, MaxCombinations)
+	}
+	return &Policy{root: root}, nil
+}
+
+// Match reports whether the attributes satisfy the policy. Attribute values are strings,
+// float64 numbers or arrays of those; an array matches when any element matches.
+// Numeric conditions also accept strings holding a number.
+func (p *Policy) Match(attrs map[string]any) bool {
+	return p.root.match(attrs)
+}
+
+// MatchBody reports whether the JSON message body satisfies the policy.
+// A body that is not a JSON object never matches.
+func (p *Policy) MatchBody(body []byte) bool {
+	var obj map[string]any
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return false
+	}
+	return p.root.match(obj)
+}
+
+func (e *expr) match(attrs map[string]any) bool {
+	for _, k := range e.keys {
+		v, present := attrs[k.name]
+		if !k.match(v, present) {
+			return false
+		}
+	}
+	for _, alternatives := range e.ors {
+		matched := false
+		for _, alt := range alternatives {
+			if alt.match(attrs) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (k keyExpr) match(v any, present bool) bool {
+	if k.nested != nil {
+		switch v := v.(type) {
+		case map[string]any:
+			return k.nested.match(v)
+		case []any:
+			for _, elem := range v {
+				if obj, ok := elem.(map[string]any); ok && k.nested.match(obj) {
+					return true
+				}
+			}
+			return false
+		}
+		return !present && k.nested.match(map[string]any{})
+	}
+
+	for _, c := range k.conds {
+		if values, ok := v.([]any); ok && present {
+			for _, elem := range values {
+				if c.match(elem, true) {
+					return true
+				}
+			}
+			continue
+		}
+		if c.match(v, present) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxKeys counts the leaf keys along the widest $or alternative
+func (e *expr) maxKeys() int {
+	n := 0
+	for _, k := range e.keys {
+		if k.nested != nil {
+			n += k.nested.maxKeys()
+		} else {
+			n++
+		}
+	}
+	for _, alternatives := range e.ors {
+		widest := 0
+		for _, alt := range alternatives {
+			widest = max(widest, alt.maxKeys())
+		}
+		n += widest
+	}
+	return n
+}
+
+// combinations multiplies the values of every key and adds up the $or alternatives
+func (e *expr) combinations() int {
+	n := 1
+	for _, k := range e.keys {
+		if k.nested != nil {
+			n *= k.nested.combinations()
+		} else {
+			n *= len(k.conds)
+		}
+		if n > MaxCombinations {
+			return n
+		}
+	}
+	for _, alternatives := range e.ors {
+		sum := 0
+		for _, alt := range alternatives {
+			sum += alt.combinations()
+		}
+		n *= sum
+		if n > MaxCombinations {
+			return n
+		}
+	}
+	return n
+}
+
+type parser struct {
+	scope Scope
+}
+
+func (p parser) parseExpr(raw map[string]any, depth int) (*expr, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: policy must have at least one key", ErrInvalidPolicy)
+	}
+	if depth >= MaxDepth {
+		return nil, fmt.Errorf("%w: policy is nested deeper than %d levels", ErrInvalidPolicy, MaxDepth)
+	}
+
+	e := &expr{}
+	for name, value := range raw {
+		if name == OrKey {
+			alternatives, err := p.parseOr(value, depth)
+			if err != nil {
+				return nil, err
+			}
+			e.ors = append(e.ors, alternatives)
+			continue
+		}
+
+		if obj, ok := value.(map[string]any); ok {
+			if p.scope != ScopeMessageBody {
+				return nil, fmt.Errorf("%w: %q: nested keys are only supported for the message body", ErrInvalidPolicy, name)
+			}
+			nested, err := p.parseExpr(obj, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			e.keys = append(e.keys, keyExpr{name: name, nested: nested})
+			continue
+		}
+
+		values, ok := value.([]any)
+		if !ok || len(values) == 0 {
+			return nil, fmt.Errorf("%w: %q must be a non-empty array", ErrInvalidPolicy, name)
+		}
+		k := keyExpr{name: name}
+		for _, v := range values {
+			c, err := p.parseValue(name, v)
+			if err != nil {
+				return nil, err
+			}
+			k.conds = append(k.conds, c)
+		}
+		e.keys = append(e.keys, k)
+	}
+	return e, nil
+}
+
+func (p parser) parseOr(value any, depth int) ([]*expr, error) {
+	values, ok := value.([]any)
+	if !ok || len(values) < 2 {
+		return nil, fmt.Errorf("%w: %s must be an array of at least two policies", ErrInvalidPolicy, OrKey)
+	}
+	alternatives := make([]*expr, 0, len(values))
+	for _, v := range values {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be an array of policies", ErrInvalidPolicy, OrKey)
+		}
+		alt, err := p.parseExpr(obj, depth)
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, alt)
+	}
+	return alternatives, nil
+}
+
+// parseValue accepts JSON booleans and nulls in addition to the conditions of parseCondition
+// when the policy matches the message body
+func (p parser) parseValue(name string, v any) (condition, error) {
+	if p.scope == ScopeMessageBody {
+		switch v := v.(type) {
+		case bool:
+			return exactBool(v), nil
+		case nil:
+			return exactNull{}, nil
+		}
+	}
+	return parseCondition(name, v)
+}
+
+func parseCondition(name string, v any) (condition, error) {
+	switch v := v.(type) {
+	case string:
+		return exactString(v), nil
+	case float64:
+		return exactNumber(v), nil
+	case map[string]any:
+		if len(v) != 1 {
+			return nil, fmt.Errorf("%w: %q: an operator object must have exactly one operator", ErrInvalidPolicy, name)
+		}
+		for op, arg := range v {
+			return parseOperator(name, op, arg)
+		}
+	}
+	return nil, fmt.Errorf("%w: %q: unsupported value %v", ErrInvalidPolicy, name, v)
+}
+
+func parseOperator(name, op string, arg any) (condition, error) {
+	switch op {
+	case "prefix", "suffix", "equals-ignore-case":
+		s, ok := arg.(string)
+		if !ok || (s == "" && op != "equals-ignore-case") {
+			return nil, fmt.Errorf("%w: %q: %s needs a non-empty string", ErrInvalidPolicy, name, op)
+		}
+		switch op {
+		case "prefix":
+			return prefix(s), nil
+		case "suffix":
+			return suffix(s), nil
+		}
+		return equalsIgnoreCase(s), nil
+	case "anything-but":
+		return parseAnythingBut(name, arg)
+	case "numeric":
+		return parseNumeric(name, arg)
+	case "exists":
+		b, ok := arg.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q: exists needs true or false", ErrInvalidPolicy, name)
+		}
+		return exists(b), nil
+	}
+	return nil, fmt.Errorf("%w: %q: unknown operator %q", ErrInvalidPolicy, name, op)
+}
+
+func parseAnythingBut(name string, arg any) (condition, error) {
+	var inner []condition
+	switch arg := arg.(type) {
+	case string, float64:
+		c, _ := parseCondition(name, arg)
+		inner = append(inner, c)
+	case []any:
+		if len(arg) == 0 {
+			return nil, fmt.Errorf("%w: %q: anything-but needs at least one value", ErrInvalidPolicy, name)
+		}
+		for _, v := range arg {
+			switch v.(type) {
+			case string, float64:
+				c, _ := parseCondition(name, v)
+				inner = append(inner, c)
+			default:
+				return nil, fmt.Errorf("%w: %q: anything-but values must be strings or numbers", ErrInvalidPolicy, name)
+			}
+		}
+	case map[string]any:
+		p, ok := arg["prefix"].(string)
+		if len(arg) != 1 || !ok || p == "" {
+			return nil, fmt.Errorf("%w: %q: anything-but only nests a prefix", ErrInvalidPolicy, name)
+		}
+		inner = append(inner, prefix(p))
+	default:
+		return nil, fmt.Errorf("%w: %q: unsupported anything-but value", ErrInvalidPolicy, name)
+	}
+	return anythingBut(inner), nil
+}
+
+// parseNumeric accepts one comparison, or a lower and an upper bound
+func parseNumeric(name string, arg any) (condition, error) {
+	values, ok := arg.([]any)
+	if !ok || (len(values) != 2 && len(values) != 4) {
+		return nil, fmt.Errorf("%w: %q: numeric needs [op, number] or [lower op, number, upper op, number]", ErrInvalidPolicy, name)
+	}
+
+	var n numeric
+	for i := 0; i < len(values); i += 2 {
+		op, ok1 := values[i].(string)
+		bound, ok2 := values[i+1].(float64)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%w: %q: numeric needs operator and number pairs", ErrInvalidPolicy, name)
+		}
+		n.comparisons = append(n.comparisons, comparison{op: op, bound: bound})
+	}
+
+	first := n.comparisons[0].op
+	switch {
+	case len(n.comparisons) == 1:
+		switch first {
+		case "=", "<", "<=", ">", ">=":
+		default:
+			return nil, fmt.Errorf("%w: %q: unknown numeric operator %q", ErrInvalidPolicy, name, first)
+		}
+	default:
+		second := n.comparisons[1]
+		if (first != ">" && first != ">=") || (second.op != "<" && second.op != "<=") {
+			return nil, fmt.Errorf("%w: %q: a numeric range is a lower bound (>, >=) followed by an upper bound (<, <=)", ErrInvalidPolicy, name)
+		}
+		if n.comparisons[0].bound > second.bound {
+			return nil, fmt.Errorf("%w: %q: numeric range bounds are reversed", ErrInvalidPolicy, name)
+		}
+	}
+	return n, nil
+}
+
+type exactString string
+
+func (c exactString) match(v any, present bool) bool {
+	s, ok := v.(string)
+	return present && ok && s == string(c)
+}
+
+type exactNumber float64
+
+func (c exactNumber) match(v any, present bool) bool {
+	f, ok := toNumber(v)
+	return present && ok && f == float64(c)
+}
+
+type exactBool bool
+
+func (c exactBool) match(v any, present bool) bool {
+	b, ok := v.(bool)
+	return present && ok && b == bool(c)
+}
+
+// exactNull matches a JSON null in the message body
+type exactNull struct{}
+
+func (exactNull) match(v any, present bool) bool {
+	return present && v == nil
+}
+
+type prefix string
+
+func (c prefix) match(v any, present bool) bool {
+	s, ok := v.(string)
+	return present && ok && strings.HasPrefix(s, string(c))
+}
+
+type suffix string
+
+func (c suffix) match(v any, present bool) bool {
+	s, ok := v.(string)
+	return present && ok && strings.HasSuffix(s, string(c))
+}
+
+type equalsIgnoreCase string
+
+func (c equalsIgnoreCase) match(v any, present bool) bool {
+	s, ok := v.(string)
+	return present && ok && strings.EqualFold(s, string(c))
+}
+
+type anythingBut []condition
+
+func (c anythingBut) match(v any, present bool) bool {
+	if !present {
+		return false
+	}
+	for _, inner := range c {
+		if inner.match(v, true) {
+			return false
+		}
+	}
+	return true
+}
+
+type exists bool
+
+func (c exists) match(_ any, present bool) bool {
+	return present == bool(c)
+}
+
+type comparison struct {
+	op    string
+	bound float64
+}
+
+type numeric struct {
+	comparisons []comparison
+}
+
+func (c numeric) match(v any, present bool) bool {
+	f, ok := toNumber(v)
+	if !present || !ok {
+		return false
+	}
+	for _, cmp := range c.comparisons {
+		var ok bool
+		switch cmp.op {
+		case "=":
+			ok = f == cmp.bound
+		case "<":
+			ok = f < cmp.bound
+		case "<=":
+			ok = f <= cmp.bound
+		case ">":
+			ok = f > cmp.bound
+		case ">=":
+			ok = f >= cmp.bound
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func toNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
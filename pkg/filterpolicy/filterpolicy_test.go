@@ -0,0 +1,146 @@
+package filterpolicy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		`[]`,
+		`{}`,
+		`not json`,
+		`{"store": "example_corp"}`,
+		`{"store": []}`,
+		`{"store": [true]}`,
+		`{"store": [{"prefix": ""}]}`,
+		`{"store": [{"prefix": "a", "suffix": "b"}]}`,
+		`{"store": [{"wildcard": "a*"}]}`,
+		`{"price": [{"numeric": [">", "100"]}]}`,
+		`{"price": [{"numeric": ["!=", 100]}]}`,
+		`{"price": [{"numeric": ["<", 100, ">", 0]}]}`,
+		`{"price": [{"numeric": [">", 100, "<", 0]}]}`,
+		`{"price": [{"exists": "yes"}]}`,
+		`{"event": [{"anything-but": []}]}`,
+		`{"event": [{"anything-but": {"suffix": "x"}}]}`,
+		`{"$or": [{"a": ["1"]}]}`,
+		`{"$or": {"a": ["1"]}}`,
+		`{"store": {"name": ["a"]}}`,
+		`{"a": ["1"], "b": ["1"], "c": ["1"], "d": ["1"], "e": ["1"], "f": ["1"]}`,
+		`{"a": ["1", "2", "3", "4", "5", "6", "7", "8", "9", "10"], "b": ["1", "2", "3", "4", "5", "6", "7", "8", "9", "10"], "c": ["1", "2"]}`,
+	}
+
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt), ScopeMessageAttributes)
+		assert.True(t, errors.Is(err, ErrInvalidPolicy), tt)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		attrs  map[string]any
+		want   bool
+	}{
+		{"exact string", `{"store": ["example_corp"]}`, map[string]any{"store": "example_corp"}, true},
+		{"exact string mismatch", `{"store": ["example_corp"]}`, map[string]any{"store": "other"}, false},
+		{"exact string missing", `{"store": ["example_corp"]}`, map[string]any{}, false},
+		{"one of values", `{"store": ["a", "b"]}`, map[string]any{"store": "b"}, true},
+		{"array attribute", `{"sport": ["rugby"]}`, map[string]any{"sport": []any{"soccer", "rugby"}}, true},
+		{"array attribute mismatch", `{"sport": ["rugby"]}`, map[string]any{"sport": []any{"soccer"}}, false},
+		{"exact number", `{"price": [100]}`, map[string]any{"price": float64(100)}, true},
+		{"exact number from string", `{"price": [100]}`, map[string]any{"price": "100.0"}, true},
+		{"number does not match string", `{"price": ["100"]}`, map[string]any{"price": float64(100)}, false},
+		{"prefix", `{"region": [{"prefix": "kr-"}]}`, map[string]any{"region": "kr-west1"}, true},
+		{"prefix mismatch", `{"region": [{"prefix": "kr-"}]}`, map[string]any{"region": "us-east1"}, false},
+		{"suffix", `{"file": [{"suffix": ".png"}]}`, map[string]any{"file": "a.png"}, true},
+		{"equals-ignore-case", `{"name": [{"equals-ignore-case": "ALICE"}]}`, map[string]any{"name": "alice"}, true},
+		{"anything-but value", `{"event": [{"anything-but": "cancelled"}]}`, map[string]any{"event": "created"}, true},
+		{"anything-but same value", `{"event": [{"anything-but": "cancelled"}]}`, map[string]any{"event": "cancelled"}, false},
+		{"anything-but list", `{"event": [{"anything-but": ["a", "b"]}]}`, map[string]any{"event": "b"}, false},
+		{"anything-but number", `{"code": [{"anything-but": [0, 1]}]}`, map[string]any{"code": float64(2)}, true},
+		{"anything-but prefix", `{"event": [{"anything-but": {"prefix": "order-"}}]}`, map[string]any{"event": "order-created"}, false},
+		{"anything-but missing", `{"event": [{"anything-but": "cancelled"}]}`, map[string]any{}, false},
+		{"numeric greater", `{"price": [{"numeric": [">", 100]}]}`, map[string]any{"price": float64(101)}, true},
+		{"numeric not greater", `{"price": [{"numeric": [">", 100]}]}`, map[string]any{"price": float64(100)}, false},
+		{"numeric equal", `{"price": [{"numeric": ["=", 100]}]}`, map[string]any{"price": "100"}, true},
+		{"numeric range", `{"price": [{"numeric": [">=", 0, "<", 10]}]}`, map[string]any{"price": float64(0)}, true},
+		{"numeric range upper", `{"price": [{"numeric": [">=", 0, "<", 10]}]}`, map[string]any{"price": float64(10)}, false},
+		{"numeric on text", `{"price": [{"numeric": [">", 0]}]}`, map[string]any{"price": "free"}, false},
+		{"exists", `{"store": [{"exists": true}]}`, map[string]any{"store": "x"}, true},
+		{"exists missing", `{"store": [{"exists": true}]}`, map[string]any{}, false},
+		{"not exists", `{"store": [{"exists": false}]}`, map[string]any{}, true},
+		{"not exists present", `{"store": [{"exists": false}]}`, map[string]any{"store": "x"}, false},
+		{"all keys must match", `{"store": ["a"], "event": ["created"]}`, map[string]any{"store": "a", "event": "deleted"}, false},
+		{"all keys match", `{"store": ["a"], "event": ["created"]}`, map[string]any{"store": "a", "event": "created"}, true},
+		{"or first", `{"$or": [{"store": ["a"]}, {"price": [{"numeric": [">", 10]}]}]}`, map[string]any{"store": "a"}, true},
+		{"or second", `{"$or": [{"store": ["a"]}, {"price": [{"numeric": [">", 10]}]}]}`, map[string]any{"price": float64(20)}, true},
+		{"or none", `{"$or": [{"store": ["a"]}, {"price": [{"numeric": [">", 10]}]}]}`, map[string]any{"price": float64(5)}, false},
+		{"or with key", `{"source": ["aws.cloudwatch"], "$or": [{"metric": ["cpu"]}, {"namespace": ["ec2"]}]}`, map[string]any{"source": "aws.cloudwatch", "namespace": "ec2"}, true},
+		{"or with key mismatch", `{"source": ["aws.cloudwatch"], "$or": [{"metric": ["cpu"]}, {"namespace": ["ec2"]}]}`, map[string]any{"source": "other", "namespace": "ec2"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte(tt.policy), ScopeMessageAttributes)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, p.Match(tt.attrs))
+			}
+		})
+	}
+}
+
+func TestParseInvalidBody(t *testing.T) {
+	tests := []string{
+		`{"store": {}}`,
+		`{"a": {"b": {"c": {"d": {"e": {"f": ["1"]}}}}}}`,
+		`{"a": {"b": ["1"], "c": ["1"], "d": ["1"]}, "e": {"f": ["1"], "g": ["1"], "h": ["1"]}}`,
+	}
+
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt), ScopeMessageBody)
+		assert.True(t, errors.Is(err, ErrInvalidPolicy), tt)
+	}
+
+	_, err := Parse(make([]byte, MaxPolicySize+1), ScopeMessageBody)
+	assert.True(t, errors.Is(err, ErrInvalidPolicy))
+}
+
+func TestMatchBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		body   string
+		want   bool
+	}{
+		{"top-level key", `{"store": ["example_corp"]}`, `{"store": "example_corp"}`, true},
+		{"nested key", `{"order": {"customer": {"tier": ["gold"]}}}`, `{"order": {"customer": {"tier": "gold", "id": 7}}}`, true},
+		{"nested key mismatch", `{"order": {"customer": {"tier": ["gold"]}}}`, `{"order": {"customer": {"tier": "silver"}}}`, false},
+		{"nested key missing", `{"order": {"customer": {"tier": ["gold"]}}}`, `{"order": {}}`, false},
+		{"nested not exists", `{"order": {"coupon": [{"exists": false}]}}`, `{"store": "a"}`, true},
+		{"nested value is not an object", `{"order": {"id": [1]}}`, `{"order": 1}`, false},
+		{"array of objects", `{"items": {"sku": [{"prefix": "book-"}]}}`, `{"items": [{"sku": "pen-1"}, {"sku": "book-2"}]}`, true},
+		{"array of values", `{"tags": ["urgent"]}`, `{"tags": ["normal", "urgent"]}`, true},
+		{"number", `{"price": [{"numeric": [">", 10]}]}`, `{"price": 12.5}`, true},
+		{"boolean", `{"paid": [true]}`, `{"paid": true}`, true},
+		{"boolean mismatch", `{"paid": [true]}`, `{"paid": "true"}`, false},
+		{"null", `{"coupon": [null]}`, `{"coupon": null}`, true},
+		{"null missing", `{"coupon": [null]}`, `{}`, false},
+		{"or nested", `{"$or": [{"a": {"b": ["1"]}}, {"c": ["2"]}]}`, `{"c": "2"}`, true},
+		{"not json", `{"store": [{"exists": false}]}`, `plain text`, false},
+		{"not an object", `{"store": [{"exists": false}]}`, `["store"]`, false},
+		{"empty body", `{"store": [{"exists": false}]}`, ``, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte(tt.policy), ScopeMessageBody)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, p.MatchBody([]byte(tt.body)))
+			}
+		})
+	}
+}
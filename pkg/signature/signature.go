@@ -0,0 +1,298 @@
+// Package signature signs and verifies SNS-style notification envelopes.
+//
+// The signed bytes are the canonical string of the envelope: for each signed field that is
+// present, in alphabetical order, the field name and its value each followed by a newline.
+//
+//	Notification:             Message, MessageId, Subject (if set), Timestamp, TopicSrn, Type
+//	SubscriptionConfirmation: Message, MessageId, SubscribeURL, Timestamp, Token, TopicSrn, Type
+//
+// Signature version 2 signs the canonical string with RSA PKCS #1 v1.5 over SHA-256.
+// Receivers decode the delivered JSON body into a Message and call Verifier.Verify.
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the only supported SignatureVersion.
+const Version = "2"
+
+// Envelope types with their own canonical string.
+const (
+	TypeNotification             = "Notification"
+	TypeSubscriptionConfirmation = "SubscriptionConfirmation"
+)
+
+var (
+	// ErrInvalidSignature is returned when a message does not match its signature.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrUntrustedCertificate is returned for certificates outside the trusted URLs or validity.
+	ErrUntrustedCertificate = errors.New("untrusted signing certificate")
+)
+
+// Message holds the envelope fields involved in signing. Unknown JSON fields are ignored.
+type Message struct {
+	Type             string `json:"Type"`
+	MessageId        string `json:"MessageId"`
+	TopicSrn         string `json:"TopicSrn"`
+	Subject          string `json:"Subject,omitempty"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	Token            string `json:"Token,omitempty"`
+	SubscribeURL     string `json:"SubscribeURL,omitempty"`
+	SignatureVersion string `json:"SignatureVersion,omitempty"`
+	Signature        string `json:"Signature,omitempty"`
+	SigningCertURL   string `json:"SigningCertURL,omitempty"`
+}
+
+// CanonicalString returns the bytes that are signed for the message type.
+func CanonicalString(m *Message) ([]byte, error) {
+	var fields [][2]string
+	switch m.Type {
+	case TypeNotification:
+		fields = [][2]string{
+			{"Message", m.Message},
+			{"MessageId", m.MessageId},
+			{"Subject", m.Subject},
+			{"Timestamp", m.Timestamp},
+			{"TopicSrn", m.TopicSrn},
+			{"Type", m.Type},
+		}
+	case TypeSubscriptionConfirmation:
+		fields = [][2]string{
+			{"Message", m.Message},
+			{"MessageId", m.MessageId},
+			{"SubscribeURL", m.SubscribeURL},
+			{"Timestamp", m.Timestamp},
+			{"Token", m.Token},
+			{"TopicSrn", m.TopicSrn},
+			{"Type", m.Type},
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported message type %q", ErrInvalidSignature, m.Type)
+	}
+
+	var b strings.Builder
+	for _, f := range fields {
+		if f[0] == "Subject" && f[1] == "" {
+			continue
+		}
+		b.WriteString(f[0])
+		b.WriteByte('\n')
+		b.WriteString(f[1])
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), nil
+}
+
+// Sign sets SignatureVersion and Signature of the message.
+func Sign(m *Message, key *rsa.PrivateKey) error {
+	data, err := CanonicalString(m)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return err
+	}
+	m.SignatureVersion = Version
+	m.Signature = base64.StdEncoding.EncodeToString(sig)
+	return nil
+}
+
+// VerifyWithCertificate checks the signature of the message against the certificate key.
+// The certificate itself is not checked.
+func VerifyWithCertificate(m *Message, cert *x509.Certificate) error {
+	if m.SignatureVersion != Version {
+		return fmt.Errorf("%w: unsupported SignatureVersion %q", ErrInvalidSignature, m.SignatureVersion)
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: certificate key is not RSA", ErrUntrustedCertificate)
+	}
+	sig, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature is not base64", ErrInvalidSignature)
+	}
+	data, err := CanonicalString(m)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ParsePrivateKey decodes a PEM encoded PKCS #1 or PKCS #8 RSA private key.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// ParseCertificate decodes a PEM encoded certificate.
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("certificate is not PEM encoded")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// DefaultCertCacheTTL is how long a downloaded certificate is used before it is downloaded again
+const DefaultCertCacheTTL = time.Hour
+
+// Verifier verifies messages with the certificate named by their SigningCertURL.
+// Certificates are only downloaded from the trusted URL prefixes and are cached for CertCacheTTL,
+// so that a certificate replaced under the same URL is picked up.
+type Verifier struct {
+	// AllowHTTP also trusts http prefixes, for a local API without TLS. Certificates are
+	// fetched over https only by default.
+	AllowHTTP bool
+	// CertCacheTTL is how long a certificate is cached, DefaultCertCacheTTL when zero.
+	CertCacheTTL time.Duration
+
+	client  *http.Client
+	trusted []string
+	now     func() time.Time
+
+	mu    sync.Mutex
+	certs map[string]cachedCert
+}
+
+type cachedCert struct {
+	cert    *x509.Certificate
+	expires time.Time
+}
+
+// NewVerifier creates a verifier trusting certificates whose URL has the scheme and host of one
+// of the prefixes and a path under its path, for example "https://sns.example.com/v1/".
+func NewVerifier(client *http.Client, trustedURLPrefixes ...string) *Verifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Verifier{client: client, trusted: trustedURLPrefixes, now: time.Now, certs: make(map[string]cachedCert)}
+}
+
+// Verify checks the signing certificate and the signature of the message.
+func (v *Verifier) Verify(ctx context.Context, m *Message) error {
+	cert, err := v.certificate(ctx, m.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	if now := v.now(); now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return fmt.Errorf("%w: certificate is expired or not yet valid", ErrUntrustedCertificate)
+	}
+	return VerifyWithCertificate(m, cert)
+}
+
+func (v *Verifier) certificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	if !v.isTrusted(certURL) {
+		return nil, fmt.Errorf("%w: %q", ErrUntrustedCertificate, certURL)
+	}
+
+	v.mu.Lock()
+	cached, ok := v.certs[certURL]
+	if ok && !v.now().Before(cached.expires) {
+		delete(v.certs, certURL)
+		ok = false
+	}
+	v.mu.Unlock()
+	if ok {
+		return cached.cert, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signing certificate %s: %s", certURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	cert, err := ParseCertificate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := v.CertCacheTTL
+	if ttl <= 0 {
+		ttl = DefaultCertCacheTTL
+	}
+	v.mu.Lock()
+	v.certs[certURL] = cachedCert{cert: cert, expires: v.now().Add(ttl)}
+	v.mu.Unlock()
+	return cert, nil
+}
+
+// isTrusted requires the URL to be well formed and to match a trusted prefix. Scheme and host
+// are compared exactly, so "https://sns.example.com" does not trust "https://sns.example.com.evil.io".
+func (v *Verifier) isTrusted(certURL string) bool {
+	u, err := url.Parse(certURL)
+	if err != nil || !v.trustedScheme(u.Scheme) || u.Host == "" || u.User != nil || path.Clean("/"+u.Path) != u.Path {
+		return false
+	}
+	for _, prefix := range v.trusted {
+		p, err := url.Parse(prefix)
+		if err != nil || p.Host == "" || p.Scheme != u.Scheme || !strings.EqualFold(p.Host, u.Host) {
+			continue
+		}
+		if underPath(u.Path, p.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Verifier) trustedScheme(scheme string) bool {
+	return scheme == "https" || (v.AllowHTTP && scheme == "http")
+}
+
+// underPath reports whether p is prefix itself or below it. A prefix without a trailing slash
+// only matches whole path segments, "/v1" covers "/v1/cert.pem" but not "/v1evil/cert.pem".
+func underPath(p, prefix string) bool {
+	if prefix == "" || strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(p, prefix)
+	}
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
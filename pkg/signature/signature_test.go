@@ -0,0 +1,146 @@
+package signature
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCertificate(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCanonicalString(t *testing.T) {
+	m := &Message{Type: TypeNotification, MessageId: "id", TopicSrn: "srn", Message: "hello", Timestamp: "ts"}
+	got, err := CanonicalString(m)
+	assert.NoError(t, err)
+	assert.Equal(t, "Message\nhello\nMessageId\nid\nTimestamp\nts\nTopicSrn\nsrn\nType\nNotification\n", string(got))
+
+	m.Subject = "orders"
+	got, _ = CanonicalString(m)
+	assert.Equal(t, "Message\nhello\nMessageId\nid\nSubject\norders\nTimestamp\nts\nTopicSrn\nsrn\nType\nNotification\n", string(got))
+
+	_, err = CanonicalString(&Message{Type: "Other"})
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := testCertificate(t, key)
+	var downloads int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write(certPEM)
+	}))
+	defer srv.Close()
+
+	m := &Message{Type: TypeNotification, MessageId: "id", TopicSrn: "srn", Message: "hello", Timestamp: "ts", SigningCertURL: srv.URL + "/v1/signing-cert.pem"}
+	assert.NoError(t, Sign(m, key))
+	assert.Equal(t, Version, m.SignatureVersion)
+
+	v := NewVerifier(srv.Client(), srv.URL+"/v1/")
+	assert.NoError(t, v.Verify(context.Background(), m))
+	assert.NoError(t, v.Verify(context.Background(), m))
+	assert.Equal(t, 1, downloads)
+
+	tampered := *m
+	tampered.Message = "bye"
+	assert.True(t, errors.Is(v.Verify(context.Background(), &tampered), ErrInvalidSignature))
+
+	untrusted := NewVerifier(srv.Client(), "https://sns.example.com/")
+	assert.True(t, errors.Is(untrusted.Verify(context.Background(), m), ErrUntrustedCertificate))
+
+	expired := NewVerifier(srv.Client(), srv.URL+"/v1/")
+	expired.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	assert.True(t, errors.Is(expired.Verify(context.Background(), m), ErrUntrustedCertificate))
+}
+
+func TestVerifierIsTrusted(t *testing.T) {
+	v := NewVerifier(nil, "https://sns.example.com", "https://certs.example.com/v1")
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://sns.example.com/v1/signing-cert.pem", true},
+		{"https://SNS.example.com/signing-cert.pem", true},
+		{"https://certs.example.com/v1/signing-cert.pem", true},
+		{"https://sns.example.com.evil.io/signing-cert.pem", false},
+		{"https://sns.example.com@evil.io/signing-cert.pem", false},
+		{"https://sns.example.com:8443/signing-cert.pem", false},
+		{"http://sns.example.com/signing-cert.pem", false},
+		{"https://certs.example.com/v1evil/signing-cert.pem", false},
+		{"https://certs.example.com/v1/../evil/signing-cert.pem", false},
+		{"https://certs.example.com/v1/%2e%2e/evil/signing-cert.pem", false},
+		{"https://evil.io/https://sns.example.com/signing-cert.pem", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, v.isTrusted(tt.url), tt.url)
+	}
+
+	local := NewVerifier(nil, "http://localhost:8080/v1/")
+	assert.False(t, local.isTrusted("http://localhost:8080/v1/signing-cert.pem"))
+	local.AllowHTTP = true
+	assert.True(t, local.isTrusted("http://localhost:8080/v1/signing-cert.pem"))
+}
+
+func TestVerifierCertCacheTTL(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := testCertificate(t, oldKey)
+	var downloads int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write(certPEM)
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	v := NewVerifier(srv.Client(), srv.URL+"/v1/")
+	v.CertCacheTTL = 30 * time.Minute
+	v.now = func() time.Time { return now }
+
+	m := &Message{Type: TypeNotification, MessageId: "id", TopicSrn: "srn", Message: "hello", Timestamp: "ts", SigningCertURL: srv.URL + "/v1/signing-cert.pem"}
+	assert.NoError(t, Sign(m, oldKey))
+	assert.NoError(t, v.Verify(context.Background(), m))
+
+	// the certificate is replaced under the same URL
+	certPEM = testCertificate(t, newKey)
+	assert.NoError(t, Sign(m, newKey))
+	assert.True(t, errors.Is(v.Verify(context.Background(), m), ErrInvalidSignature), "cached certificate is used within the TTL")
+	assert.Equal(t, 1, downloads)
+
+	now = now.Add(30 * time.Minute)
+	assert.NoError(t, v.Verify(context.Background(), m))
+	assert.Equal(t, 2, downloads)
+}